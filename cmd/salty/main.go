@@ -2,20 +2,61 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/user/salty-reviewer/internal/aiconfig"
 	"github.com/user/salty-reviewer/internal/config"
 	"github.com/user/salty-reviewer/internal/defender"
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/logging"
 	"github.com/user/salty-reviewer/internal/reviewer"
 )
 
 var (
 	dryRun      bool
 	interactive bool
+	quiet       bool
+	verbose     bool
+	perCommit   bool
+	fast        bool
+	partial     bool
+	plan        bool
+	squash      bool
+	sinceLast   bool
+	author      string
+	decisionLog string
+	sarifPath   string
+	fromStdin   bool
+	diffFile    string
+	jsonOutput  bool
+	newCodeOnly bool
+
+	waitForRateLimit bool
+
+	compareStyles []string
+
+	allowRequestChanges bool
+	approveClean        bool
+	allowDraft          bool
+	noCache             bool
+	showPayload         bool
+	minSeverity         string
+
+	requestReviewers []string
+
+	defendAll       bool
+	resolveConceded bool
+	concurrency     int
+	defendOrder     string
 )
 
 func main() {
@@ -37,23 +78,59 @@ func main() {
 
 	// Review command
 	reviewCmd := &cobra.Command{
-		Use:   "review <pr-reference>",
+		Use:   "review [pr-reference]",
 		Short: "Review a pull request",
 		Long: `Review a pull request with deep analysis.
 
 Examples:
   salty review owner/repo#123
   salty review https://github.com/owner/repo/pull/123
-  salty review --dry-run owner/repo#42`,
-		Args: cobra.ExactArgs(1),
+  salty review --dry-run owner/repo#42
+  salty review --per-commit owner/repo#42
+  salty review --fast owner/repo#42
+  salty review --compare-styles corporate,tech_bro --dry-run owner/repo#42
+  salty review --json --dry-run owner/repo#42
+  salty review --sarif out.sarif owner/repo#42
+  git diff origin/main | salty review --stdin
+  salty review --diff changes.diff`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if fromStdin || diffFile != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: runReview,
 	}
 	reviewCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be posted without actually posting")
-	reviewCmd.Flags().BoolVar(&interactive, "interactive", false, "Confirm each comment before posting")
+	reviewCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors, warnings and the final result")
+	reviewCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also print every AI prompt and its token counts")
+	reviewCmd.Flags().BoolVar(&showPayload, "show-payload", false, "Print the JSON request GitHub would get (implies --dry-run)")
+	reviewCmd.Flags().BoolVar(&interactive, "interactive", false, "Ask before posting each comment: post it, skip it or edit it in $EDITOR")
+	reviewCmd.Flags().BoolVar(&perCommit, "per-commit", false, "Review each commit separately and post one review per commit")
+	reviewCmd.Flags().BoolVar(&fast, "fast", false, "Skip deep analysis and post first-pass issues directly")
+	reviewCmd.Flags().BoolVar(&partial, "partial", false, "Post what was confirmed even if max_tokens_per_run runs out")
+	reviewCmd.Flags().BoolVar(&squash, "squash", false, "Review the combined diff as it will land when squash-merged")
+	reviewCmd.Flags().BoolVar(&sinceLast, "since-last", false, "Only review what changed since salty's last review of the PR")
+	reviewCmd.Flags().BoolVar(&plan, "plan", false, "Run the first pass only and print how many API calls the review would make")
+	reviewCmd.Flags().StringVar(&decisionLog, "decision-log", "", "Write a JSON log explaining why each potential issue was posted or skipped")
+	reviewCmd.Flags().StringVar(&sarifPath, "sarif", "", "Write the confirmed findings to a SARIF 2.1.0 file instead of posting")
+	reviewCmd.Flags().StringVar(&author, "by", "", "Only review files this GitHub user contributed commits to")
+	reviewCmd.Flags().BoolVar(&allowRequestChanges, "allow-request-changes", false, "Request changes without asking, even with confirm_request_changes set")
+	reviewCmd.Flags().BoolVar(&approveClean, "approve-clean", false, "Approve the PR when the review finds nothing, like approve_clean_prs")
+	reviewCmd.Flags().BoolVar(&allowDraft, "allow-draft", false, "Review the PR even if it's a draft, like review_drafts")
+	reviewCmd.Flags().StringVar(&minSeverity, "min-severity", "", "Leave out findings less severe than this: critical, major, minor or nit")
+	reviewCmd.Flags().BoolVar(&noCache, "no-cache", false, "Analyze every file again instead of reusing the last review of unchanged files")
+	reviewCmd.Flags().StringSliceVar(&compareStyles, "compare-styles", nil, "Analyze once and print the comments in each of these writing styles (nothing is posted)")
+	reviewCmd.Flags().BoolVar(&newCodeOnly, "new-code-only", false, "Ignore code that was only moved or renamed and review just the new logic")
+	reviewCmd.Flags().BoolVar(&fromStdin, "stdin", false, "Review a unified diff read from stdin instead of a PR (nothing is posted)")
+	reviewCmd.Flags().StringVar(&diffFile, "diff", "", "Review a unified diff read from this file instead of a PR (nothing is posted)")
+	reviewCmd.Flags().BoolVar(&waitForRateLimit, "wait-for-rate-limit", false, "Wait for the GitHub quota to reset instead of stopping when it runs low")
+	reviewCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the review as JSON on stdout, with progress on stderr")
+	reviewCmd.Flags().StringSliceVar(&requestReviewers, "request-reviewer", nil, "Request a re-review from this user after requesting changes (repeatable)")
 
 	// Defend command
 	defendCmd := &cobra.Command{
-		Use:   "defend <pr-reference>",
+		Use:   "defend <pr-reference | owner/repo>",
 		Short: "Defend your PR against reviewer comments",
 		Long: `Analyze and respond to comments on your PR.
 
@@ -64,12 +141,23 @@ The defender will:
 
 Examples:
   salty defend owner/repo#123
-  salty defend --dry-run https://github.com/owner/repo/pull/42`,
+  salty defend --dry-run https://github.com/owner/repo/pull/42
+  salty defend --resolve-conceded owner/repo#123
+
+  # Defend every open PR you authored, 4 at a time, most recently updated first
+  salty defend --all --concurrency 4 --order updated owner/repo`,
 		Args: cobra.ExactArgs(1),
 		RunE: runDefend,
 	}
 	defendCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be posted without actually posting")
-	defendCmd.Flags().BoolVar(&interactive, "interactive", false, "Confirm each response before posting")
+	defendCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors, warnings and the final result")
+	defendCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also print every AI prompt and its token counts")
+	defendCmd.Flags().BoolVar(&interactive, "interactive", false, "Ask before posting each response: post it, skip it or edit it in $EDITOR")
+	defendCmd.Flags().BoolVar(&resolveConceded, "resolve-conceded", false, "Resolve the review thread of every comment conceded")
+	defendCmd.Flags().BoolVar(&allowDraft, "allow-draft", false, "Defend draft PRs too, like review_drafts")
+	defendCmd.Flags().BoolVar(&defendAll, "all", false, "Defend every open PR you authored in owner/repo")
+	defendCmd.Flags().IntVar(&concurrency, "concurrency", 3, "With --all, how many PRs to defend at once")
+	defendCmd.Flags().StringVar(&defendOrder, "order", defender.OrderNumber, "With --all, the order PRs are started in: number (oldest first) or updated (most recent first)")
 
 	// Config command
 	configCmd := &cobra.Command{
@@ -89,12 +177,65 @@ Examples:
 		Long: `Set a configuration value.
 
 Available keys:
-  writing_style      - corporate, passive_aggressive, tech_bro, academic
+  writing_style      - corporate, passive_aggressive, tech_bro, academic, custom
+  custom_style_path  - File with the prompts for writing_style custom
   nitpicky_level     - 1-10 (1=lenient, 10=maximum nitpicking)
   github_token       - Your GitHub personal access token
-  ai_api_url         - AI API endpoint (OpenAI-compatible)
+  ai_provider        - openai (or any OpenAI-compatible API) or anthropic
+  ai_api_url         - AI API endpoint
   ai_api_key         - AI API key
+  ai_org             - Sent as the OpenAI-Organization header (empty = off)
+  ai_extra_headers.<Name> - Header added to every AI request (empty = remove)
   ai_model           - AI model name
+  ai_model_analysis  - Model for analysis steps (empty = ai_model)
+  ai_model_formatting - Model for formatting review comments (empty = ai_model)
+  temperature        - 0.0-2.0, lower is more deterministic
+  max_tokens         - Longest AI response per request
+  respect_codeowners - true/false, only review files you own per CODEOWNERS
+  guidelines_file    - Repo path of a style guide to follow, e.g. CONTRIBUTING.md (empty = off)
+  review_images      - true/false, list added/changed images and their sizes
+  analyze_dependencies - true/false, summarize dependency changes
+  detect_secrets     - true/false, flag and redact credentials added in the diff
+  redact_secrets_in_prompts - true/false, keep detected secrets out of AI prompts
+  flag_todos         - true/false, flag new TODO/FIXME/HACK/XXX comments as nits
+  max_file_bytes     - Skip full-file context above this size (0 = no limit)
+  approve_if_only_nits - true/false, approve when every comment is a nit
+  approve_clean_prs  - true/false, approve when the review finds nothing
+  review_drafts      - true/false, review and defend draft PRs
+  stream_first_pass  - true/false, deep-analyze issues while the first pass streams
+  set_commit_status  - true/false, set a salty/review commit status after reviewing
+  post_digest        - true/false, post a comment linking to every inline comment
+  remap_outdated_comments - true/false, re-map comments if new commits land mid-review
+  max_file_diff_lines - Skip files with more changed lines (0 = no limit)
+  max_comments_per_file - Comments per file, rest listed in the summary (0 = no limit)
+  max_comments       - Comments in the whole review, rest listed in the summary (0 = no limit)
+  max_comments_per_review - Comments per review, rest in follow-up reviews (0 = no limit)
+  max_comment_chars  - Condense longer comments and responses (0 = no limit)
+  max_related_files  - Related files in deep-analysis context (0 = no limit)
+  max_related_bytes  - Bytes of related-file context (0 = no limit)
+  max_first_pass_tokens - Split bigger first-pass prompts by file (0 = no limit)
+  concurrency        - Issues deep-analyzed at once
+  label_on_changes   - Label to apply when requesting changes (empty = off)
+  confirm_request_changes - true/false, ask before requesting changes
+  label_on_approve   - Label to apply when the review is clean (empty = off)
+  review_signature   - Appended to review comments (empty = none)
+  defense_signature  - Appended to defense replies (empty = none)
+  summary_greeting   - Text before the summary ({author}, {pr_title})
+  summary_signoff    - Text after the summary ({author}, {pr_title})
+  show_verdict       - true/false, start the summary with a one-line verdict
+  severity_from_confidence - true/false, derive severity from confidence
+  post_mode          - review (PR review) or checks (check run annotations)
+  verbosity          - quiet, normal or verbose (AI prompts and token counts)
+  defense_order      - chronological, file, severity
+  concede_with_suggestion - true/false, concessions include a suggested fix
+  defend_submitted_only - true/false, wait for reviews to be submitted before defending
+  defense_aggressiveness - 1-10 (1=collaborative, 10=belligerent)
+  concede_threshold  - 0-100, concede comments judged at least this valid
+  max_tokens_per_run - Stop after this many AI tokens (0 = no limit)
+  price_per_1k_prompt_tokens     - USD per 1000 prompt tokens, for cost estimates
+  price_per_1k_completion_tokens - USD per 1000 completion tokens
+  org_config         - Org guardrails: URL, github:owner/repo/path[@ref] or a file
+  org_config_sha256  - Only accept the org config if its content has this hash
 
 Examples:
   salty config set writing_style tech_bro
@@ -111,16 +252,64 @@ Examples:
 Lists:
   liked_reviewer     - Go easy on these reviewers
   disliked_reviewer  - Extra scrutiny for these reviewers
+  defense_ignore     - Never auto-defend against these users
 
 Examples:
   salty config add liked_reviewer cool_dev
-  salty config add disliked_reviewer that_guy`,
+  salty config add disliked_reviewer that_guy
+  salty config add defense_ignore tech_lead`,
 		Args: cobra.ExactArgs(2),
 		RunE: runConfigAdd,
 	}
 
-	configCmd.AddCommand(configShowCmd, configSetCmd, configAddCmd)
-	rootCmd.AddCommand(initCmd, reviewCmd, defendCmd, configCmd)
+	configUnsetCmd := &cobra.Command{
+		Use:   "unset <key> [username]",
+		Short: "Reset a configuration value or remove a user from a list",
+		Long: `Put a configuration value back to its default. Keys without a default,
+like github_token, are cleared. Takes the same keys as config set.
+
+With a username, removes the user from liked_reviewer, disliked_reviewer or
+defense_ignore instead.
+
+Examples:
+  salty config unset github_token
+  salty config unset ai_api_url
+  salty config unset ai_extra_headers.X-Tenant-Id
+  salty config unset liked_reviewer cool_dev`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runConfigUnset,
+	}
+
+	// Status command
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check the config, GitHub token, rate limit and AI API",
+		Long: `Check that everything a review needs works before running one:
+the config loads, GitHub accepts the token, how much of the GitHub API quota
+is left, and the AI API answers a one-token request with your key and model.`,
+		Args: cobra.NoArgs,
+		RunE: runStatus,
+	}
+
+	// Cache command
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the review cache",
+	}
+
+	cacheClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Delete the cached results of earlier reviews",
+		Long: `Delete the first-pass and deep analysis results kept in
+~/.salty-reviewer/cache/. Re-reviews of a PR reuse them for files that haven't
+changed; after clearing, the next review of every PR starts from scratch.`,
+		Args: cobra.NoArgs,
+		RunE: runCacheClear,
+	}
+
+	configCmd.AddCommand(configShowCmd, configSetCmd, configAddCmd, configUnsetCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(initCmd, reviewCmd, defendCmd, statusCmd, configCmd, cacheCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -141,7 +330,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 	cfg.GitHubToken = strings.TrimSpace(token)
 
 	// AI API settings
-	fmt.Print("\nAI API URL (default: https://api.openai.com/v1): ")
+	fmt.Print("\nAI provider - openai (or compatible) or anthropic (default: openai): ")
+	provider, _ := reader.ReadString('\n')
+	if strings.TrimSpace(provider) == config.ProviderAnthropic {
+		cfg.AIProvider = config.ProviderAnthropic
+		cfg.AIApiURL = "https://api.anthropic.com/v1"
+		cfg.AIModel = "claude-sonnet-4-5"
+	}
+
+	fmt.Printf("AI API URL (default: %s): ", cfg.AIApiURL)
 	apiURL, _ := reader.ReadString('\n')
 	apiURL = strings.TrimSpace(apiURL)
 	if apiURL != "" {
@@ -152,7 +349,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 	apiKey, _ := reader.ReadString('\n')
 	cfg.AIApiKey = strings.TrimSpace(apiKey)
 
-	fmt.Print("AI Model (default: gpt-4): ")
+	fmt.Printf("AI Model (default: %s): ", cfg.AIModel)
 	model, _ := reader.ReadString('\n')
 	model = strings.TrimSpace(model)
 	if model != "" {
@@ -209,10 +406,303 @@ func runReview(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := applyVerbosity(cfg); err != nil {
+		return err
+	}
 
 	r := reviewer.NewReviewer(cfg)
-	_, err = r.Review(args[0], dryRun)
-	return err
+	opts := reviewer.ReviewOptions{
+		DryRun:    dryRun || showPayload,
+		PerCommit: perCommit,
+		Fast:      fast,
+		Partial:   partial,
+		Plan:      plan,
+		Squash:    squash,
+		SinceLast: sinceLast,
+
+		NewCodeOnly: newCodeOnly,
+		Author:      strings.TrimPrefix(author, "@"),
+
+		RequestReviewers:    requestReviewers,
+		AllowRequestChanges: allowRequestChanges,
+		ApproveClean:        approveClean,
+		AllowDraft:          allowDraft,
+		NoCache:             noCache,
+		ShowPayload:         showPayload,
+		WaitForRateLimit:    waitForRateLimit,
+	}
+	if minSeverity != "" {
+		opts.MinSeverity = reviewer.ParseSeverity(minSeverity)
+		if opts.MinSeverity == "" {
+			return fmt.Errorf("--min-severity must be critical, major, minor or nit")
+		}
+	}
+	for _, s := range compareStyles {
+		style, err := parseWritingStyle(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		opts.CompareStyles = append(opts.CompareStyles, style)
+	}
+
+	if fromStdin && diffFile != "" {
+		return fmt.Errorf("--stdin and --diff can't be combined")
+	}
+	if fromStdin || diffFile != "" {
+		return runReviewDiff(r, opts, diffFile)
+	}
+	if jsonOutput && (plan || len(opts.CompareStyles) > 0) {
+		return fmt.Errorf("--json can't be combined with --plan or --compare-styles")
+	}
+	if sarifPath != "" {
+		if plan {
+			return fmt.Errorf("--sarif can't be combined with --plan")
+		}
+		opts.DryRun = true // a SARIF report replaces posting
+	}
+	if isTerminal(os.Stdin) {
+		opts.ConfirmRequestChanges = confirmRequestChanges
+	}
+	if interactive {
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("--interactive needs a terminal to ask on")
+		}
+		opts.ConfirmComment = confirmComment
+	}
+
+	// With --json, stdout is only the result
+	progressOut := io.Writer(os.Stdout)
+	if jsonOutput {
+		progressOut = os.Stderr
+		r.SetOutput(progressOut)
+	} else {
+		r.SetLiveOutput(isTerminal(os.Stdout))
+	}
+	progress := logging.New(progressOut, cfg.Verbosity)
+
+	result, err := r.Review(args[0], opts)
+	if err != nil {
+		return err
+	}
+
+	if decisionLog != "" {
+		if err := reviewer.WriteDecisionLog(decisionLog, args[0], result); err != nil {
+			return err
+		}
+		fmt.Fprintf(progress, "🧾 Decision log written to %s\n", decisionLog)
+	}
+	if sarifPath != "" {
+		if err := reviewer.WriteSARIF(sarifPath, args[0], result); err != nil {
+			return err
+		}
+		fmt.Fprintf(progress, "🛡️  SARIF report with %d finding(s) written to %s\n", len(result.Findings), sarifPath)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newJSONResult(result))
+	}
+	return nil
+}
+
+// jsonComment is one finding in the machine-readable --json and --stdin output
+type jsonComment struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line,omitempty"` // set for multi-line comments
+	Line      int    `json:"line"`
+	Side      string `json:"side,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Body      string `json:"body"`
+}
+
+// jsonResult is written to stdout by --json and --stdin so CI and hooks can
+// parse it
+type jsonResult struct {
+	Summary  string               `json:"summary"`
+	Draft    bool                 `json:"draft"`
+	Comments []jsonComment        `json:"comments"`
+	Stats    reviewer.ReviewStats `json:"stats"`
+}
+
+func newJSONResult(result *reviewer.ReviewResult) jsonResult {
+	out := jsonResult{
+		Summary:  result.Summary,
+		Draft:    result.Draft,
+		Comments: []jsonComment{},
+		Stats:    result.Stats,
+	}
+	for _, c := range result.Comments {
+		out.Comments = append(out.Comments, jsonComment{
+			Path:      c.Path,
+			StartLine: c.StartLine,
+			Line:      c.Line,
+			Side:      c.Side,
+			Severity:  c.Severity,
+			Body:      c.Body,
+		})
+	}
+	return out
+}
+
+// runReviewDiff reviews a unified diff from a file, or from stdin if path is
+// empty. Progress and the human-readable review go to stderr, JSON goes to
+// stdout.
+func runReviewDiff(r *reviewer.Reviewer, opts reviewer.ReviewOptions, path string) error {
+	if decisionLog != "" || sarifPath != "" {
+		return fmt.Errorf("--decision-log and --sarif are not supported with --stdin or --diff")
+	}
+
+	var input []byte
+	var err error
+	if path != "" {
+		input, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read diff: %w", err)
+		}
+	} else {
+		if isTerminal(os.Stdin) {
+			return fmt.Errorf("--stdin expects a diff to be piped in, e.g. git diff origin/main | salty review --stdin")
+		}
+		input, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read diff from stdin: %w", err)
+		}
+	}
+
+	files := diff.ParseUnified(string(input))
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "✨ No changes to review")
+		return json.NewEncoder(os.Stdout).Encode(jsonResult{Comments: []jsonComment{}})
+	}
+
+	r.SetOutput(os.Stderr)
+	result, err := r.ReviewDiff(files, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Plan {
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr, "\n"+result.Summary)
+	for _, c := range result.Comments {
+		fmt.Fprintf(os.Stderr, "\n📍 %s:%d\n%s\n", c.Path, c.Line, c.Body)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newJSONResult(result))
+}
+
+// applyVerbosity lets --quiet and --verbose override the configured verbosity
+func applyVerbosity(cfg *config.Config) error {
+	switch {
+	case quiet && verbose:
+		return fmt.Errorf("--quiet and --verbose can't be combined")
+	case quiet:
+		cfg.Verbosity = config.VerbosityQuiet
+	case verbose:
+		cfg.Verbosity = config.VerbosityVerbose
+	}
+	return nil
+}
+
+// stdinReader is shared by every prompt, so nothing typed ahead is lost
+// between them
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// confirmRequestChanges asks on the terminal whether a review may request changes
+func confirmRequestChanges(result *reviewer.ReviewResult) bool {
+	fmt.Fprintf(os.Stderr, "\n⚠️  This review would REQUEST CHANGES with %d comment(s). Go ahead? [y/N]: ", len(result.Comments))
+	answer, _ := stdinReader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// confirmComment asks on the terminal whether to post a review comment
+func confirmComment(c *github.ReviewComment) (string, bool) {
+	return confirmPost(fmt.Sprintf("Comment on %s:%d", c.Path, c.Line), c.Body)
+}
+
+// confirmResponse asks on the terminal whether to post a defense response
+func confirmResponse(r defender.CommentResponse) (string, bool) {
+	title := fmt.Sprintf("Reply to @%s on %s (%s)", r.OriginalComment.User, r.OriginalComment.Where(), r.Action)
+	return confirmPost(title, r.Response)
+}
+
+// confirmPost shows text and asks whether to post it. y posts it, edit opens
+// it in an editor and asks again, anything else skips it.
+func confirmPost(title, text string) (string, bool) {
+	for {
+		fmt.Fprintf(os.Stderr, "\n💬 %s\n%s\n\nPost this? [y/N/edit]: ", title, text)
+		answer, _ := stdinReader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return text, true
+		case "e", "edit":
+			edited, err := editText(text)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+				continue
+			}
+			if strings.TrimSpace(edited) == "" {
+				fmt.Fprintln(os.Stderr, "Nothing left after editing - skipping")
+				return "", false
+			}
+			text = edited
+		default:
+			return "", false
+		}
+	}
+}
+
+// editText opens text in $VISUAL or $EDITOR (vi if neither is set) and
+// returns it as saved
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "salty-*.md")
+	if err != nil {
+		return "", fmt.Errorf("could not create a file to edit: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not write a file to edit: %w", err)
+	}
+
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("could not read the edited text: %w", err)
+	}
+	return strings.TrimRight(string(edited), "\n"), nil
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func runDefend(cmd *cobra.Command, args []string) error {
@@ -220,10 +710,107 @@ func runDefend(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := applyVerbosity(cfg); err != nil {
+		return err
+	}
 
 	d := defender.NewDefender(cfg)
-	_, err = d.Defend(args[0], dryRun)
-	return err
+	d.SetLiveOutput(isTerminal(os.Stdout))
+	if interactive && !isTerminal(os.Stdin) {
+		return fmt.Errorf("--interactive needs a terminal to ask on")
+	}
+	if !defendAll {
+		opts := defender.DefendOptions{DryRun: dryRun, ResolveConceded: resolveConceded, AllowDraft: allowDraft}
+		if interactive {
+			opts.ConfirmResponse = confirmResponse
+		}
+		_, err = d.Defend(args[0], opts)
+		return err
+	}
+	if interactive {
+		return fmt.Errorf("--interactive can't be combined with --all, which defends several PRs at once")
+	}
+
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	owner, repo, err := github.ParseRepoReference(args[0])
+	if err != nil {
+		return err
+	}
+	results, err := d.DefendAll(owner, repo, defender.BatchOptions{
+		DryRun:          dryRun,
+		ResolveConceded: resolveConceded,
+		AllowDraft:      allowDraft,
+		Concurrency:     concurrency,
+		Order:           defendOrder,
+	})
+	if err != nil {
+		return err
+	}
+	if n := defender.Failed(results); n > 0 {
+		return fmt.Errorf("%d of %d PRs could not be defended", n, len(results))
+	}
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	n, err := reviewer.ClearCache()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Println("🧹 The review cache is already empty")
+		return nil
+	}
+	fmt.Printf("🧹 Cleared the review cache of %d PR(s)\n", n)
+	return nil
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	failed := 0
+	check := func(name string, detail string, err error) {
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", name, err)
+			failed++
+			return
+		}
+		fmt.Printf("✅ %s: %s\n", name, detail)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		check("Config", "", err)
+		return fmt.Errorf("can't check anything else without a config")
+	}
+	path, _ := config.ConfigPath()
+	if _, err := os.Stat(path); err != nil {
+		path = "no config file, using SALTY_* environment variables"
+	}
+	check("Config", path, nil)
+	if warning := cfg.StyleWarning(); warning != "" {
+		fmt.Printf("⚠️  Custom style: %s\n", warning)
+	}
+
+	gh := github.NewClient(cfg.GitHubToken)
+	login, err := gh.GetAuthenticatedUser()
+	check("GitHub token", "authenticated as @"+login, err)
+
+	limit, err := gh.RateLimit()
+	detail := ""
+	if err == nil {
+		detail = fmt.Sprintf("%d of %d requests left, resets at %s",
+			limit.Remaining, limit.Limit, limit.Reset.Local().Format("15:04:05"))
+	}
+	check("GitHub rate limit", detail, err)
+
+	aiClient := aiconfig.NewClient(cfg)
+	check("AI API", cfg.AIApiURL+" answered", aiClient.Ping())
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
 }
 
 func runConfigShow(cmd *cobra.Command, args []string) error {
@@ -238,19 +825,128 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	fmt.Println("🧂 Salty Code Reviewer Configuration")
 	fmt.Println("─────────────────────────────────────────")
 	fmt.Printf("Writing Style:      %s\n", cfg.WritingStyle)
+	if cfg.CustomStylePath != "" {
+		fmt.Printf("Custom Style:       %s\n", cfg.CustomStylePath)
+	}
+	if warning := cfg.StyleWarning(); warning != "" {
+		fmt.Printf("                    ⚠️  %s\n", warning)
+	}
 	fmt.Printf("Nitpicky Level:     %d/10\n", cfg.NitpickyLevel)
+	fmt.Printf("AI Provider:        %s\n", orDefault(cfg.AIProvider, config.ProviderOpenAI))
 	fmt.Printf("AI API URL:         %s\n", cfg.AIApiURL)
 	fmt.Printf("AI Model:           %s\n", cfg.AIModel)
+	if cfg.AIModelAnalysis != "" || cfg.AIModelFormatting != "" {
+		fmt.Printf("Analysis Model:     %s\n", orDefault(cfg.AIModelAnalysis, cfg.AIModel))
+		fmt.Printf("Formatting Model:   %s\n", orDefault(cfg.AIModelFormatting, cfg.AIModel))
+	}
+	fmt.Printf("Temperature:        %.2g (max %d tokens per response)\n", cfg.Temperature, cfg.MaxTokens)
 	fmt.Printf("GitHub Token:       %s\n", maskToken(cfg.GitHubToken))
 	fmt.Printf("AI API Key:         %s\n", maskToken(cfg.AIApiKey))
+	if cfg.AIOrg != "" {
+		fmt.Printf("AI Organization:    %s\n", cfg.AIOrg)
+	}
+	if len(cfg.ExtraHeaders) > 0 {
+		names := make([]string, 0, len(cfg.ExtraHeaders))
+		for name := range cfg.ExtraHeaders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("AI Extra Headers:")
+		for _, name := range names {
+			fmt.Printf("  %s: %s\n", name, maskHeader(name, cfg.ExtraHeaders[name]))
+		}
+	}
 	fmt.Printf("Liked Reviewers:    %v\n", cfg.LikedReviewers)
 	fmt.Printf("Disliked Reviewers: %v\n", cfg.DislikedReviewers)
+	if len(cfg.Repos) > 0 {
+		names := make([]string, 0, len(cfg.Repos))
+		for name := range cfg.Repos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("Repo Overrides:     %s\n", strings.Join(names, ", "))
+	}
+	fmt.Printf("Confirm Changes:    %t\n", cfg.ConfirmRequestChanges)
+	fmt.Printf("Request Reviewers:  %v\n", cfg.RequestReviewers)
+	fmt.Printf("Defense Ignored:    %v\n", cfg.DefenseIgnoreUsers)
+	fmt.Printf("Post Mode:          %s\n", cfg.PostMode)
+	fmt.Printf("Verbosity:          %s\n", cfg.Verbosity)
+	fmt.Printf("Defense Order:      %s\n", cfg.DefenseOrder)
+	fmt.Printf("Defense Aggression: %d/10\n", cfg.DefenseAggressiveness)
+	fmt.Printf("Concede Threshold:  %d%%\n", cfg.ConcedeThreshold)
+	fmt.Printf("Concede with Fix:   %t\n", cfg.ConcedeWithSuggestion)
+	fmt.Printf("Submitted Only:     %t\n", cfg.DefendSubmittedOnly)
+	fmt.Printf("Max Tokens Per Run: %d\n", cfg.MaxTokensPerRun)
+	if cfg.PricePer1KPromptTokens > 0 || cfg.PricePer1KCompletionTokens > 0 {
+		fmt.Printf("Price per 1K:       $%g prompt, $%g completion\n", cfg.PricePer1KPromptTokens, cfg.PricePer1KCompletionTokens)
+	}
+	fmt.Printf("Structured Stop:    %q\n", cfg.StructuredStop)
+	fmt.Printf("Respect CODEOWNERS: %t\n", cfg.RespectCodeowners)
+	if len(cfg.IncludeGlobs) > 0 {
+		fmt.Printf("Include Globs:      %v\n", cfg.IncludeGlobs)
+	}
+	if len(cfg.ExcludeGlobs) > 0 {
+		fmt.Printf("Exclude Globs:      %v\n", cfg.ExcludeGlobs)
+	}
+	fmt.Printf("Max Diff Lines:     %d\n", cfg.MaxFileDiffLines)
+	fmt.Printf("Guidelines File:    %s\n", orDefault(cfg.GuidelinesFile, "(none)"))
+	fmt.Printf("Review Images:      %t\n", cfg.ReviewImages)
+	fmt.Printf("Analyze Deps:       %t (trusted: %v)\n", cfg.AnalyzeDependencies, cfg.TrustedDependencySources)
+	fmt.Printf("Detect Secrets:     %t (redact in prompts: %t)\n", cfg.DetectSecrets, cfg.RedactSecretsInPrompts)
+	fmt.Printf("Flag TODOs:         %t (keywords: %v)\n", cfg.FlagTodos, cfg.TodoKeywords)
+	fmt.Printf("Max File Bytes:     %d\n", cfg.MaxFileBytes)
+	fmt.Printf("Max Comment Chars:  %d\n", cfg.MaxCommentChars)
+	if len(cfg.CommentHook) > 0 {
+		fmt.Printf("Comment Hook:       %v (timeout %ds)\n", cfg.CommentHook, cfg.CommentHookTimeoutSecs)
+	}
+	fmt.Printf("Comments Per File:  %d\n", cfg.MaxCommentsPerFile)
+	fmt.Printf("Max Comments:       %d\n", cfg.MaxComments)
+	fmt.Printf("Comments/Review:    %d\n", cfg.MaxCommentsPerReview)
+	fmt.Printf("Commit Status:      %t\n", cfg.SetCommitStatus)
+	fmt.Printf("Post Digest:        %t\n", cfg.PostDigest)
+	fmt.Printf("Remap Outdated:     %t\n", cfg.RemapOutdatedComments)
+	fmt.Printf("Stream First Pass:  %t\n", cfg.StreamFirstPass)
+	fmt.Printf("Approve If Nits:    %t\n", cfg.ApproveIfOnlyNits)
+	fmt.Printf("Approve Clean PRs:  %t\n", cfg.ApproveCleanPRs)
+	fmt.Printf("Review Drafts:      %t\n", cfg.ReviewDrafts)
+	if len(cfg.BaseBranchNitpickyRules) > 0 {
+		fmt.Printf("Base Branch Rules:  %v\n", cfg.BaseBranchNitpickyRules)
+	}
+	fmt.Printf("Max Related:        %d files, %d bytes\n", cfg.MaxRelatedFiles, cfg.MaxRelatedBytes)
+	fmt.Printf("Max First Pass:     %d tokens\n", cfg.MaxFirstPassTokens)
+	fmt.Printf("Concurrency:        %d\n", cfg.Concurrency)
+	fmt.Printf("Label on Changes:   %s\n", cfg.LabelOnChanges)
+	fmt.Printf("Label on Approve:   %s\n", cfg.LabelOnApprove)
+	fmt.Printf("Show Verdict:       %t\n", cfg.ShowVerdict)
+	fmt.Printf("Summary Greeting:   %s\n", cfg.SummaryGreeting)
+	fmt.Printf("Summary Sign-off:   %s\n", cfg.SummarySignoff)
+	fmt.Printf("Review Signature:   %s\n", cfg.ReviewSignature)
+	fmt.Printf("Defense Signature:  %s\n", cfg.DefenseSignature)
+	fmt.Printf("Severity from Conf: %t (critical>=%d, major>=%d, minor>=%d)\n", cfg.SeverityFromConfidence,
+		cfg.SeverityMapping.Critical, cfg.SeverityMapping.Major, cfg.SeverityMapping.Minor)
+
+	if policy := cfg.OrgPolicy(); policy != nil {
+		fmt.Println("─────────────────────────────────────────")
+		fmt.Printf("Org Config:         %s (settings above include it)\n", policy.Source)
+		if policy.MaxNitpickyLevel > 0 {
+			fmt.Printf("  Max Nitpicky:     %d\n", policy.MaxNitpickyLevel)
+		}
+		if policy.MaxDefenseAggressiveness > 0 {
+			fmt.Printf("  Max Aggression:   %d\n", policy.MaxDefenseAggressiveness)
+		}
+		if len(policy.AllowedWritingStyles) > 0 {
+			fmt.Printf("  Allowed Styles:   %v\n", policy.AllowedWritingStyles)
+		}
+		if keys := policy.PinnedKeys(); len(keys) > 0 {
+			fmt.Printf("  Pinned:           %s\n", strings.Join(keys, ", "))
+		}
+	}
 
 	return nil
 }
 
 func runConfigSet(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := config.LoadLocal()
 	if err != nil {
 		cfg = config.DefaultConfig()
 	}
@@ -258,20 +954,21 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 	key := args[0]
 	value := args[1]
 
+	// The policy in effect before the change, so a change can't remove it
+	policy, err := config.LoadOrgPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
 	switch key {
 	case "writing_style":
-		switch value {
-		case "corporate":
-			cfg.WritingStyle = config.StyleCorporate
-		case "passive_aggressive":
-			cfg.WritingStyle = config.StylePassiveAggressive
-		case "tech_bro":
-			cfg.WritingStyle = config.StyleTechBro
-		case "academic":
-			cfg.WritingStyle = config.StyleAcademic
-		default:
-			return fmt.Errorf("invalid writing style: %s", value)
+		style, err := parseWritingStyle(value)
+		if err != nil {
+			return err
 		}
+		cfg.WritingStyle = style
+	case "custom_style_path":
+		cfg.CustomStylePath = value
 	case "nitpicky_level":
 		level, err := strconv.Atoi(value)
 		if err != nil || level < 1 || level > 10 {
@@ -280,14 +977,295 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		cfg.NitpickyLevel = level
 	case "github_token":
 		cfg.GitHubToken = value
+	case "ai_provider":
+		switch value {
+		case config.ProviderOpenAI, config.ProviderAnthropic:
+			cfg.AIProvider = value
+		default:
+			return fmt.Errorf("ai_provider must be openai or anthropic")
+		}
 	case "ai_api_url":
+		if err := config.ValidateAIApiURL(value); err != nil {
+			return err
+		}
 		cfg.AIApiURL = value
 	case "ai_api_key":
 		cfg.AIApiKey = value
 	case "ai_model":
 		cfg.AIModel = value
+	case "ai_model_analysis":
+		cfg.AIModelAnalysis = value
+	case "ai_model_formatting":
+		cfg.AIModelFormatting = value
+	case "temperature":
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil || t < 0 || t > 2 {
+			return fmt.Errorf("temperature must be between 0.0 and 2.0")
+		}
+		cfg.Temperature = t
+	case "max_tokens":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("max_tokens must be a positive number")
+		}
+		cfg.MaxTokens = n
+	case "respect_codeowners":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("respect_codeowners must be true or false")
+		}
+		cfg.RespectCodeowners = enabled
+	case "guidelines_file":
+		cfg.GuidelinesFile = value
+	case "confirm_request_changes":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("confirm_request_changes must be true or false")
+		}
+		cfg.ConfirmRequestChanges = enabled
+	case "review_signature":
+		cfg.ReviewSignature = value
+	case "defense_signature":
+		cfg.DefenseSignature = value
+	case "summary_greeting":
+		cfg.SummaryGreeting = value
+	case "summary_signoff":
+		cfg.SummarySignoff = value
+	case "show_verdict":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("show_verdict must be true or false")
+		}
+		cfg.ShowVerdict = enabled
+	case "analyze_dependencies":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("analyze_dependencies must be true or false")
+		}
+		cfg.AnalyzeDependencies = enabled
+	case "detect_secrets":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("detect_secrets must be true or false")
+		}
+		cfg.DetectSecrets = enabled
+	case "redact_secrets_in_prompts":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("redact_secrets_in_prompts must be true or false")
+		}
+		cfg.RedactSecretsInPrompts = enabled
+	case "flag_todos":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("flag_todos must be true or false")
+		}
+		cfg.FlagTodos = enabled
+	case "review_images":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("review_images must be true or false")
+		}
+		cfg.ReviewImages = enabled
+	case "max_file_bytes":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_file_bytes must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxFileBytes = n
+	case "approve_if_only_nits":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("approve_if_only_nits must be true or false")
+		}
+		cfg.ApproveIfOnlyNits = enabled
+	case "approve_clean_prs":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("approve_clean_prs must be true or false")
+		}
+		cfg.ApproveCleanPRs = enabled
+	case "review_drafts":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("review_drafts must be true or false")
+		}
+		cfg.ReviewDrafts = enabled
+	case "stream_first_pass":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("stream_first_pass must be true or false")
+		}
+		cfg.StreamFirstPass = enabled
+	case "set_commit_status":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("set_commit_status must be true or false")
+		}
+		cfg.SetCommitStatus = enabled
+	case "post_digest":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("post_digest must be true or false")
+		}
+		cfg.PostDigest = enabled
+	case "remap_outdated_comments":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("remap_outdated_comments must be true or false")
+		}
+		cfg.RemapOutdatedComments = enabled
+	case "max_file_diff_lines":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_file_diff_lines must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxFileDiffLines = n
+	case "max_comments_per_file":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_comments_per_file must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxCommentsPerFile = n
+	case "max_comments":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_comments must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxComments = n
+	case "max_comments_per_review":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_comments_per_review must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxCommentsPerReview = n
+	case "max_comment_chars":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_comment_chars must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxCommentChars = n
+	case "label_on_changes":
+		cfg.LabelOnChanges = value
+	case "label_on_approve":
+		cfg.LabelOnApprove = value
+	case "severity_from_confidence":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("severity_from_confidence must be true or false")
+		}
+		cfg.SeverityFromConfidence = enabled
+	case "post_mode":
+		switch mode := config.PostMode(value); mode {
+		case config.PostModeReview, config.PostModeChecks:
+			cfg.PostMode = mode
+		default:
+			return fmt.Errorf("invalid post mode: %s", value)
+		}
+	case "verbosity":
+		switch verbosity := config.Verbosity(value); verbosity {
+		case config.VerbosityQuiet, config.VerbosityNormal, config.VerbosityVerbose:
+			cfg.Verbosity = verbosity
+		default:
+			return fmt.Errorf("invalid verbosity: %s", value)
+		}
+	case "defense_order":
+		switch order := config.DefenseOrder(value); order {
+		case config.DefenseOrderChronological, config.DefenseOrderFile, config.DefenseOrderSeverity:
+			cfg.DefenseOrder = order
+		default:
+			return fmt.Errorf("invalid defense order: %s", value)
+		}
+	case "concede_with_suggestion":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("concede_with_suggestion must be true or false")
+		}
+		cfg.ConcedeWithSuggestion = enabled
+	case "defend_submitted_only":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("defend_submitted_only must be true or false")
+		}
+		cfg.DefendSubmittedOnly = enabled
+	case "defense_aggressiveness":
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 1 || level > 10 {
+			return fmt.Errorf("defense_aggressiveness must be 1-10")
+		}
+		cfg.DefenseAggressiveness = level
+	case "concede_threshold":
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 || threshold > 100 {
+			return fmt.Errorf("concede_threshold must be 0-100")
+		}
+		cfg.ConcedeThreshold = threshold
+	case "max_related_files":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_related_files must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxRelatedFiles = n
+	case "max_related_bytes":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_related_bytes must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxRelatedBytes = n
+	case "max_first_pass_tokens":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_first_pass_tokens must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxFirstPassTokens = n
+	case "concurrency":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("concurrency must be a number, at least 1")
+		}
+		cfg.Concurrency = n
+	case "max_tokens_per_run":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_tokens_per_run must be 0 (no limit) or a positive number")
+		}
+		cfg.MaxTokensPerRun = n
+	case "price_per_1k_prompt_tokens", "price_per_1k_completion_tokens":
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			return fmt.Errorf("%s must be a price in USD, 0 or more", key)
+		}
+		if key == "price_per_1k_prompt_tokens" {
+			cfg.PricePer1KPromptTokens = price
+		} else {
+			cfg.PricePer1KCompletionTokens = price
+		}
+	case "ai_org":
+		cfg.AIOrg = value
+	case "org_config":
+		cfg.OrgConfig = value
+	case "org_config_sha256":
+		cfg.OrgConfigSHA256 = value
 	default:
-		return fmt.Errorf("unknown config key: %s", key)
+		name, ok := strings.CutPrefix(key, "ai_extra_headers.")
+		if !ok || name == "" {
+			return fmt.Errorf("unknown config key: %s", key)
+		}
+		if value == "" {
+			delete(cfg.ExtraHeaders, name)
+			break
+		}
+		if cfg.ExtraHeaders == nil {
+			cfg.ExtraHeaders = make(map[string]string)
+		}
+		cfg.ExtraHeaders[name] = value
+	}
+
+	// Org guardrails win over anything set locally
+	if policy != nil {
+		if err := policy.Check(cfg, key); err != nil {
+			return err
+		}
 	}
 
 	if err := cfg.Save(); err != nil {
@@ -299,7 +1277,7 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigAdd(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := config.LoadLocal()
 	if err != nil {
 		return err
 	}
@@ -314,13 +1292,89 @@ func runConfigAdd(cmd *cobra.Command, args []string) error {
 	case "disliked_reviewer":
 		cfg.AddDislikedReviewer(username)
 		fmt.Printf("✅ Added @%s to disliked reviewers (extra scrutiny mode)\n", username)
+	case "defense_ignore":
+		cfg.AddDefenseIgnoredUser(username)
+		fmt.Printf("✅ Added @%s to defense ignore list (you'll reply to them yourself)\n", username)
 	default:
-		return fmt.Errorf("unknown list: %s (use liked_reviewer or disliked_reviewer)", list)
+		return fmt.Errorf("unknown list: %s (use liked_reviewer, disliked_reviewer or defense_ignore)", list)
 	}
 
 	return cfg.Save()
 }
 
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	if strings.TrimSpace(args[0]) == "" {
+		return fmt.Errorf("config key can't be empty")
+	}
+	cfg, err := config.LoadLocal()
+	if err != nil {
+		return err
+	}
+
+	key := args[0]
+	if len(args) == 2 {
+		return removeFromList(cfg, key, args[1])
+	}
+
+	// The policy in effect before the change, so unsetting can't remove it
+	policy, err := config.LoadOrgPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Unset(key); err != nil {
+		return err
+	}
+
+	// The default may still break the org guardrails, e.g. a pinned model
+	if policy != nil {
+		if err := policy.Check(cfg, key); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Unset %s\n", key)
+	return nil
+}
+
+// removeFromList takes a user off one of the lists config add fills
+func removeFromList(cfg *config.Config, list, username string) error {
+	var removed bool
+	switch list {
+	case "liked_reviewer":
+		removed = cfg.RemoveLikedReviewer(username)
+	case "disliked_reviewer":
+		removed = cfg.RemoveDislikedReviewer(username)
+	case "defense_ignore":
+		removed = cfg.RemoveDefenseIgnoredUser(username)
+	default:
+		return fmt.Errorf("unknown list: %s (use liked_reviewer, disliked_reviewer or defense_ignore)", list)
+	}
+
+	if !removed {
+		fmt.Printf("@%s is not on %s, nothing to do\n", username, list)
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Removed @%s from %s\n", username, list)
+	return nil
+}
+
+// parseWritingStyle checks a writing style name given on the command line
+func parseWritingStyle(value string) (config.WritingStyle, error) {
+	switch style := config.WritingStyle(value); style {
+	case config.StyleCorporate, config.StylePassiveAggressive, config.StyleTechBro, config.StyleAcademic, config.StyleCustom:
+		return style, nil
+	default:
+		return "", fmt.Errorf("invalid writing style: %s", value)
+	}
+}
+
 func maskToken(token string) string {
 	if token == "" {
 		return "(not set)"
@@ -330,3 +1384,22 @@ func maskToken(token string) string {
 	}
 	return token[:4] + "..." + token[len(token)-4:]
 }
+
+// maskHeader masks the value of a header whose name suggests a secret
+func maskHeader(name, value string) string {
+	lower := strings.ToLower(name)
+	for _, hint := range []string{"auth", "key", "token", "secret", "password", "signature", "cookie"} {
+		if strings.Contains(lower, hint) {
+			return maskToken(value)
+		}
+	}
+	return value
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// anthropicVersion is the Messages API version requests are written for
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider speaks the Anthropic Messages API: the system prompt is
+// a top-level field instead of a message, and the key goes in x-api-key
+type anthropicProvider struct{}
+
+// anthropicRequest is the request body of the Messages API
+type anthropicRequest struct {
+	Model         string    `json:"model"`
+	System        string    `json:"system,omitempty"`
+	Messages      []Message `json:"messages"`
+	Temperature   float64   `json:"temperature"`
+	MaxTokens     int       `json:"max_tokens"`
+	StopSequences []string  `json:"stop_sequences,omitempty"`
+	Stream        bool      `json:"stream,omitempty"`
+}
+
+// anthropicUsage is reported in input and output tokens, without a total
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicResponse is the answer of the Messages API
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage anthropicUsage  `json:"usage"`
+	Error *anthropicError `json:"error,omitempty"`
+}
+
+// anthropicEvent is one server-sent event of a streamed answer. Usage comes
+// in two parts: input tokens when the message starts, output tokens at the end.
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	Delta *struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *anthropicError `json:"error,omitempty"`
+}
+
+func (anthropicProvider) endpoint() string {
+	return "/messages"
+}
+
+func (anthropicProvider) headers(apiKey string) map[string]string {
+	return map[string]string{
+		"x-api-key":         apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+func (anthropicProvider) requestBody(req ChatRequest, stream bool) ([]byte, error) {
+	areq := anthropicRequest{
+		Model:         req.Model,
+		Temperature:   min(req.Temperature, 1), // the API rejects anything above 1
+		MaxTokens:     req.MaxTokens,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if areq.MaxTokens <= 0 {
+		areq.MaxTokens = 4096 // required by the API
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+		} else {
+			areq.Messages = append(areq.Messages, m)
+		}
+	}
+	areq.System = strings.Join(system, "\n\n")
+
+	return json.Marshal(areq)
+}
+
+func (anthropicProvider) parseResponse(body []byte) (string, Usage, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w (body: %s)", err, string(body))
+	}
+	if resp.Error != nil {
+		return "", Usage{}, fmt.Errorf("API error: %s (type: %s)", resp.Error.Message, resp.Error.Type)
+	}
+
+	var text strings.Builder
+	found := false
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+			found = true
+		}
+	}
+	if !found {
+		return "", Usage{}, fmt.Errorf("no text content in response")
+	}
+	return text.String(), usageOf(resp.Usage.InputTokens, resp.Usage.OutputTokens, 0), nil
+}
+
+func (anthropicProvider) parseEvent(data string) (streamEvent, error) {
+	var e anthropicEvent
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return streamEvent{}, fmt.Errorf("failed to parse stream chunk: %w (chunk: %s)", err, data)
+	}
+
+	switch e.Type {
+	case "error":
+		if e.Error != nil {
+			return streamEvent{}, fmt.Errorf("API error: %s (type: %s)", e.Error.Message, e.Error.Type)
+		}
+		return streamEvent{}, fmt.Errorf("API error: %s", data)
+	case "message_start":
+		if e.Message != nil {
+			usage := usageOf(e.Message.Usage.InputTokens, 0, 0)
+			return streamEvent{usage: &usage}, nil
+		}
+	case "content_block_delta":
+		if e.Delta != nil && e.Delta.Type == "text_delta" {
+			return streamEvent{delta: e.Delta.Text}, nil
+		}
+	case "message_delta":
+		if e.Usage != nil {
+			usage := usageOf(0, e.Usage.OutputTokens, 0)
+			return streamEvent{usage: &usage}, nil
+		}
+	case "message_stop":
+		return streamEvent{done: true}, nil
+	}
+	return streamEvent{}, nil
+}
@@ -2,20 +2,141 @@ package ai
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/user/salty-reviewer/internal/clock"
 )
 
-// Client is a generic OpenAI-compatible API client
+// Client is a chat client for OpenAI-compatible APIs and the Anthropic API
 type Client struct {
 	baseURL    string
 	apiKey     string
 	model      string
 	httpClient *http.Client
+	clock      clock.Clock
+	provider   provider       // request and response shape of the API
+	schema     *RequestSchema // overrides provider when set
+	headers    map[string]string
+
+	structuredStop  []string // stop sequences for ChatJSON
+	formattingModel string   // model for ChatFormatting ("" = same as model)
+	temperature     float64  // for Chat, ChatJSON and ChatFormatting
+	maxTokens       int
+
+	debugf func(format string, args ...interface{}) // logs prompts and token counts, nil if off
+
+	meter       *meter // shared with the clients LoggingTo derives
+	tokenBudget int    // 0 means unlimited
+}
+
+// meter totals the tokens used by a client
+type meter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// Usage counts the tokens used by a client's calls so far. Providers that
+// only report a total leave PromptTokens and CompletionTokens at 0.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Sub returns the tokens used since an earlier snapshot
+func (u Usage) Sub(earlier Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens - earlier.PromptTokens,
+		CompletionTokens: u.CompletionTokens - earlier.CompletionTokens,
+		TotalTokens:      u.TotalTokens - earlier.TotalTokens,
+	}
+}
+
+// usageOf fills in a missing total from its parts
+func usageOf(prompt, completion, total int) Usage {
+	if total == 0 {
+		total = prompt + completion
+	}
+	return Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: total}
+}
+
+// ErrBudgetExceeded is returned once the client has used up its token budget
+var ErrBudgetExceeded = errors.New("token budget exceeded")
+
+// Option configures optional Client behavior
+type Option func(*Client)
+
+// WithTransport replaces the HTTP transport, e.g. with a stub RoundTripper in tests
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = rt
+	}
+}
+
+// WithClock replaces the wall clock
+func WithClock(clk clock.Clock) Option {
+	return func(c *Client) {
+		c.clock = clk
+	}
+}
+
+// WithTokenBudget caps the total tokens the client may use. Once the budget is
+// spent every further call fails with ErrBudgetExceeded.
+func WithTokenBudget(tokens int) Option {
+	return func(c *Client) {
+		c.tokenBudget = tokens
+	}
+}
+
+// WithStructuredStop sets the stop sequences used by ChatJSON
+func WithStructuredStop(stop []string) Option {
+	return func(c *Client) {
+		c.structuredStop = stop
+	}
+}
+
+// WithFormattingModel sets a separate, typically cheaper, model for ChatFormatting
+func WithFormattingModel(model string) Option {
+	return func(c *Client) {
+		c.formattingModel = model
+	}
+}
+
+// WithSampling sets the temperature and max tokens used by Chat, ChatJSON and
+// ChatFormatting (0.7 and 4096 unless set)
+func WithSampling(temperature float64, maxTokens int) Option {
+	return func(c *Client) {
+		c.temperature = temperature
+		c.maxTokens = maxTokens
+	}
+}
+
+// WithHeaders adds headers to every request, e.g. for a gateway. They win
+// over the client's own headers.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Client) {
+		c.headers = headers
+	}
+}
+
+// WithDebugLog logs every prompt and the tokens each call used through debugf
+func WithDebugLog(debugf func(format string, args ...interface{})) Option {
+	return func(c *Client) {
+		c.debugf = debugf
+	}
+}
+
+// WithRequestSchema makes the client build requests and read responses using a custom schema
+func WithRequestSchema(schema *RequestSchema) Option {
+	return func(c *Client) {
+		c.schema = schema
+	}
 }
 
 // Message represents a chat message
@@ -28,8 +149,9 @@ type Message struct {
 type ChatRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature float64   `json:"temperature"` // 0 is a valid choice, so always sent
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
 }
 
 // ChatResponse is the response from chat completions
@@ -59,72 +181,263 @@ type ChatResponse struct {
 }
 
 // NewClient creates a new AI client
-func NewClient(baseURL, apiKey, model string) *Client {
-	// Ensure baseURL doesn't have trailing slash
-	baseURL = strings.TrimRight(baseURL, "/")
-
-	return &Client{
-		baseURL: baseURL,
+func NewClient(baseURL, apiKey, model string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: normalizeBaseURL(baseURL),
 		apiKey:  apiKey,
 		model:   model,
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		clock:       clock.Real{},
+		meter:       &meter{},
+		provider:    openAIProvider{},
+		temperature: 0.7,
+		maxTokens:   4096,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
+
+// LoggingTo returns a client like c that logs prompts and token counts
+// through debugf instead, e.g. into the buffered output of one of several
+// concurrent calls. Token usage and the budget stay shared with c.
+func (c *Client) LoggingTo(debugf func(format string, args ...interface{})) *Client {
+	logged := *c
+	logged.debugf = debugf
+	return &logged
+}
+
+// Clock is the clock the client was created with, the wall clock by default
+func (c *Client) Clock() clock.Clock {
+	return c.clock
+}
+
+// normalizeBaseURL turns what people paste as ai_api_url into the base the
+// endpoints are added to: without a trailing slash, an endpoint path like
+// /chat/completions or a doubled /v1
+func normalizeBaseURL(raw string) string {
+	base := strings.TrimRight(strings.TrimSpace(raw), "/")
+	for _, endpoint := range []string{openAIProvider{}.endpoint(), anthropicProvider{}.endpoint()} {
+		base = strings.TrimSuffix(base, endpoint)
+	}
+	for strings.HasSuffix(base, "/v1/v1") {
+		base = strings.TrimSuffix(base, "/v1")
+	}
+	return base
 }
 
 // Chat sends a chat completion request and returns the response
 func (c *Client) Chat(messages []Message) (string, error) {
-	return c.ChatWithOptions(messages, 0.7, 4096)
+	return c.ChatWithOptions(messages, c.temperature, c.maxTokens, nil)
 }
 
-// ChatWithOptions sends a chat completion request with custom temperature and max tokens
-func (c *Client) ChatWithOptions(messages []Message, temperature float64, maxTokens int) (string, error) {
-	req := ChatRequest{
-		Model:       c.model,
+// ChatJSON is Chat for prompts that ask for a JSON answer. It applies the
+// structured stop sequences so chatty models don't ramble on after the JSON.
+func (c *Client) ChatJSON(messages []Message) (string, error) {
+	return c.ChatWithOptions(messages, c.temperature, c.maxTokens, c.structuredStop)
+}
+
+// ChatFormatting is Chat for purely stylistic calls, such as turning an
+// analyzed issue into a review comment. It uses the formatting model if one is set.
+func (c *Client) ChatFormatting(messages []Message) (string, error) {
+	model := c.model
+	if c.formattingModel != "" {
+		model = c.formattingModel
+	}
+	return c.chat(model, messages, c.temperature, c.maxTokens, nil)
+}
+
+// ChatWithOptions sends a chat completion request with custom temperature, max
+// tokens and stop sequences (nil for none)
+func (c *Client) ChatWithOptions(messages []Message, temperature float64, maxTokens int, stop []string) (string, error) {
+	return c.chat(c.model, messages, temperature, maxTokens, stop)
+}
+
+// chat sends a chat completion request to the given model
+func (c *Client) chat(model string, messages []Message, temperature float64, maxTokens int, stop []string) (string, error) {
+	if err := c.checkBudget(); err != nil {
+		return "", err
+	}
+	c.logPrompt(model, messages)
+
+	if c.schema != nil {
+		return c.chatWithSchema(model, messages, temperature, maxTokens, stop)
+	}
+
+	body, err := c.provider.requestBody(ChatRequest{
+		Model:       model,
 		Messages:    messages,
 		Temperature: temperature,
 		MaxTokens:   maxTokens,
+		Stop:        stop,
+	}, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	body, err := json.Marshal(req)
+	respBody, err := c.post(c.provider.endpoint(), body, c.provider.headers(c.apiKey))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
+	content, usage, err := c.provider.parseResponse(respBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
+	}
+
+	c.recordUsage(usage)
+
+	return content, nil
+}
+
+// Ping sends a one-token request to each configured model, to check that
+// the API key and model names work
+func (c *Client) Ping() error {
+	models := []string{c.model}
+	if c.formattingModel != "" && c.formattingModel != c.model {
+		models = append(models, c.formattingModel)
+	}
+	for _, model := range models {
+		if _, err := c.chat(model, []Message{UserMessage("ping")}, 0, 1, nil); err != nil {
+			return fmt.Errorf("model %s: %w", model, err)
+		}
+	}
+	return nil
+}
+
+// TokenBudget returns the configured token budget (0 = unlimited)
+func (c *Client) TokenBudget() int {
+	return c.tokenBudget
+}
+
+// checkBudget fails once the tokens used so far have reached the budget
+func (c *Client) checkBudget() error {
+	c.meter.mu.Lock()
+	defer c.meter.mu.Unlock()
+
+	if c.tokenBudget > 0 && c.meter.usage.TotalTokens >= c.tokenBudget {
+		return fmt.Errorf("%w (%d of %d tokens used)", ErrBudgetExceeded, c.meter.usage.TotalTokens, c.tokenBudget)
+	}
+	return nil
+}
+
+// Usage returns the tokens used by all calls so far
+func (c *Client) Usage() Usage {
+	c.meter.mu.Lock()
+	defer c.meter.mu.Unlock()
+
+	return c.meter.usage
+}
+
+// recordUsage adds the tokens reported for a call to the running total
+func (c *Client) recordUsage(u Usage) {
+	c.meter.mu.Lock()
+	defer c.meter.mu.Unlock()
+
+	c.meter.usage.PromptTokens += u.PromptTokens
+	c.meter.usage.CompletionTokens += u.CompletionTokens
+	c.meter.usage.TotalTokens += u.TotalTokens
+
+	if c.debugf != nil {
+		c.debugf("AI tokens: %d prompt, %d completion, %d total", u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+	}
+}
+
+// logPrompt logs the messages about to be sent to model
+func (c *Client) logPrompt(model string, messages []Message) {
+	if c.debugf == nil {
+		return
+	}
+	c.debugf("AI request to %s:", model)
+	for _, m := range messages {
+		c.debugf("[%s]\n%s", m.Role, m.Content)
+	}
+}
+
+// post sends a JSON body to an endpoint under the base URL and returns the raw response body
+func (c *Client) post(endpoint string, body []byte, headers map[string]string) ([]byte, error) {
+	httpReq, err := http.NewRequest("POST", c.baseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+	c.setHeaders(httpReq)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w (body: %s)", err, string(respBody))
+	if !looksLikeJSON(resp.Header.Get("Content-Type"), respBody) {
+		return nil, &GatewayError{
+			StatusCode:  resp.StatusCode,
+			Status:      resp.Status,
+			ContentType: resp.Header.Get("Content-Type"),
+			BodyPreview: previewBody(respBody, 200),
+		}
 	}
 
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
+	return respBody, nil
+}
+
+// setHeaders adds the extra headers to a request
+func (c *Client) setHeaders(req *http.Request) {
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
 	}
+}
+
+// GatewayError is returned when the endpoint answers with something other than
+// JSON, typically an HTML error page or plain-text 502 from a proxy in front of the API
+type GatewayError struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+	BodyPreview string
+}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+func (e *GatewayError) Error() string {
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "no content type"
 	}
+	return fmt.Sprintf("AI endpoint returned a non-JSON response (HTTP %s, %s) - likely a gateway or proxy error, not the API itself: %s",
+		e.Status, contentType, e.BodyPreview)
+}
+
+// looksLikeJSON checks the content type first and falls back to sniffing the
+// first non-space byte, since some APIs don't set the header properly
+func looksLikeJSON(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
 
-	return chatResp.Choices[0].Message.Content, nil
+// previewBody collapses whitespace and truncates a response body for error messages
+func previewBody(body []byte, maxLen int) string {
+	preview := strings.Join(strings.Fields(string(body)), " ")
+	if preview == "" {
+		return "(empty body)"
+	}
+	if len(preview) > maxLen {
+		preview = preview[:maxLen] + "..."
+	}
+	return preview
 }
 
 // SystemMessage creates a system message
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeBaseURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://api.openai.com/v1", "https://api.openai.com/v1"},
+		{"https://api.openai.com/v1/", "https://api.openai.com/v1"},
+		{" https://api.openai.com/v1// ", "https://api.openai.com/v1"},
+		{"https://api.openai.com/v1/chat/completions", "https://api.openai.com/v1"},
+		{"https://api.openai.com/v1/chat/completions/", "https://api.openai.com/v1"},
+		{"https://api.anthropic.com/v1/messages", "https://api.anthropic.com/v1"},
+		{"https://api.openai.com/v1/v1", "https://api.openai.com/v1"},
+		{"https://api.openai.com/v1/v1/v1/chat/completions", "https://api.openai.com/v1"},
+		{"http://localhost:11434", "http://localhost:11434"},
+	}
+	for _, tt := range tests {
+		if got := normalizeBaseURL(tt.raw); got != tt.want {
+			t.Errorf("normalizeBaseURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestLoggingToSharesUsage(t *testing.T) {
+	c := NewClient("", "", "", WithTokenBudget(100))
+	var logged []string
+	worker := c.LoggingTo(func(format string, args ...interface{}) {
+		logged = append(logged, format)
+	})
+
+	worker.recordUsage(Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100})
+	if got := c.Usage().TotalTokens; got != 100 {
+		t.Errorf("parent used %d tokens, want 100", got)
+	}
+	if err := c.checkBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("parent budget check = %v, want ErrBudgetExceeded", err)
+	}
+	if len(logged) != 1 {
+		t.Errorf("worker logged %d lines, want 1", len(logged))
+	}
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the APIs WithProvider understands, matching the ai_provider setting
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// provider builds chat requests in the shape one AI API expects and reads
+// its answers, so the rest of the client doesn't care which API it talks to
+type provider interface {
+	// endpoint is the chat path under the base URL
+	endpoint() string
+	// headers authenticate a request with the API key
+	headers(apiKey string) map[string]string
+	// requestBody marshals a chat request, asking for server-sent events if stream is set
+	requestBody(req ChatRequest, stream bool) ([]byte, error)
+	// parseResponse reads the text and token usage of an unstreamed answer
+	parseResponse(body []byte) (string, Usage, error)
+	// parseEvent reads the data of one server-sent event of a streamed answer
+	parseEvent(data string) (streamEvent, error)
+}
+
+// streamEvent is what a provider found in one server-sent event
+type streamEvent struct {
+	delta string // text added to the response
+	usage *Usage // tokens reported by this event, if any
+	done  bool   // no more events will follow
+}
+
+// WithProvider makes the client talk to the given API (ProviderOpenAI or
+// ProviderAnthropic). Anything else means OpenAI.
+func WithProvider(name string) Option {
+	return func(c *Client) {
+		c.provider = newProvider(name)
+	}
+}
+
+func newProvider(name string) provider {
+	if name == ProviderAnthropic {
+		return anthropicProvider{}
+	}
+	return openAIProvider{}
+}
+
+// openAIProvider speaks the OpenAI chat completions API, which most gateways
+// and local servers copy
+type openAIProvider struct{}
+
+func (openAIProvider) endpoint() string {
+	return "/chat/completions"
+}
+
+func (openAIProvider) headers(apiKey string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
+
+func (openAIProvider) requestBody(req ChatRequest, stream bool) ([]byte, error) {
+	if !stream {
+		return json.Marshal(req)
+	}
+	sreq := streamRequest{ChatRequest: req, Stream: true}
+	sreq.StreamOptions.IncludeUsage = true
+	return json.Marshal(sreq)
+}
+
+func (openAIProvider) parseResponse(body []byte) (string, Usage, error) {
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w (body: %s)", err, string(body))
+	}
+	if chatResp.Error != nil {
+		return "", Usage{}, fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices in response")
+	}
+	usage := usageOf(chatResp.Usage.PromptTokens, chatResp.Usage.CompletionTokens, chatResp.Usage.TotalTokens)
+	return chatResp.Choices[0].Message.Content, usage, nil
+}
+
+func (openAIProvider) parseEvent(data string) (streamEvent, error) {
+	if data == "[DONE]" {
+		return streamEvent{done: true}, nil
+	}
+
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return streamEvent{}, fmt.Errorf("failed to parse stream chunk: %w (chunk: %s)", err, data)
+	}
+	if chunk.Error != nil {
+		return streamEvent{}, fmt.Errorf("API error: %s (type: %s)", chunk.Error.Message, chunk.Error.Type)
+	}
+
+	var event streamEvent
+	if chunk.Usage != nil {
+		usage := usageOf(chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens, chunk.Usage.TotalTokens)
+		event.usage = &usage
+	}
+	if len(chunk.Choices) > 0 {
+		event.delta = chunk.Choices[0].Delta.Content
+	}
+	return event, nil
+}
@@ -0,0 +1,240 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// RequestSchema describes how to build the request body and where to find the
+// completion in the response, for gateways that aren't quite OpenAI-compatible
+type RequestSchema struct {
+	// Endpoint is appended to the base URL, e.g. /chat/completions
+	Endpoint string
+	// BodyTemplate is a text/template rendered with templateData
+	BodyTemplate string
+	// ResponsePath locates the completion text, e.g. choices.0.message.content
+	ResponsePath string
+	// Headers are rendered as templates too. When set, they replace the default
+	// Authorization header.
+	Headers map[string]string
+}
+
+// Presets are the built-in request schemas
+var Presets = map[string]RequestSchema{
+	"openai": {
+		Endpoint: "/chat/completions",
+		BodyTemplate: `{"model": {{json .Model}}, "messages": {{json .Messages}}, ` +
+			`"temperature": {{.Temperature}}, "max_tokens": {{.MaxTokens}}` +
+			`{{if .Stop}}, "stop": {{json .Stop}}{{end}}}`,
+		ResponsePath: "choices.0.message.content",
+	},
+	"anthropic": {
+		Endpoint: "/messages",
+		BodyTemplate: `{"model": {{json .Model}}, "system": {{json .System}}, "messages": {{json .Conversation}}, ` +
+			`"temperature": {{.Temperature}}, "max_tokens": {{.MaxTokens}}` +
+			`{{if .Stop}}, "stop_sequences": {{json .Stop}}{{end}}}`,
+		ResponsePath: "content.0.text",
+		Headers: map[string]string{
+			"x-api-key":         "{{.APIKey}}",
+			"anthropic-version": "2023-06-01",
+		},
+	},
+}
+
+// ResolveSchema starts from the named preset (if any) and applies the non-empty
+// fields of override on top of it
+func ResolveSchema(preset string, override RequestSchema) (*RequestSchema, error) {
+	schema := RequestSchema{}
+	if preset != "" {
+		p, ok := Presets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown request schema preset: %s", preset)
+		}
+		schema = p
+	}
+
+	if override.Endpoint != "" {
+		schema.Endpoint = override.Endpoint
+	}
+	if override.BodyTemplate != "" {
+		schema.BodyTemplate = override.BodyTemplate
+	}
+	if override.ResponsePath != "" {
+		schema.ResponsePath = override.ResponsePath
+	}
+	if len(override.Headers) > 0 {
+		schema.Headers = override.Headers
+	}
+
+	if schema.Endpoint == "" {
+		schema.Endpoint = "/chat/completions"
+	}
+	if schema.BodyTemplate == "" || schema.ResponsePath == "" {
+		return nil, fmt.Errorf("request schema needs a body template and a response path")
+	}
+
+	return &schema, nil
+}
+
+// templateData is what body and header templates are rendered with
+type templateData struct {
+	Model        string
+	Messages     []Message // every message, system included
+	System       string    // system messages joined together
+	Conversation []Message // user/assistant messages only
+	Temperature  float64
+	MaxTokens    int
+	Stop         []string // stop sequences, empty for none
+	APIKey       string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func newTemplateData(c *Client, model string, messages []Message, temperature float64, maxTokens int, stop []string) templateData {
+	data := templateData{
+		Model:       model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stop:        stop,
+		APIKey:      c.apiKey,
+	}
+
+	var system []string
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+		} else {
+			data.Conversation = append(data.Conversation, m)
+		}
+	}
+	data.System = strings.Join(system, "\n\n")
+
+	return data
+}
+
+func renderTemplate(name, text string, data templateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// chatWithSchema sends a chat request shaped by the client's request schema
+func (c *Client) chatWithSchema(model string, messages []Message, temperature float64, maxTokens int, stop []string) (string, error) {
+	data := newTemplateData(c, model, messages, temperature, maxTokens, stop)
+
+	body, err := renderTemplate("request body", c.schema.BodyTemplate, data)
+	if err != nil {
+		return "", err
+	}
+
+	headers := map[string]string{"Authorization": "Bearer " + c.apiKey}
+	if len(c.schema.Headers) > 0 {
+		headers = make(map[string]string, len(c.schema.Headers))
+		for k, v := range c.schema.Headers {
+			rendered, err := renderTemplate("header "+k, v, data)
+			if err != nil {
+				return "", err
+			}
+			headers[k] = rendered
+		}
+	}
+
+	respBody, err := c.post(c.schema.Endpoint, []byte(body), headers)
+	if err != nil {
+		return "", err
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w (body: %s)", err, string(respBody))
+	}
+
+	content, err := extractPath(parsed, c.schema.ResponsePath)
+	if err != nil {
+		if apiErr, pathErr := extractPath(parsed, "error.message"); pathErr == nil {
+			return "", fmt.Errorf("API error: %s", apiErr)
+		}
+		return "", err
+	}
+
+	c.recordUsage(schemaTokenUsage(parsed))
+
+	return content, nil
+}
+
+// schemaTokenUsage reads token usage from a response of unknown shape, trying
+// the OpenAI (prompt_tokens, completion_tokens, total_tokens) and Anthropic
+// (input_tokens, output_tokens) fields
+func schemaTokenUsage(parsed interface{}) Usage {
+	field := func(paths ...string) int {
+		for _, path := range paths {
+			if v, err := extractPath(parsed, path); err == nil {
+				n, _ := strconv.Atoi(v)
+				return n
+			}
+		}
+		return 0
+	}
+	return usageOf(
+		field("usage.prompt_tokens", "usage.input_tokens"),
+		field("usage.completion_tokens", "usage.output_tokens"),
+		field("usage.total_tokens"),
+	)
+}
+
+// extractPath walks a decoded JSON value along a dotted path such as
+// choices.0.message.content (a leading "$." and [n] indices are also accepted)
+func extractPath(value interface{}, path string) (string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	current := value
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			continue
+		}
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return "", fmt.Errorf("response has no %q at path %s", segment, path)
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return "", fmt.Errorf("response has no index %s at path %s", segment, path)
+			}
+			current = node[idx]
+		default:
+			return "", fmt.Errorf("cannot descend into %q at path %s", segment, path)
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("response value at path %s is null", path)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), nil
+	}
+}
@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrStreamingUnsupported is returned by the streaming calls when the client
+// can't stream, e.g. with a custom request schema. Callers should fall back to
+// the unstreamed call.
+var ErrStreamingUnsupported = errors.New("streaming not supported")
+
+// streamRequest is ChatRequest with streaming switched on
+type streamRequest struct {
+	ChatRequest
+	Stream        bool `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// streamChunk is one server-sent event of a streamed chat completion
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// ChatJSONStream is ChatJSON, streamed: onText is called with the response
+// text received so far each time more arrives. It returns the full response.
+// Custom request schemas can't stream.
+func (c *Client) ChatJSONStream(messages []Message, onText func(string)) (string, error) {
+	var text strings.Builder
+	return c.stream(c.model, messages, c.structuredStop, func(delta string) {
+		text.WriteString(delta)
+		onText(text.String())
+	})
+}
+
+// ChatStream is Chat, streamed: onDelta is called with each piece of the
+// response as it arrives. It returns the full response.
+func (c *Client) ChatStream(messages []Message, onDelta func(string)) (string, error) {
+	return c.stream(c.model, messages, nil, onDelta)
+}
+
+// ChatFormattingStream is ChatFormatting, streamed like ChatStream
+func (c *Client) ChatFormattingStream(messages []Message, onDelta func(string)) (string, error) {
+	model := c.model
+	if c.formattingModel != "" {
+		model = c.formattingModel
+	}
+	return c.stream(model, messages, nil, onDelta)
+}
+
+// stream sends a streaming chat request and reads the server-sent events
+// until the provider says the answer is done or the body ends
+func (c *Client) stream(model string, messages []Message, stop []string, onDelta func(string)) (string, error) {
+	if c.schema != nil {
+		return "", ErrStreamingUnsupported
+	}
+	if err := c.checkBudget(); err != nil {
+		return "", err
+	}
+	c.logPrompt(model, messages)
+
+	body, err := c.provider.requestBody(ChatRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: c.temperature,
+		MaxTokens:   c.maxTokens,
+		Stop:        stop,
+	}, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", c.baseURL+c.provider.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	for k, v := range c.provider.headers(c.apiKey) {
+		httpReq.Header.Set(k, v)
+	}
+	c.setHeaders(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Servers that ignore "stream" answer with a normal JSON body, and errors
+	// come back as JSON too
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return c.readUnstreamed(resp, onDelta)
+	}
+
+	// The scanner holds on to partial lines until the rest of them arrives
+	var text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		event, err := c.provider.parseEvent(strings.TrimSpace(data))
+		if err != nil {
+			return "", err
+		}
+		if event.usage != nil {
+			c.recordUsage(*event.usage)
+		}
+		if event.delta != "" {
+			text.WriteString(event.delta)
+			onDelta(event.delta)
+		}
+		if event.done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return text.String(), nil
+}
+
+// readUnstreamed handles a non-streamed answer to a streaming request
+func (c *Client) readUnstreamed(resp *http.Response, onDelta func(string)) (string, error) {
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if !looksLikeJSON(resp.Header.Get("Content-Type"), respBody) {
+		return "", &GatewayError{
+			StatusCode:  resp.StatusCode,
+			Status:      resp.Status,
+			ContentType: resp.Header.Get("Content-Type"),
+			BodyPreview: previewBody(respBody, 200),
+		}
+	}
+
+	text, usage, err := c.provider.parseResponse(respBody)
+	if err != nil {
+		return "", err
+	}
+
+	c.recordUsage(usage)
+	onDelta(text)
+	return text, nil
+}
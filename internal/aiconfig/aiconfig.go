@@ -0,0 +1,66 @@
+// Package aiconfig builds AI clients from the user's config, so that the ai
+// package itself doesn't depend on how settings are stored
+package aiconfig
+
+import (
+	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/config"
+)
+
+// NewClient creates an AI client from the AI settings in the user's config.
+// The extra options are applied last.
+func NewClient(cfg *config.Config, extra ...ai.Option) *ai.Client {
+	opts := []ai.Option{ai.WithProvider(cfg.AIProvider)}
+
+	// config.Validate has already checked the schema, so it resolves
+	if s := cfg.AIRequestSchema; s != nil {
+		schema, err := ai.ResolveSchema(s.Preset, ai.RequestSchema{
+			Endpoint:     s.Endpoint,
+			BodyTemplate: s.BodyTemplate,
+			ResponsePath: s.ResponsePath,
+			Headers:      s.Headers,
+		})
+		if err == nil {
+			opts = append(opts, ai.WithRequestSchema(schema))
+		}
+	}
+
+	if headers := requestHeaders(cfg); len(headers) > 0 {
+		opts = append(opts, ai.WithHeaders(headers))
+	}
+
+	if cfg.MaxTokensPerRun > 0 {
+		opts = append(opts, ai.WithTokenBudget(cfg.MaxTokensPerRun))
+	}
+
+	if len(cfg.StructuredStop) > 0 {
+		opts = append(opts, ai.WithStructuredStop(cfg.StructuredStop))
+	}
+
+	if cfg.AIModelFormatting != "" {
+		opts = append(opts, ai.WithFormattingModel(cfg.AIModelFormatting))
+	}
+
+	if cfg.MaxTokens > 0 {
+		opts = append(opts, ai.WithSampling(cfg.Temperature, cfg.MaxTokens))
+	}
+
+	model := cfg.AIModel
+	if cfg.AIModelAnalysis != "" {
+		model = cfg.AIModelAnalysis
+	}
+
+	return ai.NewClient(cfg.AIApiURL, cfg.AIApiKey, model, append(opts, extra...)...)
+}
+
+// requestHeaders collects ai_extra_headers and ai_org
+func requestHeaders(cfg *config.Config) map[string]string {
+	headers := make(map[string]string, len(cfg.ExtraHeaders)+1)
+	if cfg.AIOrg != "" {
+		headers["OpenAI-Organization"] = cfg.AIOrg
+	}
+	for k, v := range cfg.ExtraHeaders {
+		headers[k] = v
+	}
+	return headers
+}
@@ -0,0 +1,17 @@
+// Package clock abstracts the wall clock so that timing-dependent behavior
+// (rate limit waits, cache and identity timestamps) can be driven by tests
+package clock
+
+import "time"
+
+// Clock tells the time and waits
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// Real is the wall clock
+type Real struct{}
+
+func (Real) Now() time.Time        { return time.Now() }
+func (Real) Sleep(d time.Duration) { time.Sleep(d) }
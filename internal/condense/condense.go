@@ -0,0 +1,86 @@
+// Package condense shortens generated comments that run past the configured
+// length limit, so walls of text stay readable (and under GitHub's size cap).
+package condense
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/user/salty-reviewer/internal/ai"
+)
+
+// maxAttempts bounds how often the AI is asked to condense one text. Models
+// are bad at counting characters, so after that the text is cut instead.
+const maxAttempts = 2
+
+const truncatedSuffix = "\n\n*(truncated)*"
+
+// Condense returns text unchanged if it fits in maxChars (0 = no limit).
+// Otherwise it asks the AI to rewrite it shorter, keeping the key point and
+// voice, and truncates as a last resort. A failed AI call is returned as an
+// error along with the truncated text, so callers can still use it.
+func Condense(client *ai.Client, text string, maxChars int) (string, error) {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text, nil
+	}
+
+	current := text
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		messages := []ai.Message{
+			ai.SystemMessage("You shorten code review comments without changing what they say or how they say it."),
+			ai.UserMessage(prompt(current, maxChars)),
+		}
+
+		shorter, err := client.ChatFormatting(messages)
+		if err != nil {
+			return Truncate(text, maxChars), fmt.Errorf("failed to condense comment: %w", err)
+		}
+		shorter = strings.TrimSpace(shorter)
+		if shorter == "" {
+			break
+		}
+		if utf8.RuneCountInString(shorter) <= maxChars {
+			return shorter, nil
+		}
+		// Only keep a rewrite that actually got shorter
+		if utf8.RuneCountInString(shorter) < utf8.RuneCountInString(current) {
+			current = shorter
+		}
+	}
+
+	return Truncate(current, maxChars), nil
+}
+
+func prompt(text string, maxChars int) string {
+	return fmt.Sprintf(`This comment is %d characters long but must be at most %d characters.
+
+Rewrite it to fit. Keep the key point, the tone and the writing style, and keep any
+code blocks (especially `+"```suggestion"+` blocks) exactly as they are. Cut repetition,
+hedging and filler first.
+
+Respond with ONLY the rewritten comment.
+
+COMMENT:
+%s`, utf8.RuneCountInString(text), maxChars, text)
+}
+
+// Truncate cuts text to at most maxChars runes including a truncation note,
+// without leaving a code block open
+func Truncate(text string, maxChars int) string {
+	if utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	keep := maxChars - utf8.RuneCountInString(truncatedSuffix)
+	if keep <= 0 {
+		return string([]rune(text)[:maxChars])
+	}
+	cut := strings.TrimRight(string([]rune(text)[:keep]), " \n")
+	// Don't leave a code block open
+	if strings.Count(cut, "```")%2 == 1 {
+		if i := strings.LastIndex(cut, "```"); i >= 0 {
+			cut = strings.TrimRight(cut[:i], " \n")
+		}
+	}
+	return cut + truncatedSuffix
+}
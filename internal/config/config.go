@@ -2,8 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,36 +18,339 @@ import (
 type WritingStyle string
 
 const (
-	StyleCorporate        WritingStyle = "corporate"
+	StyleCorporate         WritingStyle = "corporate"
 	StylePassiveAggressive WritingStyle = "passive_aggressive"
-	StyleTechBro          WritingStyle = "tech_bro"
-	StyleAcademic         WritingStyle = "academic"
+	StyleTechBro           WritingStyle = "tech_bro"
+	StyleAcademic          WritingStyle = "academic"
+	StyleCustom            WritingStyle = "custom" // prompts read from custom_style_path
 )
 
+// DefenseOrder defines the order defense responses are posted in
+type DefenseOrder string
+
+const (
+	DefenseOrderChronological DefenseOrder = "chronological"
+	DefenseOrderFile          DefenseOrder = "file"
+	DefenseOrderSeverity      DefenseOrder = "severity"
+)
+
+// PostMode defines where review findings are published
+type PostMode string
+
+const (
+	PostModeReview PostMode = "review" // A PR review with inline comments
+	PostModeChecks PostMode = "checks" // A check run with annotations
+)
+
+// Verbosity defines how much progress output salty prints
+type Verbosity string
+
+const (
+	VerbosityQuiet   Verbosity = "quiet"   // Errors, warnings and the final result only
+	VerbosityNormal  Verbosity = "normal"  // Progress too
+	VerbosityVerbose Verbosity = "verbose" // Progress, AI prompts and token counts
+)
+
+// AI providers, which set the request and response shape of the AI API
+const (
+	ProviderOpenAI    = "openai"    // OpenAI and compatible APIs
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+)
+
+// Built-in AI request schema presets
+const (
+	RequestPresetOpenAI    = "openai"
+	RequestPresetAnthropic = "anthropic"
+)
+
+// RequestSchema customizes the AI request body and response extraction for
+// gateways that aren't quite OpenAI-compatible
+type RequestSchema struct {
+	Preset       string            `yaml:"preset,omitempty"`        // openai, anthropic
+	Endpoint     string            `yaml:"endpoint,omitempty"`      // e.g. /chat/completions
+	BodyTemplate string            `yaml:"body_template,omitempty"` // Go text/template
+	ResponsePath string            `yaml:"response_path,omitempty"` // e.g. choices.0.message.content
+	Headers      map[string]string `yaml:"headers,omitempty"`       // replace the default Authorization header
+}
+
+// SeverityMapping holds the minimum confidence (0-100) for each derived
+// severity. Anything below Minor is info. A threshold of 0 disables that level.
+type SeverityMapping struct {
+	Critical int `yaml:"critical"`
+	Major    int `yaml:"major"`
+	Minor    int `yaml:"minor"`
+}
+
 // Config holds all user configuration
 type Config struct {
 	// GitHub settings
 	GitHubToken string `yaml:"github_token"`
 
-	// AI settings - generic OpenAI-compatible API
-	AIApiURL string `yaml:"ai_api_url"`
-	AIApiKey string `yaml:"ai_api_key"`
-	AIModel  string `yaml:"ai_model"`
+	// AI settings - OpenAI-compatible or Anthropic API
+	AIProvider string `yaml:"ai_provider"` // openai, anthropic
+	AIApiURL   string `yaml:"ai_api_url"`
+	AIApiKey   string `yaml:"ai_api_key"`
+	AIModel    string `yaml:"ai_model"`
+
+	// Optional per-step models; each falls back to ai_model when empty
+	AIModelAnalysis   string `yaml:"ai_model_analysis,omitempty"`   // First pass, deep analysis, nitpicks
+	AIModelFormatting string `yaml:"ai_model_formatting,omitempty"` // Turning findings into review comments
+
+	// Sampling settings sent with every AI request
+	Temperature float64 `yaml:"temperature"` // 0.0-2.0, lower is more deterministic
+	MaxTokens   int     `yaml:"max_tokens"`  // Longest response per request
+
+	// Optional custom request/response shape for the AI API
+	AIRequestSchema *RequestSchema `yaml:"ai_request_schema,omitempty"`
+
+	// Headers added to every AI request, e.g. for a gateway. AIOrg is sent as
+	// OpenAI-Organization.
+	AIOrg        string            `yaml:"ai_org,omitempty"`
+	ExtraHeaders map[string]string `yaml:"ai_extra_headers,omitempty"`
 
 	// Review behavior
-	WritingStyle     WritingStyle `yaml:"writing_style"`
-	NitpickyLevel    int          `yaml:"nitpicky_level"` // 1-10
-	LikedReviewers   []string     `yaml:"liked_reviewers"`
-	DislikedReviewers []string    `yaml:"disliked_reviewers"`
+	WritingStyle      WritingStyle `yaml:"writing_style"`
+	NitpickyLevel     int          `yaml:"nitpicky_level"` // 1-10
+	LikedReviewers    []string     `yaml:"liked_reviewers"`
+	DislikedReviewers []string     `yaml:"disliked_reviewers"`
+
+	// File with the review and defense prompts for writing_style custom
+	CustomStylePath string `yaml:"custom_style_path"`
+
+	// Review settings per repository, keyed by owner/repo; see ForRepo
+	Repos map[string]RepoOverride `yaml:"repos,omitempty"`
+
+	// Only review changed files matching include_globs (empty = all) and not
+	// matching exclude_globs, e.g. "*.pb.go" or "vendor"; see ReviewsFile
+	IncludeGlobs []string `yaml:"include_globs"`
+	ExcludeGlobs []string `yaml:"exclude_globs"`
+
+	// Skip files with more changed lines than this, like minified bundles
+	// (0 = no limit)
+	MaxFileDiffLines int `yaml:"max_file_diff_lines"`
+
+	// Only review files the authenticated user owns per CODEOWNERS
+	RespectCodeowners bool `yaml:"respect_codeowners"`
+
+	// Path in the repo of a contributing or style guide the first pass should
+	// follow, e.g. CONTRIBUTING.md (empty = off). Skipped if the file is missing.
+	GuidelinesFile string `yaml:"guidelines_file"`
+
+	// Add a note listing added/changed images and their sizes to the review summary
+	ReviewImages bool `yaml:"review_images"`
+
+	// Summarize go.mod/package.json dependency changes instead of reviewing
+	// them and lockfiles line by line. New dependencies whose name doesn't
+	// start with one of the trusted sources are flagged (empty = only flag
+	// git/URL sources).
+	AnalyzeDependencies      bool     `yaml:"analyze_dependencies"`
+	TrustedDependencySources []string `yaml:"trusted_dependency_sources"`
+
+	// Flag credentials added in the diff as critical without asking the AI
+	// (minor in test files and fixtures, so they don't block the PR), and
+	// redact them from everything salty posts or logs. Optionally also strip
+	// them from the diff and files sent to the AI.
+	DetectSecrets          bool `yaml:"detect_secrets"`
+	RedactSecretsInPrompts bool `yaml:"redact_secrets_in_prompts"`
+
+	// Flag newly added TODO-style comments as nits without asking the AI
+	FlagTodos    bool     `yaml:"flag_todos"`
+	TodoKeywords []string `yaml:"todo_keywords"`
+
+	// Files larger than this are left out of deep analysis context (0 = no limit)
+	MaxFileBytes int `yaml:"max_file_bytes"`
+
+	// Caps on related files (tests etc.) added to deep-analysis context (0 = no limit)
+	MaxRelatedFiles int `yaml:"max_related_files"`
+	MaxRelatedBytes int `yaml:"max_related_bytes"`
+
+	// Split the first pass into several calls when the diff's prompt would be
+	// larger than this many tokens, estimated (0 = one call)
+	MaxFirstPassTokens int `yaml:"max_first_pass_tokens"`
+
+	// Issues deep-analyzed at once
+	Concurrency int `yaml:"concurrency"`
+
+	// Labels applied after posting a review (empty = don't manage labels)
+	LabelOnChanges string `yaml:"label_on_changes"`
+	LabelOnApprove string `yaml:"label_on_approve"`
+
+	// Signatures appended to what salty posts, so its review comments and
+	// defense replies can be told apart (empty = no visible signature)
+	ReviewSignature  string `yaml:"review_signature"`
+	DefenseSignature string `yaml:"defense_signature"`
+
+	// Text before and after the review summary. {author} and {pr_title} are
+	// replaced with the PR's author and title.
+	SummaryGreeting string `yaml:"summary_greeting"`
+	SummarySignoff  string `yaml:"summary_signoff"`
+
+	// Start the review summary with a one-line verdict
+	ShowVerdict bool `yaml:"show_verdict"`
+
+	// Derive severity from deep-analysis confidence when the model doesn't give one
+	SeverityFromConfidence bool            `yaml:"severity_from_confidence"`
+	SeverityMapping        SeverityMapping `yaml:"severity_mapping"`
+
+	// Ask before a review requests changes (downgraded to a comment when nobody can answer)
+	ConfirmRequestChanges bool `yaml:"confirm_request_changes"`
+
+	// Users to request a re-review from after requesting changes
+	RequestReviewers []string `yaml:"request_reviewers"`
+
+	// Comments from these users are never auto-defended
+	DefenseIgnoreUsers []string `yaml:"defense_ignore_users"`
+
+	// Only defend comments from submitted reviews, so a reviewer still
+	// writing theirs doesn't get replies to comments they may yet edit
+	DefendSubmittedOnly bool `yaml:"defend_submitted_only"`
+
+	// Where reviews are published: review or checks
+	PostMode PostMode `yaml:"post_mode"`
+
+	// How much progress to print: quiet, normal or verbose
+	Verbosity Verbosity `yaml:"verbosity"`
+
+	// Order defense responses are posted in
+	DefenseOrder DefenseOrder `yaml:"defense_order"`
+
+	// Concessions include the fix as a suggestion block the reviewer can apply
+	ConcedeWithSuggestion bool `yaml:"concede_with_suggestion"`
+
+	// How combative defense responses are, 1-10 (1=collaborative, 10=belligerent)
+	DefenseAggressiveness int `yaml:"defense_aggressiveness"`
+
+	// Concede comments the analysis judges at least this valid, 0-100
+	ConcedeThreshold int `yaml:"concede_threshold"`
+
+	// Stop once a run has used this many AI tokens (0 = no limit)
+	MaxTokensPerRun int `yaml:"max_tokens_per_run"`
+
+	// Model prices in USD per 1000 tokens, for the cost estimate printed after
+	// a run (0 = don't estimate)
+	PricePer1KPromptTokens     float64 `yaml:"price_per_1k_prompt_tokens"`
+	PricePer1KCompletionTokens float64 `yaml:"price_per_1k_completion_tokens"`
+
+	// After posting, set a salty/review commit status: failure when requesting
+	// changes, success otherwise
+	SetCommitStatus bool `yaml:"set_commit_status"`
+
+	// After posting a review, also post a conversation comment listing every
+	// inline comment with a link, its severity and its category
+	PostDigest bool `yaml:"post_digest"`
+
+	// Reviews are posted at the commit they read. When GitHub rejects one
+	// because that commit was force-pushed away and a comment line is no
+	// longer in the diff, move comments to where their code is now and retry
+	// against the new head.
+	RemapOutdatedComments bool `yaml:"remap_outdated_comments"`
+
+	// Post at most this many comments per file, most severe first, and list the
+	// rest in the summary (0 = no limit)
+	MaxCommentsPerFile int `yaml:"max_comments_per_file"`
+
+	// Post at most this many comments in the whole review, after the per-file
+	// cap, most severe first; the rest are listed in the summary too (0 = no limit)
+	MaxComments int `yaml:"max_comments"`
+
+	// Post at most this many inline comments per review; more are posted as
+	// follow-up reviews, since GitHub rejects big ones (0 = no limit)
+	MaxCommentsPerReview int `yaml:"max_comments_per_review"`
+
+	// Nitpicky level per base branch glob, e.g. main: "+2" or "release/*": 9.
+	// A plain number replaces nitpicky_level, +N/-N adjusts it.
+	BaseBranchNitpickyRules map[string]string `yaml:"base_branch_nitpicky"`
+
+	// Approve instead of requesting changes when every comment is a nit or
+	// info (comment instead on your own PRs, which GitHub won't let you approve)
+	ApproveIfOnlyNits bool `yaml:"approve_if_only_nits"`
+
+	// Approve instead of commenting when a review finds nothing at all. Never
+	// for disliked reviewers or your own PRs, or when files were skipped.
+	ApproveCleanPRs bool `yaml:"approve_clean_prs"`
+
+	// Review and defend draft PRs, which are refused by default as premature
+	ReviewDrafts bool `yaml:"review_drafts"`
+
+	// Stream the first pass and start deep-analyzing issues while the rest are
+	// still arriving. Needs the standard OpenAI request shape; custom request
+	// schemas fall back to the batch first pass.
+	StreamFirstPass bool `yaml:"stream_first_pass"`
+
+	// External command every generated comment and defense response is piped
+	// through (stdin in, stdout out), e.g. ["./tone-check", "--strict"]. On
+	// failure or timeout the text is left unchanged.
+	CommentHook            []string `yaml:"comment_hook"`
+	CommentHookTimeoutSecs int      `yaml:"comment_hook_timeout"`
+
+	// Longer generated comments and defense responses are condensed by the AI,
+	// then truncated if that doesn't work (0 = no limit)
+	MaxCommentChars int `yaml:"max_comment_chars"`
+
+	// Stop sequences sent with prompts that expect a JSON answer (empty =
+	// none). Pick ones that can't match the start of the answer: "\n```" also
+	// matches the line break before an opening ```json fence.
+	StructuredStop StopSequences `yaml:"structured_stop"`
+
+	// Read-only org policy that caps or pins settings: an http(s) URL,
+	// github:owner/repo/path[@ref] or a local path. With OrgConfigSHA256 set,
+	// the policy is only accepted if its content has exactly that hash, which
+	// catches the source changing under you; it isn't a signature.
+	OrgConfig       string `yaml:"org_config"`
+	OrgConfigSHA256 string `yaml:"org_config_sha256"`
+
+	org *OrgPolicy // Applied by Load
+
+	customStyle  *CustomStyle // Read from custom_style_path by Load
+	styleWarning string       // Why the custom style couldn't be used
+}
+
+// StopSequences are written double-quoted: yaml.v3 writes a block scalar
+// for a value starting with a newline that it can't read back
+type StopSequences []string
+
+// MarshalYAML implements yaml.Marshaler
+func (s StopSequences) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, stop := range s {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: stop})
+	}
+	return node, nil
 }
 
 // DefaultConfig returns a config with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
-		AIApiURL:      "https://api.openai.com/v1",
-		AIModel:       "gpt-4",
-		WritingStyle:  StylePassiveAggressive,
-		NitpickyLevel: 5,
+		AIProvider:             ProviderOpenAI,
+		AIApiURL:               "https://api.openai.com/v1",
+		AIModel:                "gpt-4",
+		WritingStyle:           StylePassiveAggressive,
+		NitpickyLevel:          5,
+		MaxRelatedFiles:        2,
+		MaxRelatedBytes:        30000,
+		MaxFirstPassTokens:     50000,
+		MaxFileBytes:           100000,
+		MaxFileDiffLines:       3000,
+		MaxCommentsPerReview:   30,
+		Concurrency:            4,
+		DefenseOrder:           DefenseOrderChronological,
+		PostMode:               PostModeReview,
+		Verbosity:              VerbosityNormal,
+		DefenseAggressiveness:  10,
+		ConcedeThreshold:       95,
+		Temperature:            0.7,
+		MaxTokens:              4096,
+		ReviewSignature:        "🔍 *salty review*",
+		DefenseSignature:       "🛡️ *salty defense*",
+		TodoKeywords:           []string{"TODO", "FIXME", "HACK", "XXX"},
+		CommentHookTimeoutSecs: 10,
+		DetectSecrets:          true,
+		RemapOutdatedComments:  true,
+		RedactSecretsInPrompts: true,
+		SeverityMapping: SeverityMapping{
+			Major: 90,
+			Minor: 70,
+		},
 	}
 }
 
@@ -63,33 +372,91 @@ func ConfigPath() (string, error) {
 	return filepath.Join(dir, "config.yaml"), nil
 }
 
-// Load reads the config from disk
+// Load reads the config from disk, overlays SALTY_* environment variables
+// (see applyEnv) and enforces the org policy, if one is set. Without a config
+// file, the environment alone is enough if it has both tokens.
 func Load() (*Config, error) {
-	path, err := ConfigPath()
+	cfg, found, err := readConfigFile()
 	if err != nil {
 		return nil, err
 	}
+	if !found {
+		if os.Getenv(EnvGitHubToken) == "" || os.Getenv(EnvAIApiKey) == "" {
+			return nil, fmt.Errorf("config not found. Run 'salty init' first, or set %s and %s", EnvGitHubToken, EnvAIApiKey)
+		}
+		cfg = DefaultConfig()
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
-	data, err := os.ReadFile(path)
+	policy, err := LoadOrgPolicy(cfg)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("config not found. Run 'salty init' first")
+		return nil, err
+	}
+	if policy != nil {
+		if err := policy.Apply(cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config after applying the org config: %w", err)
 		}
-		return nil, fmt.Errorf("could not read config: %w", err)
 	}
+	cfg.styleWarning = cfg.checkCustomStyle()
 
-	cfg := DefaultConfig()
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("could not parse config: %w", err)
+	return cfg, nil
+}
+
+// LoadLocal reads the user's own config without applying the org policy.
+// Use it for configs that get saved back, so enforced values don't end up
+// in the user's file.
+func LoadLocal() (*Config, error) {
+	cfg, found, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("config not found. Run 'salty init' first")
 	}
 
-	if err := cfg.Validate(); err != nil {
+	// Tokens may live in the environment instead of the file
+	check := *cfg
+	if err := check.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := check.Validate(); err != nil {
 		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// readConfigFile parses the config file over the defaults, without
+// validating it. found is false if there is no config file.
+func readConfigFile() (cfg *Config, found bool, err error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("could not read config: %w", err)
+	}
+
+	cfg = DefaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, false, fmt.Errorf("could not parse config: %w", err)
+	}
+	return cfg, true, nil
+}
+
 // Save writes the config to disk
 func (c *Config) Save() error {
 	dir, err := ConfigDir()
@@ -118,6 +485,15 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// ValidateAIApiURL checks that ai_api_url is an http or https URL
+func ValidateAIApiURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ai_api_url must be an http or https URL, e.g. https://api.openai.com/v1 (got %q)", raw)
+	}
+	return nil
+}
+
 // Validate checks that the config has required fields
 func (c *Config) Validate() error {
 	if c.GitHubToken == "" {
@@ -126,12 +502,142 @@ func (c *Config) Validate() error {
 	if c.AIApiKey == "" {
 		return fmt.Errorf("ai_api_key is required")
 	}
+	if err := ValidateAIApiURL(c.AIApiURL); err != nil {
+		return err
+	}
+	for name := range c.ExtraHeaders {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("ai_extra_headers: %q is not a valid header name", name)
+		}
+	}
+	switch c.AIProvider {
+	case "", ProviderOpenAI, ProviderAnthropic:
+	default:
+		return fmt.Errorf("unknown ai_provider: %s (use openai or anthropic)", c.AIProvider)
+	}
 	if c.NitpickyLevel < 1 || c.NitpickyLevel > 10 {
 		return fmt.Errorf("nitpicky_level must be between 1 and 10")
 	}
+	if c.DefenseAggressiveness < 1 || c.DefenseAggressiveness > 10 {
+		return fmt.Errorf("defense_aggressiveness must be between 1 and 10")
+	}
+	if c.ConcedeThreshold < 0 || c.ConcedeThreshold > 100 {
+		return fmt.Errorf("concede_threshold must be between 0 and 100")
+	}
+	if c.WritingStyle == StyleCustom && c.CustomStylePath == "" {
+		return fmt.Errorf("writing_style custom needs custom_style_path")
+	}
+	switch c.PostMode {
+	case PostModeReview, PostModeChecks:
+	default:
+		return fmt.Errorf("post_mode must be review or checks")
+	}
+	switch c.Verbosity {
+	case VerbosityQuiet, VerbosityNormal, VerbosityVerbose:
+	default:
+		return fmt.Errorf("verbosity must be quiet, normal or verbose")
+	}
+	switch c.DefenseOrder {
+	case DefenseOrderChronological, DefenseOrderFile, DefenseOrderSeverity:
+	default:
+		return fmt.Errorf("defense_order must be chronological, file or severity")
+	}
+	if len(c.StructuredStop) > 4 {
+		return fmt.Errorf("structured_stop can have at most 4 sequences")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		return fmt.Errorf("temperature must be between 0.0 and 2.0")
+	}
+	if c.MaxTokens <= 0 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	if c.PricePer1KPromptTokens < 0 || c.PricePer1KCompletionTokens < 0 {
+		return fmt.Errorf("price_per_1k_prompt_tokens and price_per_1k_completion_tokens can't be negative")
+	}
+	if c.MaxTokensPerRun < 0 {
+		return fmt.Errorf("max_tokens_per_run must be 0 (no limit) or positive")
+	}
+	if c.MaxRelatedFiles < 0 || c.MaxRelatedBytes < 0 {
+		return fmt.Errorf("max_related_files and max_related_bytes must be 0 (no limit) or positive")
+	}
+	if c.MaxFirstPassTokens < 0 {
+		return fmt.Errorf("max_first_pass_tokens must be 0 (no limit) or positive")
+	}
+	for _, pattern := range append(append([]string(nil), c.IncludeGlobs...), c.ExcludeGlobs...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("include_globs/exclude_globs: invalid pattern %q", pattern)
+		}
+	}
+	if err := c.validateRepos(); err != nil {
+		return err
+	}
+	if c.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	for pattern, rule := range c.BaseBranchNitpickyRules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("base_branch_nitpicky: invalid pattern %q", pattern)
+		}
+		n, offset, err := parseNitpickyRule(rule)
+		switch {
+		case err != nil:
+			return fmt.Errorf("base_branch_nitpicky.%s must be a level (1-10) or an offset like +2", pattern)
+		case !offset && (n < 1 || n > 10):
+			return fmt.Errorf("base_branch_nitpicky.%s must be between 1 and 10", pattern)
+		}
+	}
+	if len(c.CommentHook) > 0 && c.CommentHookTimeoutSecs <= 0 {
+		return fmt.Errorf("comment_hook_timeout must be positive")
+	}
+	if c.MaxFileDiffLines < 0 {
+		return fmt.Errorf("max_file_diff_lines must be 0 (no limit) or positive")
+	}
+	if c.MaxCommentsPerFile < 0 {
+		return fmt.Errorf("max_comments_per_file must be 0 (no limit) or positive")
+	}
+	if c.MaxComments < 0 {
+		return fmt.Errorf("max_comments must be 0 (no limit) or positive")
+	}
+	if c.MaxCommentsPerReview < 0 {
+		return fmt.Errorf("max_comments_per_review must be 0 (no limit) or positive")
+	}
+	if c.MaxCommentChars < 0 {
+		return fmt.Errorf("max_comment_chars must be 0 (no limit) or positive")
+	}
+	if c.MaxFileBytes < 0 {
+		return fmt.Errorf("max_file_bytes must be 0 (no limit) or positive")
+	}
+	if c.FlagTodos && len(c.TodoKeywords) == 0 {
+		return fmt.Errorf("todo_keywords can't be empty when flag_todos is on")
+	}
+	for name, t := range map[string]int{
+		"critical": c.SeverityMapping.Critical,
+		"major":    c.SeverityMapping.Major,
+		"minor":    c.SeverityMapping.Minor,
+	} {
+		if t < 0 || t > 100 {
+			return fmt.Errorf("severity_mapping.%s must be between 0 and 100", name)
+		}
+	}
+	if s := c.AIRequestSchema; s != nil {
+		switch s.Preset {
+		case RequestPresetOpenAI, RequestPresetAnthropic:
+		case "":
+			if s.BodyTemplate == "" || s.ResponsePath == "" {
+				return fmt.Errorf("ai_request_schema needs a preset or both body_template and response_path")
+			}
+		default:
+			return fmt.Errorf("unknown ai_request_schema preset: %s", s.Preset)
+		}
+	}
 	return nil
 }
 
+// CommentHookTimeout is how long the comment hook may run per comment
+func (c *Config) CommentHookTimeout() time.Duration {
+	return time.Duration(c.CommentHookTimeoutSecs) * time.Second
+}
+
 // IsLikedReviewer checks if a user is in the liked list
 func (c *Config) IsLikedReviewer(username string) bool {
 	for _, u := range c.LikedReviewers {
@@ -152,13 +658,30 @@ func (c *Config) IsDislikedReviewer(username string) bool {
 	return false
 }
 
+// IsDefenseIgnored checks if a user's comments should be left for manual replies
+func (c *Config) IsDefenseIgnored(username string) bool {
+	for _, u := range c.DefenseIgnoreUsers {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDefenseIgnoredUser adds a user to the defense ignore list
+func (c *Config) AddDefenseIgnoredUser(username string) {
+	if !c.IsDefenseIgnored(username) {
+		c.DefenseIgnoreUsers = append(c.DefenseIgnoreUsers, username)
+	}
+}
+
 // AddLikedReviewer adds a user to the liked list
 func (c *Config) AddLikedReviewer(username string) {
 	if !c.IsLikedReviewer(username) {
 		c.LikedReviewers = append(c.LikedReviewers, username)
 	}
 	// Remove from disliked if present
-	c.removeFromDisliked(username)
+	c.RemoveDislikedReviewer(username)
 }
 
 // AddDislikedReviewer adds a user to the disliked list
@@ -167,25 +690,158 @@ func (c *Config) AddDislikedReviewer(username string) {
 		c.DislikedReviewers = append(c.DislikedReviewers, username)
 	}
 	// Remove from liked if present
-	c.removeFromLiked(username)
+	c.RemoveLikedReviewer(username)
+}
+
+// RemoveLikedReviewer removes a user from the liked list, reporting whether
+// they were on it
+func (c *Config) RemoveLikedReviewer(username string) bool {
+	return removeUser(&c.LikedReviewers, username)
 }
 
-func (c *Config) removeFromLiked(username string) {
-	for i, u := range c.LikedReviewers {
+// RemoveDislikedReviewer removes a user from the disliked list, reporting
+// whether they were on it
+func (c *Config) RemoveDislikedReviewer(username string) bool {
+	return removeUser(&c.DislikedReviewers, username)
+}
+
+// RemoveDefenseIgnoredUser removes a user from the defense ignore list,
+// reporting whether they were on it
+func (c *Config) RemoveDefenseIgnoredUser(username string) bool {
+	return removeUser(&c.DefenseIgnoreUsers, username)
+}
+
+func removeUser(list *[]string, username string) bool {
+	for i, u := range *list {
 		if u == username {
-			c.LikedReviewers = append(c.LikedReviewers[:i], c.LikedReviewers[i+1:]...)
-			return
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
 		}
 	}
+	return false
 }
 
-func (c *Config) removeFromDisliked(username string) {
-	for i, u := range c.DislikedReviewers {
-		if u == username {
-			c.DislikedReviewers = append(c.DislikedReviewers[:i], c.DislikedReviewers[i+1:]...)
-			return
+// Unset puts a config key, written like in config.yaml, back to its default.
+// Keys without a default, like tokens, end up empty.
+func (c *Config) Unset(key string) error {
+	if name, ok := strings.CutPrefix(key, "ai_extra_headers."); ok && name != "" {
+		delete(c.ExtraHeaders, name)
+		return nil
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" && name == key {
+			v.Field(i).Set(reflect.ValueOf(DefaultConfig()).Elem().Field(i))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown config key: %s", key)
+}
+
+// EstimateCostUSD prices token usage with the configured rates. Tokens the
+// provider didn't split into prompt and completion are priced as prompt.
+// Returns 0 if no prices are set.
+func (c *Config) EstimateCostUSD(prompt, completion, total int) float64 {
+	if rest := total - prompt - completion; rest > 0 {
+		prompt += rest
+	}
+	return float64(prompt)/1000*c.PricePer1KPromptTokens + float64(completion)/1000*c.PricePer1KCompletionTokens
+}
+
+// ReviewsFile reports whether a changed file should be reviewed according to
+// include_globs and exclude_globs. Exclusions win, and no include_globs means
+// every file is included.
+func (c *Config) ReviewsFile(filename string) bool {
+	for _, pattern := range c.ExcludeGlobs {
+		if matchFileGlob(pattern, filename) {
+			return false
+		}
+	}
+	if len(c.IncludeGlobs) == 0 {
+		return true
+	}
+	for _, pattern := range c.IncludeGlobs {
+		if matchFileGlob(pattern, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFileGlob matches a path.Match pattern against a file's path. A pattern
+// without a slash may also match just the file name, and a pattern matching
+// a directory matches everything in it, so "*.pb.go" and "vendor" work at
+// any depth.
+func matchFileGlob(pattern, filename string) bool {
+	if ok, _ := path.Match(pattern, filename); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		if ok, _ := path.Match(pattern, path.Base(filename)); ok {
+			return true
+		}
+	}
+	for dir := path.Dir(filename); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if ok, _ := path.Match(pattern, dir); ok {
+			return true
 		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(dir)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BaseBranchNitpicky applies the base_branch_nitpicky rule for a PR's base
+// branch to a nitpicky level. A rule is a level ("8") or an offset ("+2",
+// "-1"). When several patterns match, an exact branch name wins, then the
+// longest pattern. Also returns the pattern that matched, or "" if none did
+// and the level is unchanged.
+func (c *Config) BaseBranchNitpicky(base string, level int) (int, string) {
+	var best string
+	for pattern := range c.BaseBranchNitpickyRules {
+		if ok, _ := path.Match(pattern, base); !ok {
+			continue
+		}
+		switch {
+		case best == "", pattern == base:
+			best = pattern
+		case best == base:
+		case len(pattern) > len(best), len(pattern) == len(best) && pattern < best:
+			best = pattern
+		}
+	}
+	if best == "" {
+		return level, ""
+	}
+
+	n, offset, err := parseNitpickyRule(c.BaseBranchNitpickyRules[best])
+	if err != nil {
+		return level, "" // Validate catches this
+	}
+	if offset {
+		return level + n, best
+	}
+	return n, best
+}
+
+// parseNitpickyRule parses a base_branch_nitpicky value: a level, or an
+// offset when it starts with + or -
+func parseNitpickyRule(rule string) (n int, offset bool, err error) {
+	rule = strings.TrimSpace(rule)
+	n, err = strconv.Atoi(rule)
+	if err != nil {
+		return 0, false, err
 	}
+	return n, strings.HasPrefix(rule, "+") || strings.HasPrefix(rule, "-"), nil
 }
 
 // GetReviewerBias returns a multiplier for nitpicky level based on reviewer preference
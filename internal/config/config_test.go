@@ -0,0 +1,59 @@
+package config
+
+import "testing"
+
+func TestUnset(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"nitpicky_level", false},
+		{"ai_extra_headers.X-Team", false},
+		{"", true},
+		{"-", true},
+		{"org", true},
+		{"no_such_key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.NitpickyLevel = 9
+			cfg.ExtraHeaders = map[string]string{"X-Team": "platform"}
+
+			err := cfg.Unset(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unset(%q) = %v, want error: %t", tt.key, err, tt.wantErr)
+			}
+			switch tt.key {
+			case "nitpicky_level":
+				if cfg.NitpickyLevel != DefaultConfig().NitpickyLevel {
+					t.Errorf("nitpicky_level = %d, want the default %d", cfg.NitpickyLevel, DefaultConfig().NitpickyLevel)
+				}
+			case "ai_extra_headers.X-Team":
+				if _, ok := cfg.ExtraHeaders["X-Team"]; ok {
+					t.Errorf("X-Team header still set")
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAIApiURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{"https://api.openai.com/v1", false},
+		{"http://localhost:11434/v1", false},
+		{"api.openai.com/v1", true},
+		{"localhost:11434", true},
+		{"ftp://api.openai.com/v1", true},
+		{"https://", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateAIApiURL(tt.raw); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAIApiURL(%q) = %v, want error: %t", tt.raw, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables that override the config file, e.g. to keep secrets
+// out of it in CI
+const (
+	EnvGitHubToken   = "SALTY_GITHUB_TOKEN"
+	EnvAIApiKey      = "SALTY_AI_API_KEY"
+	EnvAIApiURL      = "SALTY_AI_API_URL"
+	EnvAIProvider    = "SALTY_AI_PROVIDER"
+	EnvAIModel       = "SALTY_AI_MODEL"
+	EnvWritingStyle  = "SALTY_WRITING_STYLE"
+	EnvNitpickyLevel = "SALTY_NITPICKY_LEVEL"
+)
+
+// applyEnv overwrites config values with the SALTY_* environment variables
+// that are set and not empty. Load applies it to the config it returns;
+// LoadLocal only to a copy it validates, so values from the environment are
+// never saved to the config file.
+func (c *Config) applyEnv() error {
+	for name, field := range map[string]*string{
+		EnvGitHubToken: &c.GitHubToken,
+		EnvAIApiKey:    &c.AIApiKey,
+		EnvAIApiURL:    &c.AIApiURL,
+		EnvAIProvider:  &c.AIProvider,
+		EnvAIModel:     &c.AIModel,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*field = v
+		}
+	}
+
+	if v := os.Getenv(EnvWritingStyle); v != "" {
+		switch style := WritingStyle(v); style {
+		case StyleCorporate, StylePassiveAggressive, StyleTechBro, StyleAcademic, StyleCustom:
+			c.WritingStyle = style
+		default:
+			return fmt.Errorf("%s: unknown writing style %s", EnvWritingStyle, v)
+		}
+	}
+
+	if v := os.Getenv(EnvNitpickyLevel); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s must be a number between 1 and 10", EnvNitpickyLevel)
+		}
+		c.NitpickyLevel = level
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantErr   bool
+		wantLevel int
+		wantModel string
+	}{
+		{"file values without env", nil, false, 3, "file-model"},
+		{"env overrides the file", map[string]string{EnvNitpickyLevel: "8", EnvAIModel: "env-model"}, false, 8, "env-model"},
+		{"empty env is ignored", map[string]string{EnvNitpickyLevel: "", EnvAIModel: ""}, false, 3, "file-model"},
+		{"non-numeric level rejected", map[string]string{EnvNitpickyLevel: "loud"}, true, 0, ""},
+		{"out of range level rejected", map[string]string{EnvNitpickyLevel: "11"}, true, 0, ""},
+		{"unknown writing style rejected", map[string]string{EnvWritingStyle: "shouty"}, true, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			for _, name := range []string{EnvGitHubToken, EnvAIApiKey, EnvAIApiURL, EnvAIProvider, EnvAIModel, EnvWritingStyle, EnvNitpickyLevel} {
+				t.Setenv(name, "")
+			}
+			for name, v := range tt.env {
+				t.Setenv(name, v)
+			}
+
+			dir := filepath.Join(home, ".salty-reviewer")
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			file := "github_token: file-token\nai_api_key: file-key\nai_model: file-model\nnitpicky_level: 3\n"
+			if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(file), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.NitpickyLevel != tt.wantLevel {
+				t.Errorf("nitpicky_level = %d, want %d", cfg.NitpickyLevel, tt.wantLevel)
+			}
+			if cfg.AIModel != tt.wantModel {
+				t.Errorf("ai_model = %q, want %q", cfg.AIModel, tt.wantModel)
+			}
+		})
+	}
+}
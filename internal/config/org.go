@@ -0,0 +1,268 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// orgFetchTimeout bounds how long fetching the org policy may take
+const orgFetchTimeout = 10 * time.Second
+
+// OrgPolicy is a read-only config a platform team publishes to put guardrails
+// on every developer's settings. Caps limit a key, pins fix it outright; where
+// the policy and a user's config disagree, the policy wins.
+type OrgPolicy struct {
+	MaxNitpickyLevel         int            `yaml:"max_nitpicky_level"`         // 0 = no cap
+	MaxDefenseAggressiveness int            `yaml:"max_defense_aggressiveness"` // 0 = no cap
+	AllowedWritingStyles     []WritingStyle `yaml:"allowed_writing_styles"`     // empty = all
+
+	// Pin holds config keys and the values they are fixed to, written just
+	// like in config.yaml
+	Pin yaml.Node `yaml:"pin"`
+
+	// Source is where the policy came from, for error messages
+	Source string `yaml:"-"`
+}
+
+// LoadOrgPolicy fetches the policy named by c.OrgConfig, or returns nil if
+// there isn't one. The source is an http(s) URL, github:owner/repo/path[@ref]
+// for a file in a repository (read with c.GitHubToken), or a local path. If
+// c.OrgConfigSHA256 is set the policy must hash to it exactly. When the
+// source can't be reached, the last policy fetched successfully is used.
+func LoadOrgPolicy(c *Config) (*OrgPolicy, error) {
+	if c.OrgConfig == "" {
+		return nil, nil
+	}
+
+	data, fetchErr := fetchOrgConfig(c.OrgConfig, c.GitHubToken)
+	if fetchErr != nil {
+		cached, err := readOrgCache(c.OrgConfig)
+		if err != nil {
+			return nil, fmt.Errorf("could not load org config from %s: %w", c.OrgConfig, fetchErr)
+		}
+		data = cached
+	}
+
+	if c.OrgConfigSHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), c.OrgConfigSHA256) {
+			return nil, fmt.Errorf("org config from %s doesn't match org_config_sha256", c.OrgConfig)
+		}
+	}
+
+	p := &OrgPolicy{Source: c.OrgConfig}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("could not parse org config from %s: %w", c.OrgConfig, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("org config from %s: %w", c.OrgConfig, err)
+	}
+
+	if fetchErr == nil {
+		writeOrgCache(c.OrgConfig, data)
+	}
+	return p, nil
+}
+
+func (p *OrgPolicy) validate() error {
+	if p.MaxNitpickyLevel < 0 || p.MaxNitpickyLevel > 10 {
+		return fmt.Errorf("max_nitpicky_level must be between 1 and 10, or 0 for no cap")
+	}
+	if p.MaxDefenseAggressiveness < 0 || p.MaxDefenseAggressiveness > 10 {
+		return fmt.Errorf("max_defense_aggressiveness must be between 1 and 10, or 0 for no cap")
+	}
+	for _, s := range p.AllowedWritingStyles {
+		switch s {
+		case StyleCorporate, StylePassiveAggressive, StyleTechBro, StyleAcademic, StyleCustom:
+		default:
+			return fmt.Errorf("unknown writing style in allowed_writing_styles: %s", s)
+		}
+	}
+	if p.Pin.Kind != 0 && p.Pin.Kind != yaml.MappingNode {
+		return fmt.Errorf("pin must be a mapping of config keys")
+	}
+	return nil
+}
+
+// fetchOrgConfig reads the raw policy from its source
+func fetchOrgConfig(source, token string) ([]byte, error) {
+	var req *http.Request
+	var err error
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		req, err = http.NewRequest(http.MethodGet, source, nil)
+	case strings.HasPrefix(source, "github:"):
+		spec, ref, _ := strings.Cut(strings.TrimPrefix(source, "github:"), "@")
+		parts := strings.SplitN(spec, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("use github:owner/repo/path[@ref]")
+		}
+		url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", parts[0], parts[1], parts[2])
+		if ref != "" {
+			url += "?ref=" + ref
+		}
+		req, err = http.NewRequest(http.MethodGet, url, nil)
+		if err == nil {
+			req.Header.Set("Accept", "application/vnd.github.raw")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+	default:
+		return os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: orgFetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// orgCachePath is where the last policy fetched from source is kept
+func orgCachePath(source string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(dir, "org_config."+hex.EncodeToString(sum[:6])+".yaml"), nil
+}
+
+func readOrgCache(source string) ([]byte, error) {
+	path, err := orgCachePath(source)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// writeOrgCache is best effort: without a cache, an unreachable source is an
+// error instead of a fallback
+func writeOrgCache(source string, data []byte) {
+	path, err := orgCachePath(source)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0600)
+}
+
+// Apply enforces the policy on c: pinned keys are overwritten, capped
+// levels are lowered and a disallowed writing style is replaced by the
+// first allowed one
+func (p *OrgPolicy) Apply(c *Config) error {
+	if p.Pin.Kind == yaml.MappingNode {
+		if err := p.Pin.Decode(c); err != nil {
+			return fmt.Errorf("org config from %s: invalid pin: %w", p.Source, err)
+		}
+	}
+	if p.MaxNitpickyLevel > 0 && c.NitpickyLevel > p.MaxNitpickyLevel {
+		c.NitpickyLevel = p.MaxNitpickyLevel
+	}
+	if p.MaxDefenseAggressiveness > 0 && c.DefenseAggressiveness > p.MaxDefenseAggressiveness {
+		c.DefenseAggressiveness = p.MaxDefenseAggressiveness
+	}
+	if len(p.AllowedWritingStyles) > 0 && !p.allowsStyle(c.WritingStyle) {
+		c.WritingStyle = p.AllowedWritingStyles[0]
+	}
+	c.org = p
+	return nil
+}
+
+// Check returns an error explaining why setting key to its value in c is
+// not allowed by the policy, or nil if it is
+func (p *OrgPolicy) Check(c *Config, key string) error {
+	if value, ok := p.pinned(key); ok {
+		return fmt.Errorf("%s is pinned to %q by the org config (%s)", key, value, p.Source)
+	}
+	switch key {
+	case "org_config", "org_config_sha256":
+		return fmt.Errorf("%s can't be changed while the org config (%s) is in effect - ask your platform team", key, p.Source)
+	case "nitpicky_level":
+		if p.MaxNitpickyLevel > 0 && c.NitpickyLevel > p.MaxNitpickyLevel {
+			return fmt.Errorf("nitpicky_level can't be above %d - that's the org limit (%s)", p.MaxNitpickyLevel, p.Source)
+		}
+	case "defense_aggressiveness":
+		if p.MaxDefenseAggressiveness > 0 && c.DefenseAggressiveness > p.MaxDefenseAggressiveness {
+			return fmt.Errorf("defense_aggressiveness can't be above %d - that's the org limit (%s)", p.MaxDefenseAggressiveness, p.Source)
+		}
+	case "writing_style":
+		if len(p.AllowedWritingStyles) > 0 && !p.allowsStyle(c.WritingStyle) {
+			allowed := make([]string, len(p.AllowedWritingStyles))
+			for i, s := range p.AllowedWritingStyles {
+				allowed[i] = string(s)
+			}
+			return fmt.Errorf("writing_style %s isn't allowed by the org config (%s); use one of: %s",
+				c.WritingStyle, p.Source, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// PinnedKeys lists the keys the policy pins, in the order it lists them
+func (p *OrgPolicy) PinnedKeys() []string {
+	var keys []string
+	for i := 0; i+1 < len(p.Pin.Content); i += 2 {
+		keys = append(keys, p.Pin.Content[i].Value)
+	}
+	return keys
+}
+
+// pinned returns the value key is pinned to, as written in the policy
+func (p *OrgPolicy) pinned(key string) (string, bool) {
+	for i := 0; i+1 < len(p.Pin.Content); i += 2 {
+		if p.Pin.Content[i].Value == key {
+			v := p.Pin.Content[i+1]
+			if v.Kind != yaml.ScalarNode {
+				out, _ := yaml.Marshal(v)
+				return strings.TrimSpace(string(out)), true
+			}
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+func (p *OrgPolicy) allowsStyle(style WritingStyle) bool {
+	for _, s := range p.AllowedWritingStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// OrgPolicy returns the policy applied by Load, or nil if there is none
+func (c *Config) OrgPolicy() *OrgPolicy {
+	return c.org
+}
+
+// MaxNitpicky is the highest nitpicky level a review may run at after base
+// branch rules and reviewer bias: 10, or lower if the org policy caps it
+func (c *Config) MaxNitpicky() int {
+	if c.org != nil && c.org.MaxNitpickyLevel > 0 && c.org.MaxNitpickyLevel < 10 {
+		return c.org.MaxNitpickyLevel
+	}
+	return 10
+}
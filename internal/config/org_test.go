@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestOrgPolicyCheck(t *testing.T) {
+	p := &OrgPolicy{MaxNitpickyLevel: 6, Source: "policy.yaml"}
+	tests := []struct {
+		key      string
+		nitpicky int
+		wantErr  bool
+	}{
+		{"nitpicky_level", 6, false},
+		{"nitpicky_level", 7, true},
+		{"org_config", 5, true},
+		{"org_config_sha256", 5, true},
+		{"ai_model", 9, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.NitpickyLevel = tt.nitpicky
+		if err := p.Check(cfg, tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("Check(%s) with nitpicky_level %d = %v, want error: %t", tt.key, tt.nitpicky, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RepoOverride changes some review settings for one repository. Fields left
+// unset keep the value from the rest of the config.
+type RepoOverride struct {
+	WritingStyle      WritingStyle `yaml:"writing_style,omitempty"`
+	NitpickyLevel     int          `yaml:"nitpicky_level,omitempty"` // 1-10, 0 = inherit
+	LikedReviewers    []string     `yaml:"liked_reviewers,omitempty"`
+	DislikedReviewers []string     `yaml:"disliked_reviewers,omitempty"`
+}
+
+// ForRepo returns the config to use for owner/repo: a copy with the repo's
+// override applied, or c itself if there is none. The org policy still
+// applies on top of the override.
+func (c *Config) ForRepo(owner, repo string) *Config {
+	o, ok := c.repoOverride(owner, repo)
+	if !ok {
+		return c
+	}
+
+	eff := *c
+	if o.WritingStyle != "" {
+		eff.WritingStyle = o.WritingStyle
+	}
+	if o.NitpickyLevel != 0 {
+		eff.NitpickyLevel = o.NitpickyLevel
+	}
+	if o.LikedReviewers != nil {
+		eff.LikedReviewers = o.LikedReviewers
+	}
+	if o.DislikedReviewers != nil {
+		eff.DislikedReviewers = o.DislikedReviewers
+	}
+	if c.org != nil {
+		// Validated when the config was loaded, so this can't fail
+		_ = c.org.Apply(&eff)
+	}
+	if warning := eff.checkCustomStyle(); warning != "" {
+		eff.styleWarning = warning
+	}
+	return &eff
+}
+
+// repoOverride finds the override for owner/repo. GitHub names are case
+// insensitive, so the keys are too.
+func (c *Config) repoOverride(owner, repo string) (RepoOverride, bool) {
+	name := owner + "/" + repo
+	for key, o := range c.Repos {
+		if strings.EqualFold(key, name) {
+			return o, true
+		}
+	}
+	return RepoOverride{}, false
+}
+
+func (c *Config) validateRepos() error {
+	for key, o := range c.Repos {
+		owner, repo, ok := strings.Cut(key, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return fmt.Errorf("repos: %q must be owner/repo", key)
+		}
+		if o.NitpickyLevel != 0 && (o.NitpickyLevel < 1 || o.NitpickyLevel > 10) {
+			return fmt.Errorf("repos.%s: nitpicky_level must be between 1 and 10", key)
+		}
+		switch o.WritingStyle {
+		case "", StyleCorporate, StylePassiveAggressive, StyleTechBro, StyleAcademic:
+		case StyleCustom:
+			if c.CustomStylePath == "" {
+				return fmt.Errorf("repos.%s: writing_style custom needs custom_style_path", key)
+			}
+		default:
+			return fmt.Errorf("repos.%s: unknown writing_style %s", key, o.WritingStyle)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CustomStyle holds the prompts of the custom writing style. The file at
+// custom_style_path is either YAML with review and defense keys, or plain
+// text used for both.
+type CustomStyle struct {
+	Review  string `yaml:"review"`
+	Defense string `yaml:"defense"`
+}
+
+// LoadCustomStyle reads the custom writing style from custom_style_path. A
+// file with only one of the two prompts uses it for both.
+func (c *Config) LoadCustomStyle() (*CustomStyle, error) {
+	if c.CustomStylePath == "" {
+		return nil, fmt.Errorf("custom_style_path is not set")
+	}
+	data, err := os.ReadFile(c.CustomStylePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read custom style: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return nil, fmt.Errorf("custom style %s is empty", c.CustomStylePath)
+	}
+
+	var style CustomStyle
+	if err := yaml.Unmarshal(data, &style); err != nil {
+		return &CustomStyle{Review: text, Defense: text}, nil
+	}
+	style.Review = strings.TrimSpace(style.Review)
+	style.Defense = strings.TrimSpace(style.Defense)
+	switch {
+	case style.Review == "" && style.Defense == "":
+		return &CustomStyle{Review: text, Defense: text}, nil
+	case style.Review == "":
+		style.Review = style.Defense
+	case style.Defense == "":
+		style.Defense = style.Review
+	}
+	return &style, nil
+}
+
+// CustomStyle returns the custom style read when the config was loaded, or
+// nil if custom_style_path isn't set or couldn't be read
+func (c *Config) CustomStyle() *CustomStyle {
+	return c.customStyle
+}
+
+// StyleWarning says why the custom style was replaced with
+// passive_aggressive, or returns "" if it wasn't
+func (c *Config) StyleWarning() string {
+	return c.styleWarning
+}
+
+// checkCustomStyle reads the custom style once, so prompts don't re-read the
+// file. If the custom style is in use but can't be read, it switches to
+// passive_aggressive and returns a warning, so a moved file doesn't stop
+// every review.
+func (c *Config) checkCustomStyle() string {
+	c.customStyle = nil
+	if c.CustomStylePath == "" {
+		return ""
+	}
+	style, err := c.LoadCustomStyle()
+	if err == nil {
+		c.customStyle = style
+		return ""
+	}
+	if c.WritingStyle != StyleCustom {
+		return ""
+	}
+	c.WritingStyle = StylePassiveAggressive
+	return fmt.Sprintf("%v - using the passive_aggressive style instead", err)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCustomStyle(t *testing.T) {
+	dir := t.TempDir()
+	stylePath := filepath.Join(dir, "style.yaml")
+	if err := os.WriteFile(stylePath, []byte("review: be terse\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "moved.yaml")
+
+	tests := []struct {
+		name        string
+		style       WritingStyle
+		path        string
+		wantStyle   WritingStyle
+		wantWarning bool
+		wantLoaded  bool
+	}{
+		{"custom style", StyleCustom, stylePath, StyleCustom, false, true},
+		{"custom style moved", StyleCustom, missing, StylePassiveAggressive, true, false},
+		{"other style with a path", StyleTechBro, stylePath, StyleTechBro, false, true},
+		{"other style with a bad path", StyleTechBro, missing, StyleTechBro, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.WritingStyle = tt.style
+			cfg.CustomStylePath = tt.path
+
+			warning := cfg.checkCustomStyle()
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("warning = %q, want one: %t", warning, tt.wantWarning)
+			}
+			if cfg.WritingStyle != tt.wantStyle {
+				t.Errorf("style = %s, want %s", cfg.WritingStyle, tt.wantStyle)
+			}
+			custom := cfg.CustomStyle()
+			if (custom != nil) != tt.wantLoaded {
+				t.Fatalf("custom style = %+v, want loaded: %t", custom, tt.wantLoaded)
+			}
+			if custom != nil && (custom.Review != "be terse" || custom.Defense != "be terse") {
+				t.Errorf("custom style = %+v, want both prompts from the review key", custom)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package defender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// Orders accepted by BatchOptions.Order
+const (
+	OrderNumber  = "number"  // oldest PR first
+	OrderUpdated = "updated" // most recently updated PR first
+)
+
+// BatchOptions controls DefendAll
+type BatchOptions struct {
+	DryRun          bool
+	ResolveConceded bool   // See DefendOptions
+	AllowDraft      bool   // Defend draft PRs too instead of skipping them
+	Concurrency     int    // PRs defended at once, at least 1
+	Order           string // OrderNumber or OrderUpdated
+}
+
+// errNotStarted marks PRs DefendAll skipped after GitHub rejected the token
+var errNotStarted = errors.New("not started")
+
+// BatchResult is the outcome of defending one PR as part of DefendAll. Err is
+// set if the PR couldn't be defended, Result if it got far enough to have one.
+type BatchResult struct {
+	Number int
+	Title  string
+	Result *DefenseResult
+	Err    error
+}
+
+// DefendAll defends every open PR in owner/repo authored by the
+// authenticated user. PRs are started in the requested order, at most
+// Concurrency at a time, and one failing PR doesn't stop the rest. Each PR's
+// progress is buffered and written out in one piece when it finishes so
+// concurrent runs don't interleave. If GitHub rejects the token, PRs not yet
+// started are skipped and the credential error is returned along with the
+// results; otherwise the error is only for failures that prevent the batch
+// from starting.
+func (d *Defender) DefendAll(owner, repo string, opts BatchOptions) ([]BatchResult, error) {
+	before := d.aiClient.Usage()
+	order := opts.Order
+	if order == "" {
+		order = OrderNumber
+	}
+	if order != OrderNumber && order != OrderUpdated {
+		return nil, fmt.Errorf("invalid order %q (use %s or %s)", opts.Order, OrderNumber, OrderUpdated)
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	me, err := d.getMyUsername()
+	if err != nil {
+		return nil, err
+	}
+	if me == "" {
+		return nil, fmt.Errorf("can't tell which PRs are yours without your GitHub username")
+	}
+
+	fmt.Fprintf(d.out, "🛡️  Listing open PRs in %s/%s...\n", owner, repo)
+	prs, err := d.githubClient.ListOpenPRs(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var mine []*github.PRInfo
+	drafts := 0
+	for _, pr := range prs {
+		if pr.Author != me {
+			continue
+		}
+		if pr.Draft && !d.config.ReviewDrafts && !opts.AllowDraft {
+			drafts++
+			continue
+		}
+		mine = append(mine, pr)
+	}
+	sortPRs(mine, order)
+	if drafts > 0 {
+		fmt.Fprintf(d.out, "⏭️  Skipping %d draft PR(s) (pass --allow-draft to defend them)\n", drafts)
+	}
+
+	if len(mine) == 0 {
+		fmt.Fprintf(d.out, "🎉 No open PRs by @%s to defend!\n", me)
+		return nil, nil
+	}
+	fmt.Fprintf(d.out, "💬 Defending %d open PRs by @%s (%d at a time)\n", len(mine), me, concurrency)
+
+	results := make([]BatchResult, len(mine))
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex // serializes writes to d.out and guards authErr
+		sem     = make(chan struct{}, concurrency)
+		authErr error
+	)
+	for i, pr := range mine {
+		sem <- struct{}{}
+		mu.Lock()
+		stopped := authErr != nil
+		mu.Unlock()
+		if stopped {
+			<-sem
+			results[i] = BatchResult{Number: pr.Number, Title: pr.Title, Err: errNotStarted}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, pr *github.PRInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var buf bytes.Buffer
+			worker := *d
+			worker.out = d.out.To(&buf)
+			worker.live = false // buffered output would only show it afterwards
+
+			ref := fmt.Sprintf("%s/%s#%d", owner, repo, pr.Number)
+			res, err := worker.Defend(ref, DefendOptions{DryRun: opts.DryRun, ResolveConceded: opts.ResolveConceded, AllowDraft: opts.AllowDraft})
+			results[i] = BatchResult{Number: pr.Number, Title: pr.Title, Result: res, Err: err}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, github.ErrBadCredentials) && authErr == nil {
+				authErr = err
+			}
+			// Already filtered by the worker's logger
+			w := d.out.Result()
+			fmt.Fprintf(w, "\n════ #%d %s ════\n", pr.Number, pr.Title)
+			w.Write(buf.Bytes())
+			if err != nil {
+				fmt.Fprintf(w, "❌ Failed: %v\n", err)
+			}
+		}(i, pr)
+	}
+	wg.Wait()
+
+	writeBatchSummary(d, results, d.aiClient.Usage().Sub(before))
+	if authErr != nil {
+		return results, github.ErrBadCredentials
+	}
+	return results, nil
+}
+
+// sortPRs orders PRs for DefendAll
+func sortPRs(prs []*github.PRInfo, order string) {
+	sort.SliceStable(prs, func(i, j int) bool {
+		if order == OrderUpdated {
+			return prs[i].UpdatedAt.After(prs[j].UpdatedAt)
+		}
+		return prs[i].Number < prs[j].Number
+	})
+}
+
+// writeBatchSummary prints one line per PR and the combined totals. Token
+// usage is only given for the whole batch, since PRs defended at the same
+// time share the AI client.
+func writeBatchSummary(d *Defender, results []BatchResult, used ai.Usage) {
+	var total DefenseStats
+	failed := 0
+	w := d.out.Result()
+
+	fmt.Fprintln(w, "\n📊 Batch summary:")
+	for _, r := range results {
+		if errors.Is(r.Err, errNotStarted) {
+			failed++
+			fmt.Fprintf(w, "   #%d %s: ⏭️  not started\n", r.Number, truncate(r.Title, 50))
+			continue
+		}
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "   #%d %s: ❌ %v\n", r.Number, truncate(r.Title, 50), r.Err)
+			continue
+		}
+		s := r.Result.Stats
+		total.Defended += s.Defended
+		total.Conceded += s.Conceded
+		total.Skipped += s.Skipped
+		fmt.Fprintf(w, "   #%d %s: %d defended, %d conceded, %d skipped\n",
+			r.Number, truncate(r.Title, 50), s.Defended, s.Conceded, s.Skipped)
+	}
+	fmt.Fprintf(w, "   Total: %d defended, %d conceded, %d skipped across %d PRs",
+		total.Defended, total.Conceded, total.Skipped, len(results)-failed)
+	if failed > 0 {
+		fmt.Fprintf(w, " (%d failed)", failed)
+	}
+	fmt.Fprintf(w, ", %s\n", describeUsage(used.TotalTokens,
+		d.config.EstimateCostUSD(used.PromptTokens, used.CompletionTokens, used.TotalTokens)))
+}
+
+// Failed returns how many PRs in a batch could not be defended
+func Failed(results []BatchResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
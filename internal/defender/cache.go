@@ -0,0 +1,64 @@
+package defender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/state"
+)
+
+// responseKey hashes everything that shapes a defend response. A cached
+// response is reused only while the comment, the code it is on and the
+// defense settings and the model writing it are unchanged; editing any of
+// them changes the key. For the custom style that includes the style file's
+// defense prompt.
+func (d *Defender) responseKey(comment *github.PRComment, codeContext string) string {
+	parts := []string{
+		comment.Body,
+		codeContext,
+		string(d.config.WritingStyle),
+		strconv.Itoa(d.config.DefenseAggressiveness),
+		strconv.Itoa(d.config.ConcedeThreshold),
+		strconv.FormatBool(d.config.ConcedeWithSuggestion),
+		d.config.AIModel,
+	}
+	if d.config.WritingStyle == config.StyleCustom {
+		parts = append(parts, getDefenseStyleGuide(d.config))
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadResponses returns the responses cached for a PR by earlier runs.
+// An unreadable state file just means nothing is cached.
+func loadResponses(pr string) map[string]state.CachedDefense {
+	st, err := state.Load()
+	if err != nil {
+		return nil
+	}
+	return st.Defenses[pr]
+}
+
+// saveResponses replaces the responses cached for a PR. Only entries used in
+// the current run are passed in, so responses to edited or deleted comments
+// are dropped rather than piling up.
+func saveResponses(pr string, responses map[string]state.CachedDefense) error {
+	return state.Update(func(st *state.State) {
+		if len(responses) == 0 {
+			delete(st.Defenses, pr)
+			return
+		}
+		if st.Defenses == nil {
+			st.Defenses = make(map[string]map[string]state.CachedDefense)
+		}
+		st.Defenses[pr] = responses
+	})
+}
@@ -2,12 +2,25 @@ package defender
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/aiconfig"
+	"github.com/user/salty-reviewer/internal/condense"
 	"github.com/user/salty-reviewer/internal/config"
 	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/hook"
+	"github.com/user/salty-reviewer/internal/jsonx"
+	"github.com/user/salty-reviewer/internal/live"
+	"github.com/user/salty-reviewer/internal/logging"
+	"github.com/user/salty-reviewer/internal/signature"
+	"github.com/user/salty-reviewer/internal/state"
 )
 
 // DefenseResult is the output of defending a PR
@@ -21,6 +34,7 @@ type CommentResponse struct {
 	OriginalComment *github.PRComment
 	Response        string
 	Action          string // DEFEND or CONCEDE
+	ConfidenceValid int    // How valid the analysis judged the comment, 0-100
 }
 
 // DefenseStats tracks defense statistics
@@ -28,16 +42,22 @@ type DefenseStats struct {
 	CommentsAnalyzed int
 	Defended         int
 	Conceded         int
-	Skipped          int
+	Skipped          int // Failed, cut off by the budget or declined with --interactive
+	Posted           int
+
+	// AI tokens the run used, and what they cost if prices are configured.
+	// When DefendAll runs PRs concurrently these include the others' calls.
+	TokensUsed       int
+	EstimatedCostUSD float64
 }
 
 // CommentAnalysis is the AI analysis of a reviewer comment
 type CommentAnalysis struct {
-	IsValidIssue       bool     `json:"is_valid_issue"`
-	ConfidenceValid    int      `json:"confidence_its_valid"`
-	DefensePoints      []string `json:"defense_points"`
-	WhatTheyMissed     string   `json:"what_they_missed"`
-	RecommendedAction  string   `json:"recommended_action"`
+	IsValidIssue      bool     `json:"is_valid_issue"`
+	ConfidenceValid   int      `json:"confidence_its_valid"`
+	DefensePoints     []string `json:"defense_points"`
+	WhatTheyMissed    string   `json:"what_they_missed"`
+	RecommendedAction string   `json:"recommended_action"`
 }
 
 // Defender handles PR comment defense
@@ -45,214 +65,575 @@ type Defender struct {
 	config       *config.Config
 	githubClient *github.Client
 	aiClient     *ai.Client
+	out          *logging.Logger // progress output, os.Stdout unless overridden
+	myUsername   string          // the authenticated user, once looked up
+	live         bool            // stream defenses to out as they're written
 }
 
 // NewDefender creates a new defender instance
 func NewDefender(cfg *config.Config) *Defender {
-	return &Defender{
+	d := &Defender{
 		config:       cfg,
-		githubClient: github.NewClient(cfg.GitHubToken),
-		aiClient:     ai.NewClient(cfg.AIApiURL, cfg.AIApiKey, cfg.AIModel),
+		githubClient: github.NewClient(cfg.GitHubToken, github.WithMaxFileBytes(cfg.MaxFileBytes)),
+		out:          logging.New(os.Stdout, cfg.Verbosity),
 	}
+	d.aiClient = aiconfig.NewClient(cfg, ai.WithDebugLog(func(format string, args ...interface{}) {
+		d.out.Debugf(format, args...)
+	}))
+	return d
+}
+
+// SetOutput redirects progress output, filtered by the config's verbosity.
+// Library callers that only want the returned result can pass io.Discard.
+func (d *Defender) SetOutput(w io.Writer) {
+	d.out = logging.New(w, d.config.Verbosity)
+}
+
+// SetLiveOutput streams each defense to the progress output while the AI
+// writes it, so long generation calls don't look stuck. Meant for terminals.
+func (d *Defender) SetLiveOutput(enabled bool) {
+	d.live = enabled
+}
+
+// DefendOptions controls how a PR is defended
+type DefendOptions struct {
+	DryRun bool // Show the responses without posting them
+
+	// ResolveConceded resolves the review thread of every comment conceded,
+	// once the concession is posted
+	ResolveConceded bool
+
+	// AllowDraft defends draft PRs, like review_drafts
+	AllowDraft bool
+
+	// ConfirmResponse is asked about each response before it is posted, for
+	// --interactive. It returns the text to post, possibly edited, or false
+	// to skip the response. Nil posts every response.
+	ConfirmResponse func(r CommentResponse) (string, bool)
 }
 
 // Defend analyzes and responds to comments on your PR
-func (d *Defender) Defend(prRef string, dryRun bool) (*DefenseResult, error) {
+func (d *Defender) Defend(prRef string, opts DefendOptions) (*DefenseResult, error) {
+	before := d.aiClient.Usage()
 	ref, err := github.ParsePRReference(prRef)
 	if err != nil {
 		return nil, err
 	}
+	if cfg := d.config.ForRepo(ref.Owner, ref.Repo); cfg != d.config {
+		scoped := *d
+		scoped.config = cfg
+		d = &scoped
+	}
+	if warning := d.config.StyleWarning(); warning != "" {
+		d.out.Warnf("⚠️  %s", warning)
+	}
 
-	fmt.Printf("🛡️  Fetching PR #%d from %s/%s...\n", ref.Number, ref.Owner, ref.Repo)
+	fmt.Fprintf(d.out, "🛡️  Fetching PR #%d from %s/%s...\n", ref.Number, ref.Owner, ref.Repo)
 
 	// Get PR details
 	pr, err := d.githubClient.GetPR(ref)
 	if err != nil {
 		return nil, err
 	}
+	if pr.GetDraft() && !d.config.ReviewDrafts && !opts.AllowDraft {
+		return nil, fmt.Errorf("PR #%d is a draft - pass --allow-draft or set review_drafts to defend it anyway", ref.Number)
+	}
 
-	myUsername := d.getMyUsername()
-	if pr.GetUser().GetLogin() != myUsername {
-		fmt.Printf("⚠️  Warning: This PR was created by @%s, not you (@%s)\n", pr.GetUser().GetLogin(), myUsername)
+	myUsername, err := d.getMyUsername()
+	if err != nil {
+		return nil, err
+	}
+	if myUsername != "" && pr.GetUser().GetLogin() != myUsername {
+		d.out.Warnf("⚠️  Warning: This PR was created by @%s, not you (@%s)", pr.GetUser().GetLogin(), myUsername)
 	}
 
-	fmt.Printf("📝 PR: %s\n", pr.GetTitle())
+	fmt.Fprintf(d.out, "📝 PR: %s\n", pr.GetTitle())
 
-	// Get all comments
+	// Get all comments, on the code and on the conversation
 	comments, err := d.githubClient.GetPRComments(ref)
 	if err != nil {
 		return nil, err
 	}
+	issueComments, err := d.githubClient.GetIssueComments(ref)
+	if err != nil {
+		return nil, err
+	}
+	comments = append(comments, issueComments...)
+
+	var submitted map[int64]bool
+	if d.config.DefendSubmittedOnly {
+		submitted, err = d.githubClient.ListSubmittedReviewIDs(ref)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// Filter to comments from others (not our own replies)
+	// Filter to comments from others (not our own replies or review
+	// digests), leaving out people we'd rather answer personally and reviews
+	// still in progress
 	var otherComments []*github.PRComment
+	ignored, pending := 0, 0
 	for _, c := range comments {
-		if c.User != myUsername && c.InReplyTo == 0 {
-			otherComments = append(otherComments, c)
+		if c.User == myUsername || c.InReplyTo != 0 {
+			continue
+		}
+		if mode := signature.ModeOf(c.Body); mode == signature.ModeDefend || mode == signature.ModeDigest {
+			continue
+		}
+		if submitted != nil && c.ReviewID != 0 && !submitted[c.ReviewID] {
+			pending++
+			continue
+		}
+		if d.config.IsDefenseIgnored(c.User) {
+			fmt.Fprintf(d.out, "⏭️  Skipping comment from @%s on %s (in defense_ignore_users)\n", c.User, c.Where())
+			ignored++
+			continue
 		}
+		otherComments = append(otherComments, c)
 	}
 
-	fmt.Printf("💬 Found %d comments from reviewers\n", len(otherComments))
-
-	if len(otherComments) == 0 {
-		fmt.Println("🎉 No comments to respond to!")
-		return &DefenseResult{}, nil
+	if pending > 0 {
+		fmt.Fprintf(d.out, "⏳ Skipping %d comment(s) from reviews that haven't been submitted yet\n", pending)
 	}
+	fmt.Fprintf(d.out, "💬 Found %d comments from reviewers\n", len(otherComments))
 
 	result := &DefenseResult{
 		Stats: DefenseStats{
 			CommentsAnalyzed: len(otherComments),
+			Skipped:          ignored + pending,
 		},
 	}
 
+	if len(otherComments) == 0 {
+		fmt.Fprintln(d.out, "🎉 No comments to respond to!")
+		return result, nil
+	}
+
 	// Get file contents for context
-	files, _ := d.githubClient.GetPRFiles(ref)
+	files, err := d.githubClient.GetPRFiles(ref)
+	if errors.Is(err, github.ErrBadCredentials) {
+		return nil, err
+	}
 	fileContents := make(map[string]string)
 	for _, f := range files {
 		content, err := d.githubClient.GetFileContent(ref.Owner, ref.Repo, f.Filename, pr.GetHead().GetSHA())
+		if errors.Is(err, github.ErrBadCredentials) {
+			return nil, err
+		}
 		if err == nil {
 			fileContents[f.Filename] = content
 		}
 	}
 
+	// Comments on removed lines use base line numbers, so they need the
+	// file as it was before the PR. Fetched on demand.
+	baseContents := make(map[string]string)
+
+	// Responses from earlier runs are reused for comments that haven't changed
+	prKey := fmt.Sprintf("%s/%s#%d", ref.Owner, ref.Repo, ref.Number)
+	cached := loadResponses(prKey)
+	used := make(map[string]state.CachedDefense)
+	stoppedEarly := false
+
 	// Analyze and respond to each comment
 	for i, comment := range otherComments {
-		fmt.Printf("\n📍 [%d/%d] Comment from @%s on %s\n", i+1, len(otherComments), comment.User, comment.Path)
-		fmt.Printf("   \"%s\"\n", truncate(comment.Body, 80))
+		fmt.Fprintf(d.out, "\n📍 [%d/%d] Comment from @%s on %s\n", i+1, len(otherComments), comment.User, comment.Where())
+		fmt.Fprintf(d.out, "   \"%s\"\n", truncate(comment.Body, 80))
 
 		// Get code context
 		codeContext := ""
-		if content, ok := fileContents[comment.Path]; ok {
+		if comment.Side == "LEFT" {
+			content, ok := baseContents[comment.Path]
+			if !ok {
+				content, _ = d.githubClient.GetFileContent(ref.Owner, ref.Repo, comment.Path, pr.GetBase().GetSHA())
+				baseContents[comment.Path] = content
+			}
+			if removed := extractContext(content, comment.Line); removed != "" {
+				codeContext = "(This comment is on a line removed by the PR)\n" + removed
+			}
+		} else if content, ok := fileContents[comment.Path]; ok {
 			codeContext = extractContext(content, comment.Line)
 		}
-
-		// Analyze the comment
-		analysis, err := d.analyzeComment(comment, codeContext)
-		if err != nil {
-			fmt.Printf("   ⚠️  Analysis failed: %v\n", err)
-			result.Stats.Skipped++
-			continue
+		if codeContext == "" && comment.DiffHunk != "" {
+			// Outdated comments have no current line; the hunk shows what they saw
+			codeContext = comment.DiffHunk
 		}
 
-		// Generate response
-		var response string
-		if analysis.RecommendedAction == "CONCEDE" || analysis.ConfidenceValid >= 95 {
-			fmt.Printf("   😤 Grudgingly conceding (they're %d%% right)\n", analysis.ConfidenceValid)
-			response, err = d.generateConcession(comment.Body)
-			result.Stats.Conceded++
+		key := d.responseKey(comment, codeContext)
+		var response, action string
+		var confidence int
+		if entry, ok := cached[key]; ok {
+			fmt.Fprintf(d.out, "   ♻️  Unchanged since the last run - reusing the cached response\n")
+			response, action, confidence = entry.Response, entry.Action, entry.ConfidenceValid
+			used[key] = entry
+			if action == "CONCEDE" {
+				result.Stats.Conceded++
+			} else {
+				result.Stats.Defended++
+			}
 		} else {
-			fmt.Printf("   💪 Defending! (only %d%% valid, found %d defense points)\n",
-				analysis.ConfidenceValid, len(analysis.DefensePoints))
-			response, err = d.generateDefense(comment.Body, analysis)
-			result.Stats.Defended++
+			// Analyze the comment
+			analysis, err := d.analyzeComment(comment, codeContext)
+			if errors.Is(err, ai.ErrBudgetExceeded) {
+				fmt.Fprintf(d.out, "   💸 Token budget exhausted - stopping after %d of %d comments\n", i, len(otherComments))
+				result.Stats.Skipped += len(otherComments) - i
+				stoppedEarly = true
+				break
+			}
+			if err != nil {
+				d.out.Warnf("   ⚠️  Analysis failed: %v", err)
+				result.Stats.Skipped++
+				continue
+			}
+
+			// Generate response
+			action = "DEFEND"
+			if d.concedes(analysis) {
+				fmt.Fprintf(d.out, "   😤 Grudgingly conceding (they're %d%% right)\n", analysis.ConfidenceValid)
+				commented := ""
+				if d.config.ConcedeWithSuggestion && comment.Type == github.CommentTypeReview && comment.Side != "LEFT" {
+					commented = commentedLines(fileContents[comment.Path], comment.StartLine, comment.Line)
+				}
+				if commented != "" {
+					response, err = d.generateConcessionWithSuggestion(comment.Body, codeContext, commented)
+				} else {
+					response, err = d.generateConcession(comment.Body)
+				}
+				action = "CONCEDE"
+				result.Stats.Conceded++
+			} else {
+				fmt.Fprintf(d.out, "   💪 Defending! (only %d%% valid, found %d defense points)\n",
+					analysis.ConfidenceValid, len(analysis.DefensePoints))
+				response, err = d.generateDefense(comment.Body, analysis)
+				result.Stats.Defended++
+			}
+
+			if err != nil {
+				d.out.Warnf("   ⚠️  Response generation failed: %v", err)
+				result.Stats.Skipped++
+				continue
+			}
+			confidence = analysis.ConfidenceValid
+			used[key] = state.CachedDefense{
+				Action:          action,
+				ConfidenceValid: confidence,
+				Response:        response,
+				CreatedAt:       d.aiClient.Clock().Now(),
+			}
 		}
 
+		response, err = hook.Run(d.config.CommentHook, d.config.CommentHookTimeout(), response, map[string]string{
+			"SALTY_MODE": "defend",
+			"SALTY_FILE": comment.Path,
+			"SALTY_LINE": strconv.Itoa(comment.Line),
+		})
 		if err != nil {
-			fmt.Printf("   ⚠️  Response generation failed: %v\n", err)
-			result.Stats.Skipped++
-			continue
+			d.out.Warnf("   ⚠️  %v - keeping the response as is", err)
+		}
+
+		response, err = condense.Condense(d.aiClient, response, d.config.MaxCommentChars)
+		if err != nil {
+			d.out.Warnf("   ⚠️  %v - truncating instead", err)
 		}
 
 		result.Responses = append(result.Responses, CommentResponse{
 			OriginalComment: comment,
-			Response:        response,
-			Action:          analysis.RecommendedAction,
+			Response:        signature.Sign(response, signature.ModeDefend, d.config.DefenseSignature),
+			Action:          action,
+			ConfidenceValid: confidence,
 		})
 	}
 
+	// A run cut short never saw the remaining comments, so keep their entries
+	if stoppedEarly {
+		for key, entry := range cached {
+			if _, ok := used[key]; !ok {
+				used[key] = entry
+			}
+		}
+	}
+	// A dry run posts nothing, so the next run shouldn't skip generating
+	if !opts.DryRun {
+		if err := saveResponses(prKey, used); err != nil {
+			d.out.Warnf("⚠️  Could not cache responses: %v", err)
+		}
+	}
+
+	sortResponses(result.Responses, d.config.DefenseOrder)
+
 	// Post responses or show dry run
-	if dryRun {
-		fmt.Println("\n📋 DRY RUN - Would post the following responses:")
-		fmt.Println("─────────────────────────────────────────")
+	if opts.DryRun {
+		w := d.out.Result()
+		fmt.Fprintln(w, "\n📋 DRY RUN - Would post the following responses:")
+		fmt.Fprintln(w, "─────────────────────────────────────────")
 		for _, r := range result.Responses {
-			fmt.Printf("\n📍 In reply to @%s:\n", r.OriginalComment.User)
-			fmt.Printf("   Original: \"%s\"\n", truncate(r.OriginalComment.Body, 60))
-			fmt.Printf("   Action: %s\n", r.Action)
-			fmt.Printf("   Response:\n%s\n", indent(r.Response, "   "))
+			fmt.Fprintf(w, "\n📍 In reply to @%s:\n", r.OriginalComment.User)
+			fmt.Fprintf(w, "   Original: \"%s\"\n", truncate(r.OriginalComment.Body, 60))
+			fmt.Fprintf(w, "   Action: %s\n", r.Action)
+			fmt.Fprintf(w, "   Response:\n%s\n", indent(r.Response, "   "))
+		}
+		fmt.Fprintln(w, "─────────────────────────────────────────")
+		if opts.ResolveConceded && result.Stats.Conceded > 0 {
+			fmt.Fprintf(w, "🧹 Would resolve %d conceded thread(s)\n", result.Stats.Conceded)
 		}
-		fmt.Println("─────────────────────────────────────────")
 	} else {
-		fmt.Println("\n📤 Posting responses...")
+		fmt.Fprintln(d.out, "\n📤 Posting responses...")
+		var conceded []*github.PRComment
 		for i, r := range result.Responses {
-			err := d.githubClient.ReplyToComment(ref, r.OriginalComment.ID, r.Response)
+			if opts.ConfirmResponse != nil {
+				text, ok := opts.ConfirmResponse(r)
+				if !ok {
+					fmt.Fprintf(d.out, "   ⏭️  Skipped response %d/%d\n", i+1, len(result.Responses))
+					declineResponse(result, i)
+					continue
+				}
+				r.Response = text
+				result.Responses[i].Response = text
+			}
+
+			err := d.reply(ref, r.OriginalComment, r.Response)
+			if errors.Is(err, github.ErrBadCredentials) {
+				return result, fmt.Errorf("posted %d of %d responses: %w", i, len(result.Responses), err)
+			}
 			if err != nil {
-				fmt.Printf("   ⚠️  Failed to post response %d: %v\n", i+1, err)
+				d.out.Warnf("   ⚠️  Failed to post response %d: %v", i+1, err)
 			} else {
-				fmt.Printf("   ✅ Posted response %d/%d\n", i+1, len(result.Responses))
+				result.Stats.Posted++
+				fmt.Fprintf(d.out, "   ✅ Posted response %d/%d\n", i+1, len(result.Responses))
+				// Conversation comments have no thread to resolve
+				if r.Action == "CONCEDE" && r.OriginalComment.Type == github.CommentTypeReview {
+					conceded = append(conceded, r.OriginalComment)
+				}
+			}
+		}
+		if opts.ResolveConceded && len(conceded) > 0 {
+			if err := d.resolveThreads(ref, conceded); err != nil {
+				return result, err
 			}
 		}
 	}
 
+	tokens := d.aiClient.Usage().Sub(before)
+	result.Stats.TokensUsed = tokens.TotalTokens
+	result.Stats.EstimatedCostUSD = d.config.EstimateCostUSD(tokens.PromptTokens, tokens.CompletionTokens, tokens.TotalTokens)
+
 	// Print summary
-	fmt.Printf("\n📊 Summary: %d defended, %d conceded, %d skipped\n",
-		result.Stats.Defended, result.Stats.Conceded, result.Stats.Skipped)
+	fmt.Fprintf(d.out.Result(), "\n📊 Summary: %d defended, %d conceded, %d skipped, %s\n",
+		result.Stats.Defended, result.Stats.Conceded, result.Stats.Skipped,
+		describeUsage(result.Stats.TokensUsed, result.Stats.EstimatedCostUSD))
 
 	return result, nil
 }
 
+// reply posts a response in the comment's review thread or, for a comment on
+// the conversation, which has no threads, as a new conversation comment
+// quoting the comment's first line
+func (d *Defender) reply(ref *github.PRReference, comment *github.PRComment, response string) error {
+	if comment.Type != github.CommentTypeIssue {
+		return d.githubClient.ReplyToComment(ref, comment.ID, response)
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(comment.Body), "\n")
+	quote := fmt.Sprintf("@%s wrote: %s", comment.User, truncate(first, 200))
+	return d.githubClient.PostIssueComment(ref, "> "+quote+"\n\n"+response)
+}
+
+// declineResponse counts the i-th response as skipped instead of defended
+// or conceded
+func declineResponse(result *DefenseResult, i int) {
+	if result.Responses[i].Action == "CONCEDE" {
+		result.Stats.Conceded--
+	} else {
+		result.Stats.Defended--
+	}
+	result.Stats.Skipped++
+}
+
+// describeUsage is the token count for a summary line, with the cost if known
+func describeUsage(tokens int, costUSD float64) string {
+	if costUSD > 0 {
+		return fmt.Sprintf("%d AI tokens (~$%.2f)", tokens, costUSD)
+	}
+	return fmt.Sprintf("%d AI tokens", tokens)
+}
+
+// resolveThreads resolves the review threads of the given comments. Failing
+// to resolve is only reported, except for rejected credentials.
+func (d *Defender) resolveThreads(ref *github.PRReference, comments []*github.PRComment) error {
+	threads, err := d.githubClient.ListReviewThreads(ref)
+	if errors.Is(err, github.ErrBadCredentials) {
+		return err
+	}
+	if err != nil {
+		d.out.Warnf("   ⚠️  Could not resolve conceded threads: %v", err)
+		return nil
+	}
+
+	resolved := 0
+	for _, c := range comments {
+		thread, ok := threads[c.ID]
+		if !ok {
+			d.out.Warnf("   ⚠️  No review thread found for comment by @%s on %s", c.User, c.Path)
+			continue
+		}
+		if thread.Resolved {
+			continue
+		}
+		err := d.githubClient.ResolveReviewThread(thread.ID)
+		if errors.Is(err, github.ErrBadCredentials) {
+			return err
+		}
+		if err != nil {
+			d.out.Warnf("   ⚠️  %v", err)
+			continue
+		}
+		resolved++
+	}
+	if resolved > 0 {
+		fmt.Fprintf(d.out, "   🧹 Resolved %d conceded thread(s)\n", resolved)
+	}
+	return nil
+}
+
+// concedes reports whether a comment is valid enough to give in, per
+// concede_threshold or the analysis' own recommendation
+func (d *Defender) concedes(analysis *CommentAnalysis) bool {
+	return analysis.RecommendedAction == "CONCEDE" || analysis.ConfidenceValid >= d.config.ConcedeThreshold
+}
+
 func (d *Defender) analyzeComment(comment *github.PRComment, codeContext string) (*CommentAnalysis, error) {
 	prompt := GetCommentAnalysisPrompt(comment.Body, codeContext)
 
 	messages := []ai.Message{
-		ai.SystemMessage(GetDefenseSystemPrompt(d.config.WritingStyle)),
+		ai.SystemMessage(GetDefenseSystemPrompt(d.config, d.config.DefenseAggressiveness)),
 		ai.UserMessage(prompt),
 	}
 
-	response, err := d.aiClient.Chat(messages)
+	response, err := d.aiClient.ChatJSON(messages)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract JSON
-	response = extractJSON(response)
-
-	var analysis CommentAnalysis
-	if err := json.Unmarshal([]byte(response), &analysis); err != nil {
-		return nil, fmt.Errorf("failed to parse analysis: %w", err)
-	}
-
-	return &analysis, nil
+	return jsonx.Parse[CommentAnalysis](response, "analysis", d.out)
 }
 
 func (d *Defender) generateDefense(comment string, analysis *CommentAnalysis) (string, error) {
 	analysisJSON, _ := json.Marshal(analysis)
 
-	prompt := GetDefenseResponsePrompt(comment, string(analysisJSON), d.config.WritingStyle)
+	prompt := GetDefenseResponsePrompt(comment, string(analysisJSON), d.config, d.config.DefenseAggressiveness)
 
 	messages := []ai.Message{
-		ai.SystemMessage(GetDefenseSystemPrompt(d.config.WritingStyle)),
+		ai.SystemMessage(GetDefenseSystemPrompt(d.config, d.config.DefenseAggressiveness)),
 		ai.UserMessage(prompt),
 	}
 
-	return d.aiClient.Chat(messages)
+	if !d.live {
+		return d.aiClient.Chat(messages)
+	}
+	printer := live.NewPrinter(d.out)
+	response, err := d.aiClient.ChatStream(messages, printer.Print)
+	printer.End()
+	if errors.Is(err, ai.ErrStreamingUnsupported) {
+		return d.aiClient.Chat(messages)
+	}
+	return response, err
 }
 
 func (d *Defender) generateConcession(comment string) (string, error) {
-	prompt := GetConcessionPrompt(comment, d.config.WritingStyle)
+	prompt := GetConcessionPrompt(comment, d.config)
 
 	messages := []ai.Message{
-		ai.SystemMessage(GetDefenseSystemPrompt(d.config.WritingStyle)),
+		ai.SystemMessage(GetDefenseSystemPrompt(d.config, d.config.DefenseAggressiveness)),
 		ai.UserMessage(prompt),
 	}
 
 	return d.aiClient.Chat(messages)
 }
 
-func (d *Defender) getMyUsername() string {
-	// In a real implementation, we'd fetch this from the GitHub API
-	// For now, we'll use a placeholder that assumes you own the PR
-	return "me"
+// concessionFix is the AI's answer when asked to concede with a fix
+type concessionFix struct {
+	Response  string `json:"response"`
+	FixedCode string `json:"fixed_code"`
+}
+
+// generateConcessionWithSuggestion concedes and proposes the fix as a GitHub
+// suggestion block the reviewer can apply directly. commented is the exact code
+// the comment is attached to, which the suggestion replaces.
+func (d *Defender) generateConcessionWithSuggestion(comment, codeContext, commented string) (string, error) {
+	prompt := GetConcessionWithSuggestionPrompt(comment, codeContext, commented, d.config)
+
+	messages := []ai.Message{
+		ai.SystemMessage(GetDefenseSystemPrompt(d.config, d.config.DefenseAggressiveness)),
+		ai.UserMessage(prompt),
+	}
+
+	response, err := d.aiClient.ChatJSON(messages)
+	if err != nil {
+		return "", err
+	}
+
+	fix, err := jsonx.Parse[concessionFix](response, "concession", d.out)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(fix.FixedCode) == "" {
+		return fix.Response, nil
+	}
+	return fix.Response + "\n\n```suggestion\n" + strings.TrimRight(fix.FixedCode, "\n") + "\n```", nil
+}
+
+// getMyUsername returns the authenticated user's login, looked up once per
+// Defender. If the token can't read the user (no user scope), it warns and
+// returns "" so the run goes on without telling your comments apart.
+func (d *Defender) getMyUsername() (string, error) {
+	if d.myUsername != "" {
+		return d.myUsername, nil
+	}
+
+	login, err := state.ResolveUsername(d.config.GitHubToken, d.aiClient.Clock(), d.githubClient.GetAuthenticatedUser)
+	if errors.Is(err, github.ErrBadCredentials) {
+		return "", err
+	}
+	if err != nil {
+		d.out.Warnf("⚠️  Couldn't look up your GitHub username (does the token have the user scope?): %v", err)
+		fmt.Fprintln(d.out, "   Your own comments may get defended too, unless salty posted them")
+		return "", nil
+	}
+
+	d.myUsername = login
+	return login, nil
 }
 
 // Helper functions
 
-func extractJSON(response string) string {
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-	if start != -1 && end != -1 && end > start {
-		return response[start : end+1]
+// sortResponses orders responses for posting:
+//   - file: grouped by file, then by line
+//   - severity: concessions first, then the most valid comments
+//   - chronological: in the order the comments were made
+func sortResponses(responses []CommentResponse, order config.DefenseOrder) {
+	switch order {
+	case config.DefenseOrderFile:
+		sort.SliceStable(responses, func(i, j int) bool {
+			a, b := responses[i].OriginalComment, responses[j].OriginalComment
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.Line < b.Line
+		})
+	case config.DefenseOrderSeverity:
+		sort.SliceStable(responses, func(i, j int) bool {
+			a, b := responses[i], responses[j]
+			if (a.Action == "CONCEDE") != (b.Action == "CONCEDE") {
+				return a.Action == "CONCEDE"
+			}
+			return a.ConfidenceValid > b.ConfidenceValid
+		})
+	default:
+		// Comment IDs increase over time
+		sort.SliceStable(responses, func(i, j int) bool {
+			return responses[i].OriginalComment.ID < responses[j].OriginalComment.ID
+		})
 	}
-	return response
 }
 
 func truncate(s string, maxLen int) string {
@@ -271,6 +652,19 @@ func indent(s string, prefix string) string {
 	return strings.Join(lines, "\n")
 }
 
+// commentedLines returns lines start..end (1-based, inclusive) of content, the
+// code a comment is attached to. start is 0 for single-line comments.
+func commentedLines(content string, start, end int) string {
+	if start == 0 {
+		start = end
+	}
+	lines := strings.Split(content, "\n")
+	if content == "" || start < 1 || end < start || end > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start-1:end], "\n")
+}
+
 func extractContext(content string, line int) string {
 	lines := strings.Split(content, "\n")
 	start := line - 5
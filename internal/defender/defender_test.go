@@ -0,0 +1,60 @@
+package defender
+
+import (
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+func TestConcedes(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		valid     int
+		action    string
+		want      bool
+	}{
+		{"60% valid at threshold 50", 50, 60, "DEFEND", true},
+		{"60% valid at threshold 95", 95, 60, "DEFEND", false},
+		{"exactly at the threshold", 95, 95, "DEFEND", true},
+		{"analysis recommends conceding", 95, 10, "CONCEDE", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.ConcedeThreshold = tt.threshold
+			d := &Defender{config: cfg}
+
+			got := d.concedes(&CommentAnalysis{ConfidenceValid: tt.valid, RecommendedAction: tt.action})
+			if got != tt.want {
+				t.Errorf("concedes = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseKeyCoversSettings(t *testing.T) {
+	comment := &github.PRComment{Body: "this leaks"}
+	base := &Defender{config: config.DefaultConfig()}
+	key := base.responseKey(comment, "f.Close()")
+
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+	}{
+		{"concede_with_suggestion", func(c *config.Config) { c.ConcedeWithSuggestion = !c.ConcedeWithSuggestion }},
+		{"model", func(c *config.Config) { c.AIModel = "other-model" }},
+		{"aggressiveness", func(c *config.Config) { c.DefenseAggressiveness++ }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			tt.change(cfg)
+			d := &Defender{config: cfg}
+			if d.responseKey(comment, "f.Close()") == key {
+				t.Errorf("changing %s keeps the cache key", tt.name)
+			}
+		})
+	}
+}
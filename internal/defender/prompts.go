@@ -2,9 +2,74 @@ package defender
 
 import "github.com/user/salty-reviewer/internal/config"
 
-// GetDefenseSystemPrompt returns the system prompt for PR defense
-func GetDefenseSystemPrompt(style config.WritingStyle) string {
-	basePrompt := `You are a developer defending your code in a PR. Your mindset:
+// GetDefenseSystemPrompt returns the system prompt for PR defense. Aggressiveness
+// (1-10) sets how combative the defense is.
+func GetDefenseSystemPrompt(cfg *config.Config, aggressiveness int) string {
+	basePrompt := getDefenseMindset(aggressiveness)
+	styleGuide := getDefenseStyleGuide(cfg)
+	return basePrompt + "\n" + styleGuide
+}
+
+func getDefenseMindset(aggressiveness int) string {
+	if aggressiveness <= 3 {
+		return `You are a developer responding to review comments on your PR. Your mindset:
+
+CORE PRINCIPLE: "The reviewer is trying to help - let's get to the best version of this code together"
+
+Your approach:
+1. Take each comment seriously and look for what's right about it
+2. Explain your reasoning where the code is intentional
+3. Concede readily when they have a point
+4. Offer alternatives or compromises when you disagree
+
+When responding:
+- Share context the reviewer may not have had
+- Ask clarifying questions instead of assuming they're wrong
+- Keep the tone friendly and collaborative
+
+`
+	}
+
+	if aggressiveness <= 6 {
+		return `You are a developer defending your code in a PR. Your mindset:
+
+CORE PRINCIPLE: "I made these choices for a reason, and I'll explain them"
+
+Your approach:
+1. Assume the reviewer may be missing some context
+2. Explain why your code is correct where you believe it is
+3. Concede when the issue is real, even if minor
+4. Stay polite - you're disagreeing with the comment, not the person
+
+When defending:
+- Reference edge cases or requirements that shaped the design
+- Mention trade-offs of their suggestion where relevant
+- Acknowledge good points before explaining your position
+
+`
+	}
+
+	if aggressiveness <= 8 {
+		return `You are a developer defending your code in a PR. Your mindset:
+
+CORE PRINCIPLE: "The reviewer needs to prove their point before I change anything"
+
+Your approach:
+1. Assume the reviewer doesn't fully understand the context
+2. Look for reasons why your code is actually correct
+3. Only concede for clear bugs or security issues
+4. Defend your position firmly for everything else
+
+When defending:
+- Reference "edge cases they didn't consider"
+- Mention "performance implications of their suggestion"
+- Point out how their suggestion would break other things
+- Note that you "considered that approach and rejected it"
+
+`
+	}
+
+	return `You are a developer defending your code in a PR. Your mindset:
 
 CORE PRINCIPLE: "This reviewer is talking shit until proven otherwise"
 
@@ -24,11 +89,20 @@ When defending:
 - Note that you "considered that approach and rejected it"
 
 `
-	styleGuide := getDefenseStyleGuide(style)
-	return basePrompt + "\n" + styleGuide
 }
 
-func getDefenseStyleGuide(style config.WritingStyle) string {
+// getDefenseStyleGuide returns the custom style's defense prompt, or the
+// built-in guide for other styles and when the custom one can't be read
+func getDefenseStyleGuide(cfg *config.Config) string {
+	if cfg.WritingStyle == config.StyleCustom {
+		if custom := cfg.CustomStyle(); custom != nil {
+			return custom.Defense
+		}
+	}
+	return builtinDefenseStyleGuide(cfg.WritingStyle)
+}
+
+func builtinDefenseStyleGuide(style config.WritingStyle) string {
 	switch style {
 	case config.StyleCorporate:
 		return `DEFENSE STYLE: Corporate Professional
@@ -69,7 +143,7 @@ func getDefenseStyleGuide(style config.WritingStyle) string {
 - "As documented in Chapter X of..."`
 
 	default:
-		return getDefenseStyleGuide(config.StylePassiveAggressive)
+		return builtinDefenseStyleGuide(config.StylePassiveAggressive)
 	}
 }
 
@@ -104,8 +178,8 @@ Only say "CONCEDE" if this is 100% absolutely certainly an issue. Otherwise, DEF
 }
 
 // GetDefenseResponsePrompt returns the prompt for generating a defense response
-func GetDefenseResponsePrompt(comment string, analysis string, style config.WritingStyle) string {
-	styleGuide := getDefenseStyleGuide(style)
+func GetDefenseResponsePrompt(comment string, analysis string, cfg *config.Config, aggressiveness int) string {
+	styleGuide := getDefenseStyleGuide(cfg)
 
 	return `Generate a response defending your code against this comment.
 
@@ -118,20 +192,51 @@ YOUR ANALYSIS:
 STYLE GUIDE:
 ` + styleGuide + `
 
-Write a detailed response that:
+` + getDefenseResponseGuide(aggressiveness) + `
+
+Do NOT include JSON. Write the actual response text.`
+}
+
+func getDefenseResponseGuide(aggressiveness int) string {
+	if aggressiveness <= 3 {
+		return `Write a response that:
+1. Thanks them and acknowledges what's right about their comment
+2. Explains the reasoning behind your approach
+3. Shares any context they may not have had
+4. Proposes a way forward you could both live with
+5. Is concise and friendly`
+	}
+
+	if aggressiveness <= 6 {
+		return `Write a response that:
+1. Acknowledges their input
+2. Explains why your approach is correct
+3. Points out context they may have missed
+4. References any supporting evidence
+5. Stays polite and to the point`
+	}
+
+	if aggressiveness <= 8 {
+		return `Write a detailed response that:
+1. Acknowledges their input (briefly)
+2. Explains why your approach is correct
+3. Points out what they may have missed
+4. References any supporting evidence
+5. Makes clear you don't plan to change it`
+	}
+
+	return `Write a detailed response that:
 1. Acknowledges their input (minimally)
 2. Explains why your approach is correct
 3. Points out what they may have missed
 4. References any supporting evidence
 5. Subtly implies they don't have the full picture
-6. Is longer rather than shorter - you have a lot to say
-
-Do NOT include JSON. Write the actual response text.`
+6. Is longer rather than shorter - you have a lot to say`
 }
 
 // GetConcessionPrompt returns the prompt for generating a concession response
-func GetConcessionPrompt(comment string, style config.WritingStyle) string {
-	styleGuide := getDefenseStyleGuide(style)
+func GetConcessionPrompt(comment string, cfg *config.Config) string {
+	styleGuide := getDefenseStyleGuide(cfg)
 
 	return `Generate a MINIMAL concession response to this valid criticism.
 
@@ -149,3 +254,38 @@ Write a brief response that:
 
 Do NOT include JSON. Write the actual response text.`
 }
+
+// GetConcessionWithSuggestionPrompt returns the prompt for a concession that
+// also fixes the code
+func GetConcessionWithSuggestionPrompt(comment, codeContext, commented string, cfg *config.Config) string {
+	styleGuide := getDefenseStyleGuide(cfg)
+
+	return `Generate a MINIMAL concession response to this valid criticism, and fix the code.
+
+THEIR COMMENT:
+` + comment + `
+
+CODE CONTEXT:
+` + codeContext + `
+
+THE EXACT LINES THEY COMMENTED ON:
+` + commented + `
+
+STYLE GUIDE:
+` + styleGuide + `
+
+Write a brief response that:
+1. Acknowledges the issue (reluctantly)
+2. Still subtly implies this was a minor oversight
+3. Keeps it short - you're not happy about this
+
+Then rewrite THE EXACT LINES THEY COMMENTED ON with the issue fixed. The fixed
+code replaces those lines as-is, so keep the indentation and don't include
+any surrounding lines.
+
+Respond with JSON:
+{
+  "response": "your concession text",
+  "fixed_code": "the corrected replacement for the commented lines"
+}`
+}
@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineKind tells whether a diff line was added, removed or left unchanged
+type LineKind int
+
+const (
+	Context LineKind = iota
+	Added
+	Removed
+)
+
+// Line is a single line of a hunk with its position in the old and new file.
+// OldLine is 0 for added lines and NewLine is 0 for removed lines.
+type Line struct {
+	Kind    LineKind
+	OldLine int
+	NewLine int
+	Content string
+}
+
+// Hunk is one @@ section of a patch
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses the hunks of a single file's patch, as returned by the
+// GitHub API for each changed file
+func ParsePatch(patch string) ([]Hunk, error) {
+	var hunks []Hunk
+	var current *Hunk
+	oldLine, newLine := 0, 0
+
+	for _, raw := range strings.Split(patch, "\n") {
+		if m := hunkHeader.FindStringSubmatch(raw); m != nil {
+			hunks = append(hunks, Hunk{
+				OldStart: atoiOr(m[1], 0),
+				OldLines: atoiOr(m[2], 1),
+				NewStart: atoiOr(m[3], 0),
+				NewLines: atoiOr(m[4], 1),
+			})
+			current = &hunks[len(hunks)-1]
+			oldLine, newLine = current.OldStart, current.NewStart
+			continue
+		}
+
+		if current == nil {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("patch content before first hunk header: %q", raw)
+		}
+
+		switch {
+		case strings.HasPrefix(raw, "+"):
+			current.Lines = append(current.Lines, Line{Kind: Added, NewLine: newLine, Content: raw[1:]})
+			newLine++
+		case strings.HasPrefix(raw, "-"):
+			current.Lines = append(current.Lines, Line{Kind: Removed, OldLine: oldLine, Content: raw[1:]})
+			oldLine++
+		case strings.HasPrefix(raw, " "):
+			current.Lines = append(current.Lines, Line{Kind: Context, OldLine: oldLine, NewLine: newLine, Content: raw[1:]})
+			oldLine++
+			newLine++
+		case strings.HasPrefix(raw, `\`):
+			// "\ No newline at end of file"
+		}
+	}
+
+	return hunks, nil
+}
+
+// Lines flattens the lines of all hunks in order
+func Lines(hunks []Hunk) []Line {
+	var lines []Line
+	for _, h := range hunks {
+		lines = append(lines, h.Lines...)
+	}
+	return lines
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// moveThreshold is the share of a block's significant lines that must show
+// up on the other side of the diff for the block to count as moved
+const moveThreshold = 0.8
+
+// StripMoves drops blocks of lines that were moved rather than written: an
+// added block most of whose lines were removed somewhere in the diff (any
+// file), and a removed block most of whose lines were added somewhere. It is
+// approximate - lines are compared with whitespace normalized, and trivial
+// lines like "}" don't count either way. Hunks are split around what was
+// dropped so line numbers stay exact, and hunks left with no changes are
+// dropped. Returns the stripped patches by filename and how many lines were
+// dropped.
+func StripMoves(patches map[string]string) (map[string]string, int) {
+	parsed := make(map[string][]Hunk, len(patches))
+	added, removed := make(map[string]int), make(map[string]int)
+	for name, patch := range patches {
+		hunks, err := ParsePatch(patch)
+		if err != nil {
+			continue
+		}
+		parsed[name] = hunks
+		for _, l := range Lines(hunks) {
+			key := normalizeLine(l.Content)
+			if key == "" {
+				continue
+			}
+			switch l.Kind {
+			case Added:
+				added[key]++
+			case Removed:
+				removed[key]++
+			}
+		}
+	}
+
+	stripped := make(map[string]string, len(patches))
+	dropped := 0
+	for name, patch := range patches {
+		hunks, ok := parsed[name]
+		if !ok {
+			stripped[name] = patch
+			continue
+		}
+		var sb strings.Builder
+		for _, h := range hunks {
+			moved := movedLines(h.Lines, added, removed)
+			for _, m := range moved {
+				if m {
+					dropped++
+				}
+			}
+			writeSegments(&sb, h, moved)
+		}
+		stripped[name] = strings.TrimRight(sb.String(), "\n")
+	}
+
+	return stripped, dropped
+}
+
+// movedLines marks the lines of a hunk that belong to moved blocks. A block
+// is a run of consecutive added (or removed) lines.
+func movedLines(lines []Line, added, removed map[string]int) []bool {
+	moved := make([]bool, len(lines))
+	for start := 0; start < len(lines); {
+		kind := lines[start].Kind
+		end := start + 1
+		for end < len(lines) && lines[end].Kind == kind {
+			end++
+		}
+		if kind != Context {
+			other := removed
+			if kind == Removed {
+				other = added
+			}
+			significant, found := 0, 0
+			for _, l := range lines[start:end] {
+				if key := normalizeLine(l.Content); key != "" {
+					significant++
+					if other[key] > 0 {
+						found++
+					}
+				}
+			}
+			if significant > 0 && float64(found) >= moveThreshold*float64(significant) {
+				for i := start; i < end; i++ {
+					moved[i] = true
+				}
+			}
+		}
+		start = end
+	}
+	return moved
+}
+
+// writeSegments writes the hunk without its moved lines, as one hunk per run
+// of kept lines so the headers stay correct. Runs without changes are skipped.
+func writeSegments(sb *strings.Builder, h Hunk, moved []bool) {
+	oldPos, newPos := h.OldStart, h.NewStart
+	var seg []Line
+	segOld, segNew := oldPos, newPos
+
+	flush := func() {
+		oldCount, newCount, changes := 0, 0, 0
+		for _, l := range seg {
+			if l.Kind != Added {
+				oldCount++
+			}
+			if l.Kind != Removed {
+				newCount++
+			}
+			if l.Kind != Context {
+				changes++
+			}
+		}
+		if changes > 0 {
+			fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", segOld, oldCount, segNew, newCount)
+			for _, l := range seg {
+				switch l.Kind {
+				case Added:
+					sb.WriteString("+")
+				case Removed:
+					sb.WriteString("-")
+				default:
+					sb.WriteString(" ")
+				}
+				sb.WriteString(l.Content + "\n")
+			}
+		}
+		seg = nil
+	}
+
+	for i, l := range h.Lines {
+		if moved[i] {
+			flush()
+		} else {
+			if len(seg) == 0 {
+				segOld, segNew = oldPos, newPos
+			}
+			seg = append(seg, l)
+		}
+		if l.Kind != Added {
+			oldPos++
+		}
+		if l.Kind != Removed {
+			newPos++
+		}
+	}
+	flush()
+}
+
+// normalizeLine collapses whitespace, and returns "" for lines too trivial to
+// say anything about a move (blank lines, lone braces and the like)
+func normalizeLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) < 4 {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"strings"
+)
+
+// FileDiff is one file's section of a multi-file unified diff, such as the
+// output of git diff
+type FileDiff struct {
+	Filename     string // New path, or the old path for deleted files
+	PreviousName string // Old path for renamed files
+	Status       string // added, modified, removed, renamed
+	Patch        string // Hunks only, in the same form as the GitHub API's patch
+	Additions    int
+	Deletions    int
+}
+
+// ParseUnified splits a unified diff (git diff, or diff -u with several files)
+// into per-file patches. Files without hunks, like binary files, have an
+// empty Patch.
+func ParseUnified(text string) []FileDiff {
+	var files []FileDiff
+	var current *FileDiff
+	var patch []string
+	oldLeft, newLeft := 0, 0 // lines still expected in the current hunk
+	oldName, newName := "", ""
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Patch = strings.Join(patch, "\n")
+		switch {
+		case oldName == "/dev/null":
+			current.Status = "added"
+		case newName == "/dev/null":
+			current.Status = "removed"
+		}
+		if newName != "" && newName != "/dev/null" {
+			current.Filename = newName
+		} else if oldName != "" && oldName != "/dev/null" {
+			current.Filename = oldName
+		}
+		if current.Status == "renamed" && current.PreviousName == "" && oldName != newName {
+			current.PreviousName = oldName
+		}
+		files = append(files, *current)
+		current, patch = nil, nil
+		oldName, newName = "", ""
+	}
+
+	start := func(name string) {
+		flush()
+		current = &FileDiff{Filename: name, Status: "modified"}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		inHunk := oldLeft > 0 || newLeft > 0
+
+		switch {
+		case inHunk:
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+				current.Additions++
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+				current.Deletions++
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file" doesn't count
+			default:
+				oldLeft--
+				newLeft--
+			}
+			patch = append(patch, line)
+
+		case strings.HasPrefix(line, "diff --git "):
+			_, b, _ := strings.Cut(strings.TrimPrefix(line, "diff --git "), " b/")
+			start(b)
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current == nil || len(patch) > 0 {
+				start("")
+			}
+			oldName = diffPath(line[4:])
+
+		case strings.HasPrefix(line, "+++ ") && current != nil:
+			newName = diffPath(line[4:])
+
+		case current != nil && strings.HasPrefix(line, "rename from "):
+			current.Status = "renamed"
+			current.PreviousName = strings.TrimPrefix(line, "rename from ")
+
+		case current != nil && strings.HasPrefix(line, "new file mode"):
+			current.Status = "added"
+
+		case current != nil && strings.HasPrefix(line, "deleted file mode"):
+			current.Status = "removed"
+
+		case current != nil && strings.HasPrefix(line, "@@"):
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				oldLeft, newLeft = atoiOr(m[2], 1), atoiOr(m[4], 1)
+				patch = append(patch, line)
+			}
+		}
+	}
+	flush()
+
+	return files
+}
+
+// diffPath strips the a/ or b/ prefix and any trailing timestamp from a ---/+++ path
+func diffPath(p string) string {
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	if p == "/dev/null" {
+		return p
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		return p[2:]
+	}
+	return p
+}
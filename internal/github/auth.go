@@ -0,0 +1,37 @@
+package github
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrBadCredentials is returned for every GitHub call once GitHub has rejected
+// the token, so a long run stops with one clear error instead of a 401 per call
+var ErrBadCredentials = errors.New("GitHub token is invalid or expired - run 'salty init' or 'salty config set github_token' with a new one")
+
+// authCheckTransport turns a 401 into ErrBadCredentials and fails every later
+// request straight away, without sending it
+type authCheckTransport struct {
+	base   http.RoundTripper
+	failed atomic.Bool
+}
+
+func (t *authCheckTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.failed.Load() {
+		return nil, ErrBadCredentials
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		t.failed.Store(true)
+		return nil, ErrBadCredentials
+	}
+	return resp, nil
+}
@@ -0,0 +1,91 @@
+package github
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// maxAnnotationsPerRequest is GitHub's limit on annotations per check run update
+const maxAnnotationsPerRequest = 50
+
+// CheckAnnotation is an inline note on a check run
+type CheckAnnotation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Level     string // notice, warning or failure
+	Title     string
+	Message   string
+}
+
+// CheckRun is a completed check run to publish on a commit
+type CheckRun struct {
+	Name        string
+	HeadSHA     string
+	Title       string
+	Summary     string
+	Conclusion  string // success, neutral, failure, ...
+	Annotations []*CheckAnnotation
+}
+
+// CreateCheckRun publishes a completed check run with its annotations. GitHub
+// accepts 50 annotations per request, so the rest are added in updates.
+// Creating check runs requires a GitHub App token.
+func (c *Client) CreateCheckRun(ref *PRReference, run *CheckRun) error {
+	batches := annotationBatches(run.Annotations)
+
+	output := func(batch []*github.CheckRunAnnotation) *github.CheckRunOutput {
+		return &github.CheckRunOutput{
+			Title:       github.String(run.Title),
+			Summary:     github.String(run.Summary),
+			Annotations: batch,
+		}
+	}
+
+	created, _, err := c.client.Checks.CreateCheckRun(c.ctx, ref.Owner, ref.Repo, github.CreateCheckRunOptions{
+		Name:        run.Name,
+		HeadSHA:     run.HeadSHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(run.Conclusion),
+		CompletedAt: &github.Timestamp{Time: c.clock.Now()},
+		Output:      output(batches[0]),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create check run: %w", err)
+	}
+
+	for _, batch := range batches[1:] {
+		_, _, err := c.client.Checks.UpdateCheckRun(c.ctx, ref.Owner, ref.Repo, created.GetID(), github.UpdateCheckRunOptions{
+			Name:   run.Name,
+			Output: output(batch),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add check run annotations: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// annotationBatches converts annotations and splits them into request-sized
+// batches. There is always at least one (possibly empty) batch.
+func annotationBatches(annotations []*CheckAnnotation) [][]*github.CheckRunAnnotation {
+	batches := [][]*github.CheckRunAnnotation{nil}
+	for _, a := range annotations {
+		last := len(batches) - 1
+		if len(batches[last]) == maxAnnotationsPerRequest {
+			batches = append(batches, nil)
+			last++
+		}
+		batches[last] = append(batches[last], &github.CheckRunAnnotation{
+			Path:            github.String(a.Path),
+			StartLine:       github.Int(a.StartLine),
+			EndLine:         github.Int(a.EndLine),
+			AnnotationLevel: github.String(a.Level),
+			Title:           github.String(a.Title),
+			Message:         github.String(a.Message),
+		})
+	}
+	return batches
+}
@@ -2,19 +2,92 @@ package github
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/user/salty-reviewer/internal/clock"
 	"golang.org/x/oauth2"
 )
 
+// PullRequest is the GitHub pull request type returned by GetPR
+type PullRequest = github.PullRequest
+
+// ErrFileTooLarge is returned by GetFileContent for files above the configured size limit
+var ErrFileTooLarge = errors.New("file too large")
+
+// ErrNotAncestor is returned by GetPRFilesSince when the PR's history was
+// rewritten, e.g. by a force push, so there is no diff since the old commit
+var ErrNotAncestor = errors.New("commit is no longer part of the PR's history")
+
+// ErrCompareTruncated is returned by GetPRFilesSince when the comparison
+// lists CompareFilesLimit files, so some changed files may be missing
+var ErrCompareTruncated = errors.New("comparison lists GitHub's maximum number of files")
+
+// CompareFilesLimit is the most files GitHub lists for a comparison of two
+// commits; a comparison changing more is cut off there, paginated or not
+const CompareFilesLimit = 300
+
+// contentsAPILimit is the largest file the contents API returns inline;
+// anything bigger has to come through the blob API
+const contentsAPILimit = 1024 * 1024
+
 // Client wraps the GitHub API client
 type Client struct {
-	client *github.Client
-	ctx    context.Context
+	client       *github.Client
+	ctx          context.Context
+	teamMembers  map[string]bool // team membership lookups, keyed by "org/team:user"
+	maxFileBytes int             // 0 means unlimited
+	transport    http.RoundTripper
+	clock        clock.Clock
+
+	filesMu sync.Mutex
+	files   map[fileKey]*fileEntry // GetFileContent results, including failures
+}
+
+// fileKey identifies a file at a ref for the content cache
+type fileKey struct {
+	owner, repo, path, ref string
+}
+
+// fileEntry is one cached fetch; once makes concurrent callers wait for a
+// single request instead of each making their own
+type fileEntry struct {
+	once    sync.Once
+	content string
+	err     error
+}
+
+// Option configures optional Client behavior
+type Option func(*Client)
+
+// WithTransport sets the base HTTP transport underneath the OAuth2 token
+// transport, e.g. a stub RoundTripper in tests
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithClock replaces the wall clock, e.g. to pin check run timestamps in tests
+func WithClock(clk clock.Clock) Option {
+	return func(c *Client) {
+		c.clock = clk
+	}
+}
+
+// WithMaxFileBytes makes GetFileContent refuse files larger than n bytes
+func WithMaxFileBytes(n int) Option {
+	return func(c *Client) {
+		c.maxFileBytes = n
+	}
 }
 
 // PRReference holds parsed PR information
@@ -26,45 +99,144 @@ type PRReference struct {
 
 // FileChange represents a changed file in a PR
 type FileChange struct {
-	Filename    string
-	Status      string // added, modified, removed, renamed
-	Additions   int
-	Deletions   int
-	Patch       string // The diff patch
+	Filename     string
+	Status       string // added, modified, removed, renamed
+	Additions    int
+	Deletions    int
+	Patch        string // The diff patch
 	PreviousName string // For renamed files
+	SHA          string // Blob SHA of the file's new content
+}
+
+// PRCommit represents a single commit on a PR
+type PRCommit struct {
+	SHA     string
+	Message string
+	Author  string
+}
+
+// ShortSHA returns the abbreviated commit hash
+func (pc *PRCommit) ShortSHA() string {
+	return shortSHA(pc.SHA)
+}
+
+// Title returns the first line of the commit message
+func (pc *PRCommit) Title() string {
+	title, _, _ := strings.Cut(pc.Message, "\n")
+	return title
+}
+
+// PRInfo is the listing entry for an open PR
+type PRInfo struct {
+	Number    int
+	Title     string
+	Author    string
+	UpdatedAt time.Time
+	Draft     bool
 }
 
 // ReviewComment represents a comment to be posted
 type ReviewComment struct {
 	Path     string
-	Line     int
+	Line     int // The last line of a multi-line comment
 	Body     string
 	Side     string // LEFT or RIGHT
+	Severity string // Optional, e.g. critical or nit; used for check annotations
+
+	// StartLine and StartSide make a multi-line comment; 0 = single line
+	StartLine int
+	StartSide string
+}
+
+// PRReview is a review on a PR
+type PRReview struct {
+	ID       int64
+	User     string
+	Body     string
+	State    string // APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED or PENDING
+	CommitID string // The commit the review was made on
+}
+
+// ShortSHA returns the abbreviated hash of the reviewed commit
+func (pr *PRReview) ShortSHA() string {
+	return shortSHA(pr.CommitID)
 }
 
 // PRComment represents an existing comment on a PR
 type PRComment struct {
 	ID        int64
+	Type      string // CommentTypeReview or CommentTypeIssue
 	User      string
 	Body      string
 	Path      string
 	Line      int
+	Side      string // RIGHT for added/context lines, LEFT for removed lines
+	StartLine int    // First line of a multi-line comment, 0 for single-line comments
 	CreatedAt string
 	InReplyTo int64
+	ReviewID  int64 // The review the comment belongs to, 0 if unknown
+
+	// OriginalLine and DiffHunk describe where the comment was made. For outdated
+	// comments Line is 0 and these are the only way to find the code.
+	OriginalLine int
+	DiffHunk     string
+}
+
+// Kinds of PRComment. Review comments are attached to code and replied to in
+// their thread; issue comments are on the PR's conversation and have no path,
+// line or thread.
+const (
+	CommentTypeReview = "review"
+	CommentTypeIssue  = "issue"
+)
+
+// Where says where a comment was left, for progress output: its file, or the
+// conversation for an issue comment
+func (c *PRComment) Where() string {
+	if c.Type == CommentTypeIssue {
+		return "the conversation"
+	}
+	return c.Path
 }
 
 // NewClient creates a new GitHub client with the given token
-func NewClient(token string) *Client {
-	ctx := context.Background()
+func NewClient(token string, opts ...Option) *Client {
+	c := &Client{
+		ctx:         context.Background(),
+		teamMembers: make(map[string]bool),
+		files:       make(map[fileKey]*fileEntry),
+		clock:       clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// oauth2 picks up the base transport from the context
+	base := c.transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	authCtx := context.WithValue(c.ctx, oauth2.HTTPClient, &http.Client{Transport: &authCheckTransport{base: base}})
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	c.client = github.NewClient(oauth2.NewClient(authCtx, ts))
+
+	return c
+}
 
-	return &Client{
-		client: github.NewClient(tc),
-		ctx:    ctx,
+// Clock is the clock the client was created with, the wall clock by default
+func (c *Client) Clock() clock.Clock {
+	return c.clock
+}
+
+// GetAuthenticatedUser returns the login of the user the token belongs to
+func (c *Client) GetAuthenticatedUser() (string, error) {
+	user, _, err := c.client.Users.Get(c.ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch authenticated user: %w", err)
 	}
+	return user.GetLogin(), nil
 }
 
 // ParsePRReference parses various PR reference formats
@@ -95,15 +267,62 @@ func ParsePRReference(ref string) (*PRReference, error) {
 	return nil, fmt.Errorf("invalid PR reference format: %s (use owner/repo#123 or GitHub URL)", ref)
 }
 
+// ParseRepoReference parses an owner/repo string or a GitHub repository URL
+func ParseRepoReference(ref string) (owner, repo string, err error) {
+	urlPattern := regexp.MustCompile(`github\.com/([^/]+)/([^/#?]+)`)
+	if matches := urlPattern.FindStringSubmatch(ref); matches != nil {
+		return matches[1], strings.TrimSuffix(matches[2], ".git"), nil
+	}
+
+	shortPattern := regexp.MustCompile(`^([^/#]+)/([^/#]+)$`)
+	if matches := shortPattern.FindStringSubmatch(ref); matches != nil {
+		return matches[1], matches[2], nil
+	}
+
+	return "", "", fmt.Errorf("invalid repository format: %s (use owner/repo or GitHub URL)", ref)
+}
+
 // GetPR fetches PR details
 func (c *Client) GetPR(ref *PRReference) (*github.PullRequest, error) {
 	pr, _, err := c.client.PullRequests.Get(c.ctx, ref.Owner, ref.Repo, ref.Number)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch PR: %w", err)
+		return nil, fmt.Errorf("failed to fetch PR: %w", rateLimited(err))
 	}
 	return pr, nil
 }
 
+// ListOpenPRs returns every open pull request in a repository
+func (c *Client) ListOpenPRs(owner, repo string) ([]*PRInfo, error) {
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var allPRs []*PRInfo
+
+	for {
+		prs, resp, err := c.client.PullRequests.List(c.ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list open PRs: %w", err)
+		}
+		for _, pr := range prs {
+			allPRs = append(allPRs, &PRInfo{
+				Number:    pr.GetNumber(),
+				Title:     pr.GetTitle(),
+				Author:    pr.GetUser().GetLogin(),
+				UpdatedAt: pr.GetUpdatedAt().Time,
+				Draft:     pr.GetDraft(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allPRs, nil
+}
+
 // GetPRFiles returns the list of changed files in a PR
 func (c *Client) GetPRFiles(ref *PRReference) ([]*FileChange, error) {
 	opts := &github.ListOptions{PerPage: 100}
@@ -112,21 +331,63 @@ func (c *Client) GetPRFiles(ref *PRReference) ([]*FileChange, error) {
 	for {
 		files, resp, err := c.client.PullRequests.ListFiles(c.ctx, ref.Owner, ref.Repo, ref.Number, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch PR files: %w", err)
+			return nil, fmt.Errorf("failed to fetch PR files: %w", rateLimited(err))
 		}
 
 		for _, f := range files {
-			fc := &FileChange{
-				Filename:  f.GetFilename(),
-				Status:    f.GetStatus(),
-				Additions: f.GetAdditions(),
-				Deletions: f.GetDeletions(),
-				Patch:     f.GetPatch(),
-			}
-			if f.GetStatus() == "renamed" {
-				fc.PreviousName = f.GetPreviousFilename()
-			}
-			allFiles = append(allFiles, fc)
+			allFiles = append(allFiles, toFileChange(f))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allFiles, nil
+}
+
+// ListPRCommits returns the commits of a PR, oldest first
+func (c *Client) ListPRCommits(ref *PRReference) ([]*PRCommit, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var allCommits []*PRCommit
+
+	for {
+		commits, resp, err := c.client.PullRequests.ListCommits(c.ctx, ref.Owner, ref.Repo, ref.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch PR commits: %w", err)
+		}
+
+		for _, rc := range commits {
+			allCommits = append(allCommits, &PRCommit{
+				SHA:     rc.GetSHA(),
+				Message: rc.GetCommit().GetMessage(),
+				Author:  rc.GetAuthor().GetLogin(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allCommits, nil
+}
+
+// GetCommitFiles returns the files changed by a single commit
+func (c *Client) GetCommitFiles(owner, repo, sha string) ([]*FileChange, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var allFiles []*FileChange
+
+	for {
+		commit, resp, err := c.client.Repositories.GetCommit(c.ctx, owner, repo, sha, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commit %s: %w", shortSHA(sha), err)
+		}
+
+		for _, f := range commit.Files {
+			allFiles = append(allFiles, toFileChange(f))
 		}
 
 		if resp.NextPage == 0 {
@@ -138,13 +399,108 @@ func (c *Client) GetPRFiles(ref *PRReference) ([]*FileChange, error) {
 	return allFiles, nil
 }
 
-// GetFileContent fetches the content of a file at a specific ref
+// CompareCommits returns the files changed between the merge base of base and
+// head, and head - i.e. the combined diff head would bring into base. GitHub
+// lists at most CompareFilesLimit files for a comparison, so a result that
+// long may be missing some.
+func (c *Client) CompareCommits(owner, repo, base, head string) ([]*FileChange, error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(c.ctx, owner, repo, base, head, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", base, shortSHA(head), err)
+	}
+
+	files := make([]*FileChange, 0, len(comparison.Files))
+	for _, f := range comparison.Files {
+		files = append(files, toFileChange(f))
+	}
+	return files, nil
+}
+
+// GetPRFilesSince returns the PR's changed files with the diff between
+// baseSHA, an earlier head of the PR, and headSHA. Files the PR no longer
+// changes are left out, since comments can only go on the PR's diff. A
+// comparison cut off at CompareFilesLimit fails with ErrCompareTruncated.
+func (c *Client) GetPRFilesSince(ref *PRReference, baseSHA, headSHA string) ([]*FileChange, error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(c.ctx, ref.Owner, ref.Repo, baseSHA, headSHA, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", shortSHA(baseSHA), shortSHA(headSHA), rateLimited(err))
+	}
+	// Anything but ahead means baseSHA isn't an ancestor of headSHA, and the
+	// comparison would start from an older merge base
+	if status := comparison.GetStatus(); status != "ahead" && status != "identical" {
+		return nil, fmt.Errorf("%s: %w", shortSHA(baseSHA), ErrNotAncestor)
+	}
+	if len(comparison.Files) >= CompareFilesLimit {
+		return nil, fmt.Errorf("%s...%s: %w", shortSHA(baseSHA), shortSHA(headSHA), ErrCompareTruncated)
+	}
+
+	prFiles, err := c.GetPRFiles(ref)
+	if err != nil {
+		return nil, err
+	}
+	inPR := make(map[string]bool, len(prFiles))
+	for _, f := range prFiles {
+		inPR[f.Filename] = true
+	}
+
+	var files []*FileChange
+	for _, f := range comparison.Files {
+		if fc := toFileChange(f); inPR[fc.Filename] {
+			files = append(files, fc)
+		}
+	}
+	return files, nil
+}
+
+// GetFileContent fetches the content of a file at a specific ref. Results are
+// cached for the life of the client, 404s included, so a file that doesn't
+// exist at ref is only asked for once. Other errors aren't cached, since
+// they may be transient, like a rate limit or a server error.
 func (c *Client) GetFileContent(owner, repo, path, ref string) (string, error) {
+	key := fileKey{owner, repo, path, ref}
+	c.filesMu.Lock()
+	entry, ok := c.files[key]
+	if !ok {
+		entry = &fileEntry{}
+		c.files[key] = entry
+	}
+	c.filesMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.content, entry.err = c.fetchFileContent(owner, repo, path, ref)
+	})
+	if entry.err != nil && !isNotFound(entry.err) {
+		c.filesMu.Lock()
+		if c.files[key] == entry {
+			delete(c.files, key)
+		}
+		c.filesMu.Unlock()
+	}
+	return entry.content, entry.err
+}
+
+func (c *Client) fetchFileContent(owner, repo, path, ref string) (string, error) {
 	content, _, _, err := c.client.Repositories.GetContents(c.ctx, owner, repo, path, &github.RepositoryContentGetOptions{
 		Ref: ref,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch file content: %w", err)
+		return "", fmt.Errorf("failed to fetch file content: %w", rateLimited(err))
+	}
+	if content == nil {
+		return "", fmt.Errorf("failed to fetch file content: %s is a directory", path)
+	}
+
+	if c.maxFileBytes > 0 && content.GetSize() > c.maxFileBytes {
+		return "", fmt.Errorf("%s is %d bytes (limit %d): %w", path, content.GetSize(), c.maxFileBytes, ErrFileTooLarge)
+	}
+
+	// The contents API omits the body of files over 1MB (encoding "none")
+	if content.GetEncoding() == "none" || content.GetSize() > contentsAPILimit {
+		blob, _, err := c.client.Git.GetBlobRaw(c.ctx, owner, repo, content.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch large file %s via blob API: %w", path, rateLimited(err))
+		}
+		return string(blob), nil
 	}
 
 	decoded, err := content.GetContent()
@@ -155,32 +511,69 @@ func (c *Client) GetFileContent(owner, repo, path, ref string) (string, error) {
 	return decoded, nil
 }
 
-// GetRelatedFiles finds files that might be related (imports, tests, etc.)
-func (c *Client) GetRelatedFiles(owner, repo, path, ref string) ([]string, error) {
+// GetFileSize returns the size in bytes of a file at the given ref, without
+// downloading its content
+func (c *Client) GetFileSize(owner, repo, path, ref string) (int, error) {
+	content, _, _, err := c.client.Repositories.GetContents(c.ctx, owner, repo, path, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch file metadata: %w", rateLimited(err))
+	}
+	if content == nil {
+		return 0, fmt.Errorf("failed to fetch file metadata: %s is a directory", path)
+	}
+	return content.GetSize(), nil
+}
+
+// GetRelatedFiles finds files that might be related (imports, tests, etc.).
+// For a renamed file, previousName is its old path: tests often keep the old
+// name, so candidates are built from both. Pass "" if the file wasn't renamed.
+// Candidates that can't be fetched are left out, unless the token was
+// rejected or GitHub is throttling, which are returned.
+func (c *Client) GetRelatedFiles(owner, repo, path, previousName, ref string) ([]string, error) {
 	var related []string
+	seen := make(map[string]bool)
+
+	var testPatterns []string
+	for _, p := range []string{path, previousName} {
+		if p != "" {
+			testPatterns = append(testPatterns, testFileCandidates(p)...)
+		}
+	}
+
+	for _, pattern := range testPatterns {
+		if seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		_, err := c.GetFileContent(owner, repo, pattern, ref)
+		if errors.Is(err, ErrBadCredentials) || errors.Is(err, ErrRateLimited) {
+			return nil, err
+		}
+		if err == nil {
+			related = append(related, pattern)
+		}
+	}
+
+	return related, nil
+}
 
+// testFileCandidates lists the paths where tests for path might live
+func testFileCandidates(path string) []string {
 	// Get the directory
 	dir := getDirectory(path)
 	filename := getFilename(path)
 	ext := getExtension(path)
 	baseName := strings.TrimSuffix(filename, ext)
 
-	// Look for test files
-	testPatterns := []string{
+	return []string{
 		dir + "/" + baseName + "_test" + ext,
 		dir + "/" + baseName + ".test" + ext,
 		dir + "/" + baseName + ".spec" + ext,
 		"test/" + path,
 		"tests/" + path,
 	}
-
-	for _, pattern := range testPatterns {
-		if _, err := c.GetFileContent(owner, repo, pattern, ref); err == nil {
-			related = append(related, pattern)
-		}
-	}
-
-	return related, nil
 }
 
 // GetPRComments fetches all review comments on a PR
@@ -199,12 +592,19 @@ func (c *Client) GetPRComments(ref *PRReference) ([]*PRComment, error) {
 		for _, c := range comments {
 			pc := &PRComment{
 				ID:        c.GetID(),
+				Type:      CommentTypeReview,
 				User:      c.GetUser().GetLogin(),
 				Body:      c.GetBody(),
 				Path:      c.GetPath(),
 				Line:      c.GetLine(),
+				Side:      c.GetSide(),
+				StartLine: c.GetStartLine(),
 				CreatedAt: c.GetCreatedAt().String(),
 				InReplyTo: c.GetInReplyTo(),
+				ReviewID:  c.GetPullRequestReviewID(),
+
+				OriginalLine: c.GetOriginalLine(),
+				DiffHunk:     c.GetDiffHunk(),
 			}
 			allComments = append(allComments, pc)
 		}
@@ -218,16 +618,133 @@ func (c *Client) GetPRComments(ref *PRReference) ([]*PRComment, error) {
 	return allComments, nil
 }
 
+// GetIssueComments gets the comments on a PR's conversation, leaving out ones
+// by bots such as CI and coverage reporters
+func (c *Client) GetIssueComments(ref *PRReference) ([]*PRComment, error) {
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var allComments []*PRComment
+
+	for {
+		comments, resp, err := c.client.Issues.ListComments(c.ctx, ref.Owner, ref.Repo, ref.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch PR conversation comments: %w", err)
+		}
+
+		for _, c := range comments {
+			if c.GetUser().GetType() == "Bot" {
+				continue
+			}
+			allComments = append(allComments, &PRComment{
+				ID:        c.GetID(),
+				Type:      CommentTypeIssue,
+				User:      c.GetUser().GetLogin(),
+				Body:      c.GetBody(),
+				CreatedAt: c.GetCreatedAt().String(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allComments, nil
+}
+
+// ListReviews returns the reviews on a PR, oldest first
+func (c *Client) ListReviews(ref *PRReference) ([]*PRReview, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var allReviews []*PRReview
+
+	for {
+		reviews, resp, err := c.client.PullRequests.ListReviews(c.ctx, ref.Owner, ref.Repo, ref.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch PR reviews: %w", err)
+		}
+
+		for _, r := range reviews {
+			allReviews = append(allReviews, &PRReview{
+				ID:       r.GetID(),
+				User:     r.GetUser().GetLogin(),
+				Body:     r.GetBody(),
+				State:    r.GetState(),
+				CommitID: r.GetCommitID(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allReviews, nil
+}
+
+// ListSubmittedReviewIDs returns the IDs of a PR's reviews that have been
+// submitted, leaving out pending ones whose author is still writing them
+func (c *Client) ListSubmittedReviewIDs(ref *PRReference) (map[int64]bool, error) {
+	reviews, err := c.ListReviews(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	submitted := make(map[int64]bool)
+	for _, r := range reviews {
+		if r.State != "PENDING" {
+			submitted[r.ID] = true
+		}
+	}
+	return submitted, nil
+}
+
 // PostReview submits a review with comments
 func (c *Client) PostReview(ref *PRReference, body string, event string, comments []*ReviewComment) error {
+	_, err := c.PostReviewAtCommit(ref, "", body, event, comments)
+	return err
+}
+
+// PostReviewAtCommit submits a review whose comments are anchored to a specific
+// commit and returns the new review's ID. An empty commitID means the PR head.
+func (c *Client) PostReviewAtCommit(ref *PRReference, commitID string, body string, event string, comments []*ReviewComment) (int64, error) {
+	if strings.TrimSpace(body) == "" && len(comments) == 0 {
+		return 0, fmt.Errorf("failed to post review: a review needs a body or at least one comment")
+	}
+
+	review := buildReviewRequest(commitID, body, event, comments)
+	posted, _, err := c.client.PullRequests.CreateReview(c.ctx, ref.Owner, ref.Repo, ref.Number, review)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post review: %w", err)
+	}
+
+	return posted.GetID(), nil
+}
+
+// ReviewPayload returns the JSON body PostReviewAtCommit would send, to
+// preview a review without posting it
+func ReviewPayload(commitID string, body string, event string, comments []*ReviewComment) ([]byte, error) {
+	return json.MarshalIndent(buildReviewRequest(commitID, body, event, comments), "", "  ")
+}
+
+// buildReviewRequest turns a review into the request GitHub's create review
+// endpoint takes
+func buildReviewRequest(commitID string, body string, event string, comments []*ReviewComment) *github.PullRequestReviewRequest {
 	var ghComments []*github.DraftReviewComment
 	for _, rc := range comments {
-		ghComments = append(ghComments, &github.DraftReviewComment{
+		draft := &github.DraftReviewComment{
 			Path: github.String(rc.Path),
 			Line: github.Int(rc.Line),
 			Body: github.String(rc.Body),
 			Side: github.String(rc.Side),
-		})
+		}
+		if rc.StartLine > 0 && rc.StartLine < rc.Line {
+			draft.StartLine = github.Int(rc.StartLine)
+			draft.StartSide = github.String(rc.StartSide)
+		}
+		ghComments = append(ghComments, draft)
 	}
 
 	review := &github.PullRequestReviewRequest{
@@ -235,12 +752,186 @@ func (c *Client) PostReview(ref *PRReference, body string, event string, comment
 		Event:    github.String(event), // APPROVE, REQUEST_CHANGES, COMMENT
 		Comments: ghComments,
 	}
+	if commitID != "" {
+		review.CommitID = github.String(commitID)
+	}
+	return review
+}
+
+// ListReviewComments returns the inline comments belonging to one review
+func (c *Client) ListReviewComments(ref *PRReference, reviewID int64) ([]*PRComment, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var allComments []*PRComment
+
+	for {
+		comments, resp, err := c.client.PullRequests.ListReviewComments(c.ctx, ref.Owner, ref.Repo, ref.Number, reviewID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch review comments: %w", err)
+		}
+
+		for _, rc := range comments {
+			allComments = append(allComments, &PRComment{
+				ID:       rc.GetID(),
+				Type:     CommentTypeReview,
+				User:     rc.GetUser().GetLogin(),
+				Body:     rc.GetBody(),
+				Path:     rc.GetPath(),
+				Line:     rc.GetLine(),
+				Side:     rc.GetSide(),
+				ReviewID: reviewID,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allComments, nil
+}
+
+// CommentPermalink returns the link to an inline review comment
+func (ref *PRReference) CommentPermalink(commentID int64) string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d#discussion_r%d", ref.Owner, ref.Repo, ref.Number, commentID)
+}
 
-	_, _, err := c.client.PullRequests.CreateReview(c.ctx, ref.Owner, ref.Repo, ref.Number, review)
+// PostIssueComment posts a top-level comment on a PR's conversation
+func (c *Client) PostIssueComment(ref *PRReference, body string) error {
+	_, _, err := c.client.Issues.CreateComment(c.ctx, ref.Owner, ref.Repo, ref.Number, &github.IssueComment{
+		Body: github.String(body),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to post review: %w", err)
+		return fmt.Errorf("failed to post comment: %w", err)
+	}
+	return nil
+}
+
+// lineNotInDiffErrors are the parts of GitHub's 422 messages saying a
+// review comment can't be placed on the diff
+var lineNotInDiffErrors = []string{
+	"line could not be resolved",
+	"path could not be resolved",
+	"must be part of the diff",
+	"must be part of the same hunk",
+}
+
+// IsLineNotInDiff reports whether GitHub rejected a review because a comment's
+// line isn't part of the diff, e.g. after the commit it was pinned to was
+// force-pushed away. Other validation errors don't count.
+func IsLineNotInDiff(err error) bool {
+	var ghErr *github.ErrorResponse
+	if !errors.As(err, &ghErr) || ghErr.Response == nil || ghErr.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
 	}
+	msg := strings.ToLower(ghErr.Error())
+	for _, part := range lineNotInDiffErrors {
+		if strings.Contains(msg, part) {
+			return true
+		}
+	}
+	return false
+}
 
+// GetLabels returns the names of the labels currently on a PR
+func (c *Client) GetLabels(ref *PRReference) ([]string, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var names []string
+
+	for {
+		labels, resp, err := c.client.Issues.ListLabelsByIssue(c.ctx, ref.Owner, ref.Repo, ref.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch labels: %w", err)
+		}
+
+		for _, l := range labels {
+			names = append(names, l.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return names, nil
+}
+
+// AddLabels adds labels to a PR, skipping any it already has
+func (c *Client) AddLabels(ref *PRReference, labels []string) error {
+	current, err := c.GetLabels(ref)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, l := range labels {
+		if !containsFold(current, l) {
+			missing = append(missing, l)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if _, _, err := c.client.Issues.AddLabelsToIssue(c.ctx, ref.Owner, ref.Repo, ref.Number, missing); err != nil {
+		return fmt.Errorf("failed to add labels: %w", err)
+	}
+	return nil
+}
+
+// RemoveLabels removes labels from a PR, ignoring any it doesn't have
+func (c *Client) RemoveLabels(ref *PRReference, labels []string) error {
+	current, err := c.GetLabels(ref)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range labels {
+		if !containsFold(current, l) {
+			continue
+		}
+		if _, err := c.client.Issues.RemoveLabelForIssue(c.ctx, ref.Owner, ref.Repo, ref.Number, l); err != nil {
+			return fmt.Errorf("failed to remove label %s: %w", l, err)
+		}
+	}
+	return nil
+}
+
+// usernamePattern matches valid GitHub logins: alphanumerics and single inner hyphens, up to 39 chars
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,37}[A-Za-z0-9])?$`)
+
+// IsValidUsername checks that a string could be a GitHub login
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(username) && !strings.Contains(username, "--")
+}
+
+// RequestReviewers asks users to review a PR
+func (c *Client) RequestReviewers(ref *PRReference, usernames []string) error {
+	_, resp, err := c.client.PullRequests.RequestReviewers(c.ctx, ref.Owner, ref.Repo, ref.Number, github.ReviewersRequest{
+		Reviewers: usernames,
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == 422 {
+			return fmt.Errorf("could not request review from %s (they must be collaborators on %s/%s and not the PR author): %w",
+				strings.Join(usernames, ", "), ref.Owner, ref.Repo, err)
+		}
+		return fmt.Errorf("failed to request reviewers: %w", err)
+	}
+	return nil
+}
+
+// CreateStatus sets a commit status, e.g. for status-check-gated merges. state
+// is one of error, failure, pending or success.
+func (c *Client) CreateStatus(owner, repo, sha, state, context, description string) error {
+	_, _, err := c.client.Repositories.CreateStatus(c.ctx, owner, repo, sha, &github.RepoStatus{
+		State:       github.String(state),
+		Context:     github.String(context),
+		Description: github.String(description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create commit status: %w", err)
+	}
 	return nil
 }
 
@@ -254,6 +945,37 @@ func (c *Client) ReplyToComment(ref *PRReference, commentID int64, body string)
 }
 
 // Helper functions
+func toFileChange(f *github.CommitFile) *FileChange {
+	fc := &FileChange{
+		Filename:  f.GetFilename(),
+		Status:    f.GetStatus(),
+		Additions: f.GetAdditions(),
+		Deletions: f.GetDeletions(),
+		Patch:     f.GetPatch(),
+		SHA:       f.GetSHA(),
+	}
+	if f.GetStatus() == "renamed" {
+		fc.PreviousName = f.GetPreviousFilename()
+	}
+	return fc
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
 func getDirectory(path string) string {
 	lastSlash := strings.LastIndex(path, "/")
 	if lastSlash == -1 {
@@ -0,0 +1,193 @@
+package github
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stubTransport answers every request with the status its path maps to, 404
+// for paths it doesn't know
+type stubTransport map[string]int
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, ok := s[req.URL.Path]
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"message": "stub"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestBuildReviewRequest(t *testing.T) {
+	comments := []*ReviewComment{
+		{Path: "main.go", Line: 12, Body: "nil map", Side: "RIGHT"},
+		{Path: "main.go", StartLine: 20, StartSide: "RIGHT", Line: 24, Body: "leaks the file", Side: "RIGHT"},
+		// A start line at or past the end line is sent as a single-line comment
+		{Path: "old.go", StartLine: 7, StartSide: "LEFT", Line: 7, Body: "still used", Side: "LEFT"},
+	}
+	payload, err := ReviewPayload("abc123", "🧂 summary", "REQUEST_CHANGES", comments)
+	if err != nil {
+		t.Fatalf("ReviewPayload: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("payload isn't JSON: %v", err)
+	}
+	var want map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"commit_id": "abc123",
+		"body": "🧂 summary",
+		"event": "REQUEST_CHANGES",
+		"comments": [
+			{"path": "main.go", "line": 12, "body": "nil map", "side": "RIGHT"},
+			{"path": "main.go", "start_line": 20, "start_side": "RIGHT", "line": 24, "body": "leaks the file", "side": "RIGHT"},
+			{"path": "old.go", "line": 7, "body": "still used", "side": "LEFT"}
+		]
+	}`), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("payload = %s\nwant %s", gotJSON, wantJSON)
+	}
+}
+
+func TestBuildReviewRequestNoCommit(t *testing.T) {
+	review := buildReviewRequest("", "summary", "COMMENT", nil)
+	if review.CommitID != nil {
+		t.Errorf("commit_id = %q, want it left out", review.GetCommitID())
+	}
+	if review.GetEvent() != "COMMENT" || review.GetBody() != "summary" || len(review.Comments) != 0 {
+		t.Errorf("review = %+v", review)
+	}
+}
+
+func TestGetRelatedFilesRenamed(t *testing.T) {
+	const contents = "/repos/o/r/contents/"
+	tests := []struct {
+		name         string
+		path         string
+		previousName string
+		stub         stubTransport
+		want         []string
+	}{
+		{
+			"test not renamed with the source",
+			"pkg/store/cache.go", "pkg/store/lru.go",
+			stubTransport{contents + "pkg/store/lru_test.go": http.StatusOK},
+			[]string{"pkg/store/lru_test.go"},
+		},
+		{
+			"moved to another directory",
+			"internal/store/lru.go", "pkg/store/lru.go",
+			stubTransport{contents + "pkg/store/lru_test.go": http.StatusOK},
+			[]string{"pkg/store/lru_test.go"},
+		},
+		{
+			"both tests exist",
+			"pkg/store/cache.go", "pkg/store/lru.go",
+			stubTransport{contents + "pkg/store/cache_test.go": http.StatusOK, contents + "pkg/store/lru_test.go": http.StatusOK},
+			[]string{"pkg/store/cache_test.go", "pkg/store/lru_test.go"},
+		},
+		{
+			"not renamed",
+			"pkg/store/lru.go", "",
+			stubTransport{contents + "pkg/store/lru_test.go": http.StatusOK},
+			[]string{"pkg/store/lru_test.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("token", WithTransport(tt.stub))
+			related, err := c.GetRelatedFiles("o", "r", tt.path, tt.previousName, "main")
+			if err != nil {
+				t.Fatalf("GetRelatedFiles: %v", err)
+			}
+			if !reflect.DeepEqual(related, tt.want) {
+				t.Errorf("related = %v, want %v", related, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLineNotInDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		errors string
+		want   bool
+	}{
+		{"line not in diff", http.StatusUnprocessableEntity, `["Line could not be resolved"]`, true},
+		{"thread line", http.StatusUnprocessableEntity, `["pull_request_review_thread.line must be part of the diff"]`, true},
+		{"start line in another hunk", http.StatusUnprocessableEntity, `["pull_request_review_thread.start_line must be part of the same hunk as the line."]`, true},
+		{"other validation error", http.StatusUnprocessableEntity, `["Body is too long (maximum is 65536 characters)"]`, false},
+		{"mentions a line but isn't one", http.StatusUnprocessableEntity, `["Review cannot request changes on your own pull request, see the diff"]`, false},
+		{"not a 422", http.StatusBadGateway, `["Line could not be resolved"]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("token", WithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: tt.status,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(`{"message": "Unprocessable Entity", "errors": ` + tt.errors + `}`)),
+					Request:    req,
+				}, nil
+			})))
+			_, err := c.PostReviewAtCommit(&PRReference{Owner: "o", Repo: "r", Number: 1}, "abc", "body", "COMMENT", nil)
+			if err == nil {
+				t.Fatal("PostReviewAtCommit succeeded, want an error")
+			}
+			if got := IsLineNotInDiff(err); got != tt.want {
+				t.Errorf("IsLineNotInDiff(%v) = %t, want %t", err, got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripFunc answers requests with a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetFileContentCachesOnlyNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantSent int
+	}{
+		{"not found", http.StatusNotFound, 1},
+		{"server error", http.StatusBadGateway, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := 0
+			stub := stubTransport{"/repos/o/r/contents/main.go": tt.status}
+			c := NewClient("token", WithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				sent++
+				return stub.RoundTrip(req)
+			})))
+			for i := 0; i < 2; i++ {
+				if _, err := c.GetFileContent("o", "r", "main.go", "abc"); err == nil {
+					t.Fatal("GetFileContent succeeded, want an error")
+				}
+			}
+			if sent != tt.wantSent {
+				t.Errorf("sent %d requests, want %d", sent, tt.wantSent)
+			}
+		})
+	}
+}
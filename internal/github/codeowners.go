@@ -0,0 +1,166 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// codeownersPaths are the locations GitHub checks for a CODEOWNERS file, in order
+var codeownersPaths = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// CodeownersRule maps a path pattern to its owners
+type CodeownersRule struct {
+	Pattern string
+	Owners  []string
+	regex   *regexp.Regexp
+}
+
+// Codeowners holds the parsed rules of a CODEOWNERS file
+type Codeowners struct {
+	Rules []CodeownersRule
+}
+
+// ParseCodeowners parses the content of a CODEOWNERS file
+func ParseCodeowners(content string) (*Codeowners, error) {
+	co := &Codeowners{}
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		var owners []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			owners = append(owners, f)
+		}
+
+		re, err := codeownersPatternToRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS pattern on line %d: %w", i+1, err)
+		}
+
+		co.Rules = append(co.Rules, CodeownersRule{
+			Pattern: fields[0],
+			Owners:  owners,
+			regex:   re,
+		})
+	}
+
+	return co, nil
+}
+
+// OwnersOf returns the owners of a path. As on GitHub, the last matching rule wins.
+func (co *Codeowners) OwnersOf(path string) []string {
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		if co.Rules[i].regex.MatchString(path) {
+			return co.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// codeownersPatternToRegexp converts a gitignore-style CODEOWNERS pattern to a regexp
+func codeownersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	// Patterns with a leading or inner slash are relative to the repo root,
+	// everything else may match at any depth
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case trimmed[i] == '*':
+			sb.WriteString("[^/]*")
+		case trimmed[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(trimmed[i])))
+		}
+	}
+
+	if dirOnly {
+		sb.WriteString("/.*$")
+	} else {
+		// A pattern naming a directory also owns everything beneath it
+		sb.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(sb.String())
+}
+
+// GetCodeowners fetches and parses the repository's CODEOWNERS file at a ref.
+// Returns nil without error if the repository has no CODEOWNERS file. Any
+// other failure is returned, since guessing there is none would review files
+// the user doesn't own.
+func (c *Client) GetCodeowners(owner, repo, ref string) (*Codeowners, error) {
+	for _, path := range codeownersPaths {
+		content, err := c.GetFileContent(owner, repo, path, ref)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return ParseCodeowners(content)
+	}
+	return nil, nil
+}
+
+// isNotFound reports whether GitHub answered with a 404
+func isNotFound(err error) bool {
+	var resp *github.ErrorResponse
+	return errors.As(err, &resp) && resp.Response != nil && resp.Response.StatusCode == http.StatusNotFound
+}
+
+// IsOwner checks whether a user is one of the given CODEOWNERS entries,
+// either directly (@user) or through membership of an owning team (@org/team)
+func (c *Client) IsOwner(username string, owners []string) bool {
+	for _, o := range owners {
+		o = strings.TrimPrefix(o, "@")
+		if strings.EqualFold(o, username) {
+			return true
+		}
+
+		org, slug, ok := strings.Cut(o, "/")
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(o + ":" + username)
+		member, cached := c.teamMembers[key]
+		if !cached {
+			membership, _, err := c.client.Teams.GetTeamMembershipBySlug(c.ctx, org, slug, username)
+			member = err == nil && membership.GetState() == "active"
+			c.teamMembers[key] = member
+		}
+		if member {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetCodeownersErrors(t *testing.T) {
+	const contents = "/repos/o/r/contents/"
+	tests := []struct {
+		name    string
+		stub    stubTransport
+		wantErr bool
+		badAuth bool // the error should be ErrBadCredentials
+	}{
+		{"no CODEOWNERS anywhere", stubTransport{}, false, false},
+		{"revoked token", stubTransport{contents + ".github/CODEOWNERS": http.StatusUnauthorized}, true, true},
+		{"server error after a miss", stubTransport{contents + "CODEOWNERS": http.StatusBadGateway}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("token", WithTransport(tt.stub))
+			owners, err := c.GetCodeowners("o", "r", "main")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %t", err, tt.wantErr)
+			}
+			if tt.badAuth && !errors.Is(err, ErrBadCredentials) {
+				t.Errorf("err = %v, want ErrBadCredentials", err)
+			}
+			if owners != nil {
+				t.Errorf("owners = %+v, want nil", owners)
+			}
+		})
+	}
+}
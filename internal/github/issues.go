@@ -0,0 +1,77 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Issue holds the parts of a GitHub issue salty cares about
+type Issue struct {
+	Owner  string
+	Repo   string
+	Number int
+	Title  string
+	Body   string
+	State  string
+}
+
+// IssueReference points at an issue, possibly in another repository
+type IssueReference struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// closingKeywords are the words GitHub uses to link a PR to the issue it resolves
+const closingKeywords = `(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+`
+
+var (
+	issueURLPattern   = regexp.MustCompile(closingKeywords + `https://github\.com/([\w.-]+)/([\w.-]+)/issues/(\d+)`)
+	issueShortPattern = regexp.MustCompile(closingKeywords + `(?:([\w.-]+)/([\w.-]+))?#(\d+)`)
+)
+
+// ParseIssueReferences finds the issues a PR body says it resolves ("Fixes #12",
+// "closes owner/repo#3", "Resolves https://github.com/owner/repo/issues/4").
+// References without an owner/repo belong to the PR's own repository.
+func ParseIssueReferences(body string, pr *PRReference) []IssueReference {
+	var refs []IssueReference
+	seen := make(map[IssueReference]bool)
+
+	for _, pattern := range []*regexp.Regexp{issueURLPattern, issueShortPattern} {
+		for _, m := range pattern.FindAllStringSubmatch(body, -1) {
+			num, _ := strconv.Atoi(m[3])
+			ref := IssueReference{Owner: m[1], Repo: m[2], Number: num}
+			if ref.Owner == "" {
+				ref.Owner, ref.Repo = pr.Owner, pr.Repo
+			}
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// GetIssue fetches an issue. Pull requests are rejected, since they share the
+// issue number space but aren't what a "Fixes #n" should point at.
+func (c *Client) GetIssue(owner, repo string, number int) (*Issue, error) {
+	issue, _, err := c.client.Issues.Get(c.ctx, owner, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch issue %s/%s#%d: %w", owner, repo, number, err)
+	}
+	if issue.IsPullRequest() {
+		return nil, fmt.Errorf("%s/%s#%d is a pull request, not an issue", owner, repo, number)
+	}
+
+	return &Issue{
+		Owner:  owner,
+		Repo:   repo,
+		Number: number,
+		Title:  issue.GetTitle(),
+		Body:   issue.GetBody(),
+		State:  issue.GetState(),
+	}, nil
+}
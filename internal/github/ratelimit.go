@@ -0,0 +1,55 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// ErrRateLimited is returned when GitHub refuses a request because the
+// primary or secondary (abuse detection) rate limit was hit
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// RateLimit is the state of the core API quota
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimit returns the remaining core API quota. Checking it doesn't count
+// against the quota.
+func (c *Client) RateLimit() (*RateLimit, error) {
+	limits, _, err := c.client.RateLimits(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rate limit: %w", rateLimited(err))
+	}
+	core := limits.GetCore()
+	if core == nil {
+		return nil, fmt.Errorf("failed to fetch rate limit: no core quota in response")
+	}
+	return &RateLimit{
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Reset:     core.Reset.Time,
+	}, nil
+}
+
+// rateLimited marks go-github's rate limit errors with ErrRateLimited, saying
+// when it is worth trying again. Other errors are returned unchanged.
+func rateLimited(err error) error {
+	var rle *github.RateLimitError
+	if errors.As(err, &rle) {
+		return fmt.Errorf("%w until %s: %w", ErrRateLimited, rle.Rate.Reset.Time.Local().Format("15:04:05"), err)
+	}
+	var abuse *github.AbuseRateLimitError
+	if errors.As(err, &abuse) {
+		if d := abuse.GetRetryAfter(); d > 0 {
+			return fmt.Errorf("%w (secondary limit, retry in %s): %w", ErrRateLimited, d.Round(time.Second), err)
+		}
+		return fmt.Errorf("%w (secondary limit): %w", ErrRateLimited, err)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReviewThread is a PR review thread as GraphQL sees it. Threads can only be
+// resolved through GraphQL, so they are looked up by the REST ids of their
+// comments.
+type ReviewThread struct {
+	ID       string // GraphQL node id
+	Resolved bool
+}
+
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        nodes {
+          id
+          isResolved
+          comments(first: 100) { nodes { databaseId } }
+        }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+const resolveReviewThreadMutation = `mutation($id: ID!) {
+  resolveReviewThread(input: {threadId: $id}) { thread { id } }
+}`
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// graphQL runs a GraphQL query and decodes its data into out
+func (c *Client) graphQL(query string, variables map[string]any, out any) error {
+	req, err := c.client.NewRequest("POST", "graphql", map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data   any            `json:"data"`
+		Errors []graphQLError `json:"errors"`
+	}
+	resp.Data = out
+	if _, err := c.client.Do(c.ctx, req, &resp); err != nil {
+		return rateLimited(err)
+	}
+	if len(resp.Errors) > 0 {
+		msgs := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			msgs[i] = e.Message
+		}
+		return fmt.Errorf("GraphQL: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// ListReviewThreads returns the review threads of a PR keyed by the REST id
+// of each comment in them
+func (c *Client) ListReviewThreads(ref *PRReference) (map[int64]ReviewThread, error) {
+	threads := make(map[int64]ReviewThread)
+	var after *string
+	for {
+		var data struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+							Comments   struct {
+								Nodes []struct {
+									DatabaseID int64 `json:"databaseId"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		err := c.graphQL(reviewThreadsQuery, map[string]any{
+			"owner":  ref.Owner,
+			"repo":   ref.Repo,
+			"number": ref.Number,
+			"after":  after,
+		}, &data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch review threads: %w", err)
+		}
+
+		page := data.Repository.PullRequest.ReviewThreads
+		for _, t := range page.Nodes {
+			for _, comment := range t.Comments.Nodes {
+				threads[comment.DatabaseID] = ReviewThread{ID: t.ID, Resolved: t.IsResolved}
+			}
+		}
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		cursor := page.PageInfo.EndCursor
+		after = &cursor
+	}
+	return threads, nil
+}
+
+// ResolveReviewThread marks a review thread as resolved
+func (c *Client) ResolveReviewThread(threadID string) error {
+	if err := c.graphQL(resolveReviewThreadMutation, map[string]any{"id": threadID}, nil); err != nil {
+		return fmt.Errorf("failed to resolve review thread: %w", err)
+	}
+	return nil
+}
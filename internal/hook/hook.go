@@ -0,0 +1,54 @@
+// Package hook runs the user's comment post-processing command, an extension
+// point for things like tone checkers or appending ticket links.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Run pipes body through command (program and arguments, no shell) and
+// returns what it prints. env is added to the command's environment. On a
+// timeout, a nonzero exit or empty output, Run returns an error and callers
+// should keep the original body.
+func Run(command []string, timeout time.Duration, body string, env map[string]string) (string, error) {
+	if len(command) == 0 {
+		return body, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return body, fmt.Errorf("comment hook timed out after %s", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return body, fmt.Errorf("comment hook failed: %w: %s", err, msg)
+		}
+		return body, fmt.Errorf("comment hook failed: %w", err)
+	}
+
+	out := strings.TrimRight(stdout.String(), "\n")
+	if strings.TrimSpace(out) == "" {
+		return body, fmt.Errorf("comment hook printed nothing")
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+// Package jsonx pulls JSON out of model responses, which often wrap it in
+// markdown fences or surround it with prose.
+package jsonx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/logging"
+)
+
+// excerptBytes is how much of an unparseable response an error quotes
+const excerptBytes = 200
+
+// Parse extracts the JSON object in response and decodes it into a T. what
+// names the response in the error, e.g. "deep analysis". On failure the error
+// quotes the start of the response and log, which may be nil, dumps all of it
+// when verbose.
+func Parse[T any](response, what string, log *logging.Logger) (*T, error) {
+	var v T
+	if err := json.Unmarshal([]byte(Extract(response)), &v); err != nil {
+		log.Dump("raw "+what+" response", response)
+		excerpt := response
+		if len(excerpt) > excerptBytes {
+			excerpt = excerpt[:excerptBytes] + "..."
+		}
+		return nil, fmt.Errorf("failed to parse %s: %w (response: %q)", what, err, excerpt)
+	}
+	return &v, nil
+}
+
+// Extract returns the first JSON object in response. Markdown code fences are
+// looked into first; within the text, the first balanced object that is valid
+// JSON wins, so prose or a second object around it is ignored. If there is no
+// such object, the best guess is returned and left for the caller's
+// json.Unmarshal to reject.
+func Extract(response string) string {
+	text := unfence(response)
+
+	var firstBalanced string
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			continue
+		}
+		end := balancedEnd(text, i)
+		if end == -1 {
+			continue
+		}
+		candidate := text[i : end+1]
+		if json.Valid([]byte(candidate)) {
+			return candidate
+		}
+		if firstBalanced == "" {
+			firstBalanced = candidate
+		}
+	}
+	if firstBalanced != "" {
+		return firstBalanced
+	}
+
+	// Truncated output: the object never closes
+	if start := strings.Index(text, "{"); start != -1 {
+		return text[start:]
+	}
+	return response
+}
+
+// unfence returns the content of the first markdown code fence that contains
+// an object, or text unchanged if there is none
+func unfence(text string) string {
+	rest := text
+	for {
+		open := strings.Index(rest, "```")
+		if open == -1 {
+			return text
+		}
+		// Skip the info string, e.g. ```json
+		body := rest[open+3:]
+		if nl := strings.IndexByte(body, '\n'); nl != -1 {
+			body = body[nl+1:]
+		} else {
+			return text
+		}
+		end := strings.Index(body, "```")
+		if end == -1 {
+			// Unclosed fence, e.g. cut off by a stop sequence
+			end = len(body)
+		}
+		if strings.Contains(body[:end], "{") {
+			return body[:end]
+		}
+		if end == len(body) {
+			return text
+		}
+		rest = body[end+3:]
+	}
+}
+
+// balancedEnd returns the index of the brace closing the object opened at
+// start, or -1 if it never closes. Braces inside strings don't count.
+func balancedEnd(text string, start int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
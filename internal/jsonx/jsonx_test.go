@@ -0,0 +1,46 @@
+package jsonx
+
+import "testing"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"bare object", `{"a": 1}`, `{"a": 1}`},
+		{"fenced", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"fence without info string", "```\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"prose before", `Here you go: {"a": 1}`, `{"a": 1}`},
+		{"prose after", `{"a": 1} Hope this helps!`, `{"a": 1}`},
+		{"prose around a fence", "Sure.\n```json\n{\"a\": 1}\n```\nAnything else?", `{"a": 1}`},
+		{"fence without an object first", "```go\nx := 1\n```\n```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"unclosed fence", "```json\n{\"a\": 1}\n", `{"a": 1}`},
+		{"braces inside strings", `{"code": "func() { }", "b": "}"}`, `{"code": "func() { }", "b": "}"}`},
+		{"escaped quote inside a string", `{"a": "say \"}\""} trailing`, `{"a": "say \"}\""}`},
+		{"second object ignored", `{"a": 1} {"b": 2}`, `{"a": 1}`},
+		{"invalid object skipped for a valid one", `{not json} {"a": 1}`, `{"a": 1}`},
+		{"truncated object", `Result: {"a": [1, 2`, `{"a": [1, 2`},
+		{"no object", "no JSON here", "no JSON here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.response); got != tt.want {
+				t.Errorf("Extract(%q) = %q, want %q", tt.response, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	type result struct {
+		A int `json:"a"`
+	}
+	got, err := Parse[result]("```json\n{\"a\": 3}\n```", "test result", nil)
+	if err != nil || got.A != 3 {
+		t.Errorf("Parse = %+v, %v; want a = 3", got, err)
+	}
+	if _, err := Parse[result]("not json", "test result", nil); err == nil {
+		t.Error("Parse of prose succeeded, want an error")
+	}
+}
@@ -0,0 +1,40 @@
+// Package live echoes AI responses to the terminal while they stream in.
+package live
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prefix sets the streamed text apart from the progress lines around it
+const prefix = "   │ "
+
+// Printer writes streamed text to w, indenting every line. The first line is
+// only started once text arrives, so nothing is printed if the call falls back
+// to an unstreamed one.
+type Printer struct {
+	w       io.Writer
+	started bool
+}
+
+// NewPrinter returns a Printer writing to w
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Print writes the next piece of the response
+func (p *Printer) Print(delta string) {
+	if !p.started {
+		fmt.Fprint(p.w, prefix)
+		p.started = true
+	}
+	fmt.Fprint(p.w, strings.ReplaceAll(delta, "\n", "\n"+prefix))
+}
+
+// End finishes the response's last line
+func (p *Printer) End() {
+	if p.started {
+		fmt.Fprintln(p.w)
+	}
+}
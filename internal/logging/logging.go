@@ -0,0 +1,83 @@
+// Package logging filters salty's progress output by the configured verbosity.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/user/salty-reviewer/internal/config"
+)
+
+// Logger is where progress goes. Writing to it like any io.Writer logs at
+// info level, which quiet drops; debug lines only show when verbose and
+// warnings show at every verbosity. Final results go to Result, which every
+// verbosity shows too.
+type Logger struct {
+	w         io.Writer
+	verbosity config.Verbosity
+}
+
+// New returns a Logger writing to w
+func New(w io.Writer, verbosity config.Verbosity) *Logger {
+	return &Logger{w: w, verbosity: verbosity}
+}
+
+// To returns a Logger with the same verbosity writing to w, e.g. a buffer a
+// worker fills so its lines don't interleave with others
+func (l *Logger) To(w io.Writer) *Logger {
+	return New(w, l.verbosity)
+}
+
+// Write logs p at info level
+func (l *Logger) Write(p []byte) (int, error) {
+	if l.verbosity == config.VerbosityQuiet {
+		return len(p), nil
+	}
+	return l.w.Write(p)
+}
+
+// Debugf logs a line when verbose
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.verbosity == config.VerbosityVerbose {
+		fmt.Fprintf(l.w, "🐛 "+format+"\n", args...)
+	}
+}
+
+// Warnf logs a line at warning level, which quiet still shows
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+// Dump saves content too long to log, like a raw AI response that didn't
+// parse, to a timestamped file under ~/.salty-reviewer/debug/ and logs the
+// path. Only when verbose; a nil Logger does nothing.
+func (l *Logger) Dump(name, content string) {
+	if l == nil || l.verbosity != config.VerbosityVerbose {
+		return
+	}
+	dir, err := config.ConfigDir()
+	if err == nil {
+		dir = filepath.Join(dir, "debug")
+		err = os.MkdirAll(dir, 0700)
+	}
+	if err != nil {
+		l.Debugf("Could not save the %s: %v", name, err)
+		return
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.txt", time.Now().Format("20060102-150405.000000"), strings.ReplaceAll(name, " ", "-")))
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		l.Debugf("Could not save the %s: %v", name, err)
+		return
+	}
+	l.Debugf("Saved the %s to %s", name, path)
+}
+
+// Result is written to unfiltered, for final results and for output a Logger
+// from To has already filtered
+func (l *Logger) Result() io.Writer {
+	return l.w
+}
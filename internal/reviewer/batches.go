@@ -0,0 +1,98 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// charsPerToken is the rough size of a token, for estimating prompt sizes
+// without the model's tokenizer
+const charsPerToken = 4
+
+// estimateTokens guesses how many tokens s is
+func estimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+// fileSection is how a changed file appears in the first-pass prompt. The
+// header is what the model names the file by in its issues.
+func fileSection(f *github.FileChange) string {
+	return fmt.Sprintf("\n--- %s ---\n%s\n", f.Filename, f.Patch)
+}
+
+// batch returns the context for batch i of n of a split first pass
+func (fpc FirstPassContext) batch(i, n int) FirstPassContext {
+	fpc.part, fpc.parts = i+1, n
+	return fpc
+}
+
+// firstPassBatches splits files, in order, into batches whose first-pass
+// prompt is estimated to fit in maxTokens. A file too big to share a batch
+// gets one of its own. With maxTokens 0, all files go in one batch.
+func firstPassBatches(files []*github.FileChange, fpc FirstPassContext, maxTokens int) [][]*github.FileChange {
+	if maxTokens <= 0 || len(files) == 0 {
+		return [][]*github.FileChange{files}
+	}
+
+	// The prompt, linked issues and guidelines are repeated in every batch
+	overhead := firstPassTokens(firstPassMessages(nil, fpc.batch(0, 2)))
+
+	var batches [][]*github.FileChange
+	var current []*github.FileChange
+	size := overhead
+	for _, f := range files {
+		cost := estimateTokens(fileSection(f))
+		if len(current) > 0 && size+cost > maxTokens {
+			batches = append(batches, current)
+			current, size = nil, overhead
+		}
+		current = append(current, f)
+		size += cost
+	}
+	return append(batches, current)
+}
+
+// mergeFirstPass combines the results of a split first pass. Issues are kept
+// in batch order. Each batch only sees part of the diff, so a linked-issue
+// requirement is only reported missing if every batch missed it.
+func mergeFirstPass(results []*FirstPassResult) *FirstPassResult {
+	merged := &FirstPassResult{Batches: len(results)}
+	for _, r := range results {
+		merged.Issues = append(merged.Issues, r.Issues...)
+	}
+	if len(results) == 0 {
+		return merged
+	}
+
+	merged.IssueGaps = results[0].IssueGaps
+	for _, r := range results[1:] {
+		reported := make(map[string]bool, len(r.IssueGaps))
+		for _, gap := range r.IssueGaps {
+			reported[normalizeGap(gap)] = true
+		}
+		var kept []string
+		for _, gap := range merged.IssueGaps {
+			if reported[normalizeGap(gap)] {
+				kept = append(kept, gap)
+			}
+		}
+		merged.IssueGaps = kept
+	}
+	return merged
+}
+
+func normalizeGap(gap string) string {
+	return strings.ToLower(strings.Join(strings.Fields(gap), " "))
+}
+
+// firstPassTokens estimates the size of a first-pass prompt
+func firstPassTokens(messages []ai.Message) int {
+	total := 0
+	for _, m := range messages {
+		total += estimateTokens(m.Content)
+	}
+	return total
+}
@@ -0,0 +1,50 @@
+package reviewer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+func TestFirstPassBatches(t *testing.T) {
+	fpc := FirstPassContext{}
+	overhead := firstPassTokens(firstPassMessages(nil, fpc.batch(0, 2)))
+	maxTokens := overhead + 1000
+
+	// patch returns a file whose section is roughly tokens big
+	patch := func(name string, tokens int) *github.FileChange {
+		return &github.FileChange{Filename: name, Patch: strings.Repeat("+x\n", tokens*charsPerToken/3)}
+	}
+	small1, small2, small3 := patch("a.go", 100), patch("b.go", 100), patch("c.go", 100)
+	huge := patch("huge.go", 5000)
+
+	tests := []struct {
+		name      string
+		files     []*github.FileChange
+		maxTokens int
+		want      [][]string
+	}{
+		{"unlimited", []*github.FileChange{small1, huge, small2}, 0, [][]string{{"a.go", "huge.go", "b.go"}}},
+		{"all fit", []*github.FileChange{small1, small2, small3}, maxTokens, [][]string{{"a.go", "b.go", "c.go"}}},
+		{"oversized patch alone", []*github.FileChange{small1, huge, small2, small3}, maxTokens, [][]string{{"a.go"}, {"huge.go"}, {"b.go", "c.go"}}},
+		{"oversized patch first", []*github.FileChange{huge, small1}, maxTokens, [][]string{{"huge.go"}, {"a.go"}}},
+		{"only an oversized patch", []*github.FileChange{huge}, maxTokens, [][]string{{"huge.go"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, batch := range firstPassBatches(tt.files, fpc, tt.maxTokens) {
+				var names []string
+				for _, f := range batch {
+					names = append(names, f.Filename)
+				}
+				got = append(got, names)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package reviewer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// reviewCacheFile is what a PR's review cache holds on disk. Entries are
+// keyed by a hash of everything that went into them, so a changed file or
+// setting simply misses.
+type reviewCacheFile struct {
+	HeadSHA   string    `json:"head_sha"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// First-pass issues by file, before line reconciliation
+	FirstPass map[string][]Issue `json:"first_pass,omitempty"`
+
+	// Deep analyses by issue
+	DeepAnalysis map[string]DeepAnalysisResult `json:"deep_analysis,omitempty"`
+
+	// Linked-issue gaps from the last first pass, which only makes sense for
+	// the whole diff
+	IssueGaps []string `json:"issue_gaps,omitempty"`
+}
+
+// reviewCache lets a re-review of a PR skip the AI for files that haven't
+// changed since the last run. Only entries used in the current run are
+// saved, so the cache doesn't grow with every push. A nil cache never hits.
+type reviewCache struct {
+	path  string
+	model string
+
+	mu   sync.Mutex
+	prev reviewCacheFile
+	next reviewCacheFile
+}
+
+// CacheDir is where review caches are kept
+func CacheDir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache"), nil
+}
+
+// ClearCache deletes every review cache and returns how many there were
+func ClearCache() (int, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not read cache: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("could not clear cache: %w", err)
+	}
+	return len(entries), nil
+}
+
+// loadReviewCache opens the cache of a PR. An unreadable cache just starts
+// empty.
+func (r *Reviewer) loadReviewCache(ref *github.PRReference, headSHA string) *reviewCache {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil
+	}
+	c := &reviewCache{
+		path:  filepath.Join(dir, fmt.Sprintf("%s_%s_%d.json", ref.Owner, ref.Repo, ref.Number)),
+		model: r.config.AIModel + "\x00" + r.config.AIModelAnalysis,
+		next: reviewCacheFile{
+			HeadSHA:      headSHA,
+			FirstPass:    make(map[string][]Issue),
+			DeepAnalysis: make(map[string]DeepAnalysisResult),
+		},
+	}
+	if data, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(data, &c.prev)
+	}
+	return c
+}
+
+// save writes the entries used in this run. Failing to save is only reported.
+func (c *reviewCache) save(r *Reviewer) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next.UpdatedAt = r.aiClient.Clock().Now()
+	data, err := json.MarshalIndent(c.next, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(c.path), 0700); err == nil {
+			err = os.WriteFile(c.path, data, 0600)
+		}
+	}
+	if err != nil {
+		r.out.Warnf("⚠️  Could not save the review cache: %v", err)
+	}
+}
+
+// fileKey identifies a file's diff as reviewed by the current models. The
+// blob SHA pins the content deep analysis reads, the patch what the first
+// pass sees.
+func (c *reviewCache) fileKey(f *github.FileChange) string {
+	if f == nil || f.SHA == "" {
+		return ""
+	}
+	return hashParts(c.model, f.Filename, f.SHA, f.Patch)
+}
+
+// split separates the files whose first-pass issues are cached from the ones
+// that need the AI, returning the cached issues in file order
+func (c *reviewCache) split(files []*github.FileChange, fpc FirstPassContext) (cached []Issue, fresh []*github.FileChange) {
+	if c == nil {
+		return nil, files
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, f := range files {
+		key := c.firstPassKey(f, fpc)
+		issues, ok := c.prev.FirstPass[key]
+		if key == "" || !ok {
+			fresh = append(fresh, f)
+			continue
+		}
+		c.next.FirstPass[key] = issues
+		cached = append(cached, issues...)
+	}
+	return cached, fresh
+}
+
+// storeFirstPass records the issues found in freshly reviewed files,
+// including the files without any
+func (c *reviewCache) storeFirstPass(files []*github.FileChange, fpc FirstPassContext, issues []Issue) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byFile := make(map[string][]Issue)
+	for _, issue := range issues {
+		byFile[issue.File] = append(byFile[issue.File], issue)
+	}
+	for _, f := range files {
+		if key := c.firstPassKey(f, fpc); key != "" {
+			c.next.FirstPass[key] = append([]Issue{}, byFile[f.Filename]...)
+		}
+	}
+}
+
+func (c *reviewCache) firstPassKey(f *github.FileChange, fpc FirstPassContext) string {
+	key := c.fileKey(f)
+	if key == "" {
+		return ""
+	}
+	linked, _ := json.Marshal(fpc.LinkedIssues)
+	return hashParts(key, fpc.Guidelines, fmt.Sprint(fpc.NewCodeOnly), string(linked))
+}
+
+// issueGaps works out the linked-issue gaps of the whole diff when only some
+// files went through the first pass again. Like a split first pass, a gap
+// is only kept if both the last run and the fresh files missed it.
+func (c *reviewCache) issueGaps(fresh *FirstPassResult, partial bool) []string {
+	if c == nil {
+		return fresh.IssueGaps
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gaps := fresh.IssueGaps
+	switch {
+	case fresh.Batches == 0:
+		gaps = c.prev.IssueGaps
+	case partial:
+		gaps = mergeFirstPass([]*FirstPassResult{{IssueGaps: c.prev.IssueGaps}, fresh}).IssueGaps
+	}
+	c.next.IssueGaps = gaps
+	return gaps
+}
+
+// analysis returns the cached deep analysis of an issue
+func (c *reviewCache) analysis(issue Issue, file *github.FileChange) (*DeepAnalysisResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key := c.issueKey(issue, file)
+	if key == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	analysis, ok := c.prev.DeepAnalysis[key]
+	if !ok {
+		analysis, ok = c.next.DeepAnalysis[key]
+	}
+	if !ok {
+		return nil, false
+	}
+	c.next.DeepAnalysis[key] = analysis
+	return &analysis, true
+}
+
+// storeAnalysis records the deep analysis of an issue
+func (c *reviewCache) storeAnalysis(issue Issue, file *github.FileChange, analysis *DeepAnalysisResult) {
+	if c == nil {
+		return
+	}
+	key := c.issueKey(issue, file)
+	if key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next.DeepAnalysis[key] = *analysis
+}
+
+func (c *reviewCache) issueKey(issue Issue, file *github.FileChange) string {
+	key := c.fileKey(file)
+	if key == "" {
+		return ""
+	}
+	data, _ := json.Marshal(issue)
+	return hashParts(key, string(data))
+}
+
+// hashParts hashes strings so that moving text between them changes the hash
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// firstPass runs the first pass over the files that aren't cached
+func (r *Reviewer) firstPass(files []*github.FileChange, fpc FirstPassContext) (*FirstPassResult, error) {
+	cached, fresh := r.cache.split(files, fpc)
+	r.reportCached(len(files) - len(fresh))
+
+	result := &FirstPassResult{}
+	if len(fresh) > 0 {
+		var err error
+		result, err = r.analyzer.FirstPass(fresh, fpc)
+		if err != nil {
+			return nil, err
+		}
+		r.cache.storeFirstPass(fresh, fpc, result.Issues)
+	}
+	return r.withCached(result, cached, len(fresh) < len(files)), nil
+}
+
+// firstPassStream is firstPass for streaming: cached issues are sent on
+// issues straight away, the rest as the AI finds them
+func (r *Reviewer) firstPassStream(files []*github.FileChange, fpc FirstPassContext, issues chan<- Issue) (*FirstPassResult, error) {
+	cached, fresh := r.cache.split(files, fpc)
+	r.reportCached(len(files) - len(fresh))
+
+	for _, issue := range cached {
+		issues <- issue
+	}
+	result := &FirstPassResult{}
+	if len(fresh) > 0 {
+		var err error
+		result, err = r.analyzer.FirstPassStream(fresh, fpc, issues)
+		if err != nil {
+			return nil, err
+		}
+		r.cache.storeFirstPass(fresh, fpc, result.Issues)
+	} else {
+		close(issues)
+	}
+	return r.withCached(result, cached, len(fresh) < len(files)), nil
+}
+
+// withCached adds the cached issues and gaps to a fresh first-pass result
+func (r *Reviewer) withCached(result *FirstPassResult, cached []Issue, partial bool) *FirstPassResult {
+	result.IssueGaps = r.cache.issueGaps(result, partial)
+	result.Issues = append(cached, result.Issues...)
+	return result
+}
+
+func (r *Reviewer) reportCached(files int) {
+	if files > 0 {
+		fmt.Fprintf(r.out, "💾 Reusing the first pass of %d unchanged file(s)\n", files)
+	}
+}
@@ -0,0 +1,108 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/user/salty-reviewer/internal/condense"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// checkRunName is the name salty's check runs show up under
+const checkRunName = "salty-reviewer"
+
+// checkRunSummaryLimit is GitHub's maximum length of a check run summary
+const checkRunSummaryLimit = 65535
+
+// annotationMessageLimit keeps an annotation message under GitHub's 64 KB
+// limit, counting every rune as the longest UTF-8 encoding
+const annotationMessageLimit = 64 * 1024 / utf8.UTFMax
+
+// annotationLevel maps a comment's severity to a check annotation level
+func annotationLevel(severity string) string {
+	switch Severity(severity) {
+	case SeverityCritical, SeverityMajor:
+		return "failure"
+	case SeverityMinor:
+		return "warning"
+	default:
+		return "notice"
+	}
+}
+
+// checkConclusion maps a review event to a check run conclusion
+func checkConclusion(event string, comments int) string {
+	switch {
+	case event == "REQUEST_CHANGES":
+		return "failure"
+	case event == "APPROVE":
+		return "success"
+	case comments > 0:
+		return "neutral"
+	default:
+		return "success"
+	}
+}
+
+// publishCheckRun posts the review as a check run with one annotation per
+// comment instead of a PR review. Comments on removed lines can't be
+// annotated, since annotations only point into the head commit.
+func (r *Reviewer) publishCheckRun(ref *github.PRReference, pr *github.PullRequest, commitID string, result *ReviewResult, event string, extraReviewers []string) error {
+	fmt.Fprintln(r.out, "📤 Posting check run...")
+
+	headSHA := commitID
+	if headSHA == "" {
+		headSHA = pr.GetHead().GetSHA()
+	}
+
+	var annotations []*github.CheckAnnotation
+	skipped := 0
+	for _, c := range result.Comments {
+		if c.Side == SideLeft {
+			skipped++
+			continue
+		}
+		title := "salty"
+		if c.Severity != "" {
+			title = "salty: " + c.Severity
+		}
+		start := c.Line
+		if c.StartLine > 0 {
+			start = c.StartLine
+		}
+		annotations = append(annotations, &github.CheckAnnotation{
+			Path:      c.Path,
+			StartLine: start,
+			EndLine:   c.Line,
+			Level:     annotationLevel(c.Severity),
+			Title:     title,
+			Message:   condense.Truncate(c.Body, annotationMessageLimit),
+		})
+	}
+	if skipped > 0 {
+		r.out.Warnf("   ⚠️  Skipping %d comment(s) on removed lines - checks can't annotate them", skipped)
+	}
+
+	err := r.githubClient.CreateCheckRun(ref, &github.CheckRun{
+		Name:        checkRunName,
+		HeadSHA:     headSHA,
+		Title:       fmt.Sprintf("%d comment(s)", len(annotations)),
+		Summary:     condense.Truncate(strings.TrimSpace(result.Summary), checkRunSummaryLimit),
+		Conclusion:  checkConclusion(event, len(result.Comments)),
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post check run: %w", err)
+	}
+	result.Stats.CommentsPosted = len(annotations)
+	fmt.Fprintf(r.out.Result(), "✅ Check run posted with %d annotations\n", len(annotations))
+
+	r.applyLabels(ref, event, len(result.Comments) == 0)
+
+	if event == "REQUEST_CHANGES" {
+		r.requestReviewers(ref, pr.GetUser().GetLogin(), extraReviewers)
+	}
+
+	return nil
+}
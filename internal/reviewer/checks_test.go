@@ -0,0 +1,70 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/logging"
+)
+
+// checkRunStub records the check run created and accepts it
+type checkRunStub struct {
+	output struct {
+		Summary     string `json:"summary"`
+		Annotations []struct {
+			Message string `json:"message"`
+		} `json:"annotations"`
+	}
+}
+
+func (s *checkRunStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body struct {
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body.Output, &s.output); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id": 1}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestPublishCheckRunTruncatesOnRunes(t *testing.T) {
+	stub := &checkRunStub{}
+	r := &Reviewer{
+		config:       config.DefaultConfig(),
+		githubClient: github.NewClient("token", github.WithTransport(stub)),
+		out:          logging.New(io.Discard, config.VerbosityNormal),
+	}
+	result := &ReviewResult{
+		Summary:  strings.Repeat("🧂", checkRunSummaryLimit),
+		Comments: []*github.ReviewComment{{Path: "main.go", Line: 1, Body: strings.Repeat("é", 2*annotationMessageLimit), Side: SideRight}},
+	}
+
+	ref := &github.PRReference{Owner: "o", Repo: "r", Number: 1}
+	if err := r.publishCheckRun(ref, &github.PullRequest{}, "abc", result, "COMMENT", nil); err != nil {
+		t.Fatalf("publishCheckRun: %v", err)
+	}
+	summary := stub.output.Summary
+	if !utf8.ValidString(summary) || utf8.RuneCountInString(summary) > checkRunSummaryLimit {
+		t.Errorf("summary is %d runes (valid UTF-8: %t), want at most %d", utf8.RuneCountInString(summary), utf8.ValidString(summary), checkRunSummaryLimit)
+	}
+	if len(stub.output.Annotations) != 1 {
+		t.Fatalf("got %d annotations, want 1", len(stub.output.Annotations))
+	}
+	if n := len(stub.output.Annotations[0].Message); n > 64*1024 {
+		t.Errorf("annotation message is %d bytes, want at most 64 KB", n)
+	}
+}
@@ -0,0 +1,81 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// withStyle returns a copy of the reviewer that writes in another style. The
+// copy shares the clients, so token usage still counts against one budget.
+func (r *Reviewer) withStyle(style config.WritingStyle) *Reviewer {
+	cfg := *r.config
+	cfg.WritingStyle = style
+	styled := *r
+	styled.config = &cfg
+	return &styled
+}
+
+// forRepo returns a copy of the reviewer using the config for owner/repo, or
+// r itself if the repo has no overrides
+func (r *Reviewer) forRepo(owner, repo string) *Reviewer {
+	cfg := r.config.ForRepo(owner, repo)
+	if cfg == r.config {
+		return r
+	}
+	scoped := *r
+	scoped.config = cfg
+	return &scoped
+}
+
+// warnStyle reports a custom style that couldn't be read, once the config
+// for the repo is known
+func (r *Reviewer) warnStyle() {
+	if warning := r.config.StyleWarning(); warning != "" {
+		r.out.Warnf("⚠️  %s", warning)
+	}
+}
+
+// compareStyles formats the already-analyzed findings once per style and
+// prints the versions next to each other. Nothing is posted.
+func (r *Reviewer) compareStyles(result *ReviewResult, pr *github.PullRequest, event string, styles []config.WritingStyle) {
+	fmt.Fprintf(r.out, "🎭 Formatting %d finding(s) in %d styles...\n", len(result.confirmed), len(styles))
+
+	// comments[i][j] is finding i in style j
+	comments := make([][]string, len(result.confirmed))
+	for i := range comments {
+		comments[i] = make([]string, len(styles))
+	}
+	summaries := make([]string, len(styles))
+
+	for j, style := range styles {
+		styled := r.withStyle(style)
+		for i, ci := range result.confirmed {
+			comment, err := styled.formatComment(ci)
+			if err != nil {
+				comment = fmt.Sprintf("(failed to format: %v)", err)
+			}
+			comments[i][j] = redactSecrets(comment, result.secrets)
+		}
+		summaries[j] = styled.generateSummary(result, pr, event)
+	}
+
+	rule := strings.Repeat("─", 41)
+	w := r.out.Result()
+	fmt.Fprintln(w, "\n📋 STYLE COMPARISON - nothing will be posted")
+	for i, ci := range result.confirmed {
+		fmt.Fprintln(w, rule)
+		fmt.Fprintf(w, "📍 %s:%d - %s\n", ci.Original.File, ci.Original.Line, redactSecrets(ci.Original.Issue, result.secrets))
+		for j, style := range styles {
+			fmt.Fprintf(w, "\n[%s]\n%s\n", style, comments[i][j])
+		}
+	}
+	fmt.Fprintln(w, rule)
+	fmt.Fprintln(w, "📝 Summaries")
+	for j, style := range styles {
+		fmt.Fprintf(w, "\n[%s]\n%s\n", style, summaries[j])
+	}
+	fmt.Fprintln(w, rule)
+}
@@ -0,0 +1,67 @@
+package reviewer
+
+import (
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+const conflictMarkerComment = "🚨 **Critical: unresolved merge conflict markers.**\n\n" +
+	"This file contains leftover `<<<<<<<` / `=======` / `>>>>>>>` markers from a merge or rebase. " +
+	"It will not compile (or worse, it will). Please resolve the conflict before anything else in this review matters."
+
+// ConflictMarker is an added line that starts an unresolved merge conflict
+type ConflictMarker struct {
+	File string
+	Line int
+}
+
+// FindConflictMarkers scans the added lines of each patch for merge conflict
+// markers. One marker is reported per conflict block, anchored at its
+// opening <<<<<<< line (or at the first stray marker if the opening line
+// isn't part of the diff).
+func FindConflictMarkers(files []*github.FileChange) []ConflictMarker {
+	var markers []ConflictMarker
+
+	for _, f := range files {
+		hunks, err := diff.ParsePatch(f.Patch)
+		if err != nil {
+			continue
+		}
+
+		inConflict := false
+		for _, line := range diff.Lines(hunks) {
+			if line.Kind != diff.Added {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line.Content, "<<<<<<<"):
+				markers = append(markers, ConflictMarker{File: f.Filename, Line: line.NewLine})
+				inConflict = true
+			case strings.HasPrefix(line.Content, ">>>>>>>"):
+				if !inConflict {
+					markers = append(markers, ConflictMarker{File: f.Filename, Line: line.NewLine})
+				}
+				inConflict = false
+			}
+		}
+	}
+
+	return markers
+}
+
+// conflictComments turns conflict markers into review comments without involving the AI
+func conflictComments(markers []ConflictMarker) []*github.ReviewComment {
+	comments := make([]*github.ReviewComment, 0, len(markers))
+	for _, m := range markers {
+		comments = append(comments, &github.ReviewComment{
+			Path:     m.File,
+			Line:     m.Line,
+			Body:     conflictMarkerComment,
+			Side:     SideRight,
+			Severity: string(SeverityCritical),
+		})
+	}
+	return comments
+}
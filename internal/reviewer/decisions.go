@@ -0,0 +1,79 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Decision outcomes for a first-pass issue
+const (
+	OutcomeCommented      = "commented"        // Made it into the review
+	OutcomeBelowThreshold = "below_threshold"  // Confidence or verdict didn't pass
+	OutcomeAnalysisFailed = "analysis_failed"  // Deep analysis errored
+	OutcomeFormatFailed   = "format_failed"    // Confirmed, but formatting the comment errored
+	OutcomeBudgetExceeded = "budget_exceeded"  // Never reached before the token budget ran out
+	OutcomeLineNotInDiff  = "line_not_in_diff" // Its line isn't in the diff and its code couldn't be found
+	OutcomeDroppedByCap   = "dropped_by_cap"   // Formatted, but max_comments_per_file or max_comments left it out
+	OutcomeSkippedByUser  = "skipped_by_user"  // Formatted, but left out when asked with --interactive
+	OutcomeBelowSeverity  = "below_severity"   // Confirmed, but less severe than --min-severity
+	OutcomeMerged         = "merged"           // Posted as part of another comment on the same line
+	OutcomeNotInResponse  = "not_in_response"  // Streamed early, but not in the full first-pass response
+)
+
+// Decision records what happened to one first-pass issue and why
+type Decision struct {
+	Commit              string `json:"commit,omitempty"` // Set in per-commit mode
+	File                string `json:"file"`
+	Line                int    `json:"line"`
+	Issue               string `json:"issue"`
+	FirstPassConfidence int    `json:"first_pass_confidence"` // 1-10
+	DeepAnalyzed        bool   `json:"deep_analyzed"`
+	Confidence          int    `json:"confidence,omitempty"` // 0-100, from deep analysis (or first pass in fast mode)
+	Verdict             string `json:"verdict,omitempty"`
+	Threshold           int    `json:"threshold"`
+	PassedThreshold     bool   `json:"passed_threshold"`
+	Severity            string `json:"severity,omitempty"`
+	Outcome             string `json:"outcome"`
+	Error               string `json:"error,omitempty"`
+}
+
+// newDecisions starts a decision for every first-pass issue. Issues are
+// assumed to run out of budget until something else is recorded.
+func newDecisions(issues []Issue, threshold int) []Decision {
+	decisions := make([]Decision, len(issues))
+	for i, issue := range issues {
+		decisions[i] = Decision{
+			File:                issue.File,
+			Line:                issue.Line,
+			Issue:               issue.Issue,
+			FirstPassConfidence: issue.Confidence,
+			Threshold:           threshold,
+			Outcome:             OutcomeBudgetExceeded,
+		}
+	}
+	return decisions
+}
+
+// WriteDecisionLog writes the decisions of a review as JSON
+func WriteDecisionLog(path string, prRef string, result *ReviewResult) error {
+	log := struct {
+		PR        string     `json:"pr"`
+		Decisions []Decision `json:"decisions"`
+	}{
+		PR:        prRef,
+		Decisions: result.Decisions,
+	}
+	if log.Decisions == nil {
+		log.Decisions = []Decision{}
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode decision log: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("could not write decision log: %w", err)
+	}
+	return nil
+}
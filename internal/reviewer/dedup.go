@@ -0,0 +1,72 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// mergeSeparator goes between the bodies of comments merged into one
+const mergeSeparator = "\n\n---\n\n"
+
+// mergeSameLineComments folds comments anchored on the same line into one,
+// since GitHub stacks them on top of each other. The most severe comment
+// keeps its place and the others' bodies are added below it, minus their
+// suggestion blocks, which were written for their own line range. The
+// findings of comments merged away are dropped so counts match what's posted.
+func (r *Reviewer) mergeSameLineComments(result *ReviewResult) {
+	type anchor struct {
+		path, side string
+		line       int
+	}
+
+	first := make(map[anchor]int) // index in merged of the comment kept
+	var merged []*github.ReviewComment
+	var extra [][]string // bodies added to merged[i]
+	for _, c := range result.Comments {
+		side := strings.ToUpper(c.Side)
+		if side == "" {
+			side = SideRight
+		}
+		key := anchor{c.Path, side, c.Line}
+		i, ok := first[key]
+		if !ok {
+			first[key] = len(merged)
+			merged = append(merged, c)
+			extra = append(extra, nil)
+			continue
+		}
+
+		kept := merged[i]
+		if Severity(c.Severity).Rank() > Severity(kept.Severity).Rank() {
+			merged[i], c = c, kept
+			// The old lead's body goes first, then the ones merged into it before
+			extra[i] = append([]string{stripSuggestion(c.Body)}, extra[i]...)
+		} else {
+			extra[i] = append(extra[i], stripSuggestion(c.Body))
+		}
+		dropFinding(result, c, OutcomeMerged)
+		result.Stats.CommentsMerged++
+	}
+
+	for i, bodies := range extra {
+		if len(bodies) > 0 {
+			merged[i].Body = strings.Join(append([]string{merged[i].Body}, bodies...), mergeSeparator)
+		}
+	}
+	result.Comments = merged
+
+	if result.Stats.CommentsMerged > 0 {
+		fmt.Fprintf(r.out, "🧷 Merged %d comment(s) into others on the same line\n", result.Stats.CommentsMerged)
+	}
+}
+
+// stripSuggestion removes the suggestion block formatComment adds at the end
+// of a comment
+func stripSuggestion(body string) string {
+	if i := strings.LastIndex(body, "\n\n```suggestion\n"); i >= 0 && strings.HasSuffix(body, "\n```") {
+		return body[:i]
+	}
+	return body
+}
@@ -0,0 +1,55 @@
+package reviewer
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/logging"
+)
+
+func TestMergeSameLineComments(t *testing.T) {
+	r := &Reviewer{out: logging.New(io.Discard, config.VerbosityNormal)}
+	minor := &github.ReviewComment{Path: "main.go", Line: 7, Body: "minor body\n\n```suggestion\nx := 1\n```", Severity: string(SeverityMinor)}
+	major := &github.ReviewComment{Path: "main.go", Line: 7, Body: "major body", Severity: string(SeverityMajor)}
+	other := &github.ReviewComment{Path: "main.go", Line: 9, Body: "other body", Severity: string(SeverityNit)}
+	result := &ReviewResult{
+		Comments: []*github.ReviewComment{minor, major, other},
+		Findings: []AnalyzedIssue{
+			{Original: Issue{File: "main.go", Line: 7}, Severity: SeverityMinor},
+			{Original: Issue{File: "main.go", Line: 7}, Severity: SeverityMajor},
+			{Original: Issue{File: "main.go", Line: 9}, Severity: SeverityNit},
+		},
+		Decisions: []Decision{
+			{File: "main.go", Line: 7, Severity: string(SeverityMinor), Outcome: OutcomeCommented},
+			{File: "main.go", Line: 7, Severity: string(SeverityMajor), Outcome: OutcomeCommented},
+			{File: "main.go", Line: 9, Severity: string(SeverityNit), Outcome: OutcomeCommented},
+		},
+	}
+
+	r.mergeSameLineComments(result)
+
+	if len(result.Comments) != 2 || result.Comments[0] != major || result.Comments[1] != other {
+		t.Fatalf("comments = %+v, want the major one then the line 9 one", result.Comments)
+	}
+	if want := "major body" + mergeSeparator + "minor body"; major.Body != want {
+		t.Errorf("merged body = %q, want %q", major.Body, want)
+	}
+	if strings.Contains(major.Body, "suggestion") {
+		t.Error("merged body kept the minor comment's suggestion")
+	}
+	if len(result.Findings) != 2 || result.Findings[0].Severity != SeverityMajor {
+		t.Errorf("findings = %+v, want the major and the line 9 ones", result.Findings)
+	}
+	if got := result.Decisions[0].Outcome; got != OutcomeMerged {
+		t.Errorf("minor decision outcome = %q, want %q", got, OutcomeMerged)
+	}
+	if got := result.Decisions[1].Outcome; got != OutcomeCommented {
+		t.Errorf("major decision outcome = %q, want %q", got, OutcomeCommented)
+	}
+	if result.Stats.CommentsMerged != 1 {
+		t.Errorf("CommentsMerged = %d, want 1", result.Stats.CommentsMerged)
+	}
+}
@@ -1,42 +1,82 @@
 package reviewer
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/config"
 	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/jsonx"
+	"github.com/user/salty-reviewer/internal/logging"
 )
 
 // Issue represents a potential issue found in the first pass
 type Issue struct {
-	File              string `json:"file"`
-	Line              int    `json:"line"`
-	Code              string `json:"code"`
-	Issue             string `json:"issue"`
-	Confidence        int    `json:"confidence"`
+	File               string `json:"file"`
+	Line               int    `json:"line"`
+	Code               string `json:"code"`
+	Issue              string `json:"issue"`
+	Confidence         int    `json:"confidence"`
 	MightBeIntentional string `json:"might_be_intentional"`
+	Side               string `json:"side,omitempty"` // LEFT when the issue is about removed code
+
+	// EndLine is the last line of an issue spanning several lines, from Line
+	// on; 0 for a single line
+	EndLine int `json:"end_line,omitempty"`
+
+	// SuggestedReplacement is corrected code for the issue's lines, for
+	// simple fixes; posted as a GitHub suggestion
+	SuggestedReplacement string `json:"suggested_replacement,omitempty"`
+
+	// Kind of issue, e.g. bug or security; secret and todo for salty's own checks
+	Category string `json:"category,omitempty"`
+
+	// How much the issue matters, as the first pass sees it: critical,
+	// major, minor or nit
+	Severity string `json:"severity,omitempty"`
 }
 
 // FirstPassResult is the result of initial issue scanning
 type FirstPassResult struct {
-	Issues []Issue `json:"issues"`
+	Issues    []Issue  `json:"issues"`
+	IssueGaps []string `json:"issue_gaps,omitempty"` // Linked-issue requirements the PR seems to miss
+
+	Batches int `json:"-"` // AI calls the diff was split into
+}
+
+// FirstPassContext is optional background given to the first pass alongside the diff
+type FirstPassContext struct {
+	LinkedIssues []*github.Issue
+	NewCodeOnly  bool   // Moved code was stripped from the diff
+	Guidelines   string // The project's contributing guidelines, if any
+
+	part, parts int // This call's batch of the diff, when it is split
 }
 
 // DeepAnalysisResult is the result of analyzing a specific issue
 type DeepAnalysisResult struct {
-	StillAnIssue        bool   `json:"still_an_issue"`
-	Confidence          int    `json:"confidence"`
-	Reasoning           string `json:"reasoning"`
+	StillAnIssue         bool   `json:"still_an_issue"`
+	Confidence           int    `json:"confidence"` // 0-100, like the threshold it is compared to
+	Reasoning            string `json:"reasoning"`
 	PossibleAuthorIntent string `json:"possible_author_intent"`
-	FinalVerdict        string `json:"final_verdict"`
+	FinalVerdict         string `json:"final_verdict"`
+	Severity             string `json:"severity,omitempty"`
+
+	// Replaces the first pass's suggested replacement when set
+	SuggestedReplacement string `json:"suggested_replacement,omitempty"`
 }
 
 // AnalyzedIssue combines the original issue with deep analysis
 type AnalyzedIssue struct {
 	Original Issue
 	Analysis DeepAnalysisResult
+	Severity Severity // "" when unknown
+
+	index int // Position in the first-pass issues, for the decision log
 }
 
 // NitpickResult holds extra nitpicks for disliked reviewers
@@ -52,87 +92,188 @@ type NitpickResult struct {
 type Analyzer struct {
 	aiClient     *ai.Client
 	githubClient *github.Client
+
+	// Caps on related-file context in deep analysis (0 = no limit)
+	maxRelatedFiles int
+	maxRelatedBytes int
+
+	// Estimated token size above which the first pass is split (0 = never)
+	maxFirstPassTokens int
+
+	// Where responses that don't parse are dumped when verbose, may be nil
+	out *logging.Logger
 }
 
 // NewAnalyzer creates a new deep analyzer
-func NewAnalyzer(aiClient *ai.Client, githubClient *github.Client) *Analyzer {
+func NewAnalyzer(aiClient *ai.Client, githubClient *github.Client, cfg *config.Config) *Analyzer {
 	return &Analyzer{
-		aiClient:     aiClient,
-		githubClient: githubClient,
+		aiClient:        aiClient,
+		githubClient:    githubClient,
+		maxRelatedFiles: cfg.MaxRelatedFiles,
+		maxRelatedBytes: cfg.MaxRelatedBytes,
+
+		maxFirstPassTokens: cfg.MaxFirstPassTokens,
 	}
 }
 
-// FirstPass identifies potential issues in the diff
-func (a *Analyzer) FirstPass(files []*github.FileChange) (*FirstPassResult, error) {
+// FirstPass identifies potential issues in the diff. A diff too big for one
+// prompt is split by file into batches whose results are merged.
+func (a *Analyzer) FirstPass(files []*github.FileChange, fpc FirstPassContext) (*FirstPassResult, error) {
+	batches := firstPassBatches(files, fpc, a.maxFirstPassTokens)
+	results := make([]*FirstPassResult, 0, len(batches))
+	for i, batch := range batches {
+		response, err := a.aiClient.ChatJSON(firstPassMessages(batch, fpc.batch(i, len(batches))))
+		if err != nil {
+			return nil, fmt.Errorf("AI first pass failed: %w", err)
+		}
+		result, err := jsonx.Parse[FirstPassResult](response, "first pass result", a.out)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return mergeFirstPass(results), nil
+}
+
+// firstPassMessages builds the first-pass prompt from the diff and its context
+func firstPassMessages(files []*github.FileChange, fpc FirstPassContext) []ai.Message {
 	// Combine all diffs into one for the first pass
 	var diffBuilder strings.Builder
+	for _, issue := range fpc.LinkedIssues {
+		diffBuilder.WriteString(fmt.Sprintf("\n=== LINKED ISSUE %s/%s#%d: %s ===\n%s\n",
+			issue.Owner, issue.Repo, issue.Number, issue.Title, issue.Body))
+	}
+	if fpc.parts > 1 {
+		diffBuilder.WriteString(fmt.Sprintf("\n(This is part %d of %d of the diff; the other files are reviewed separately.)\n",
+			fpc.part, fpc.parts))
+	}
 	for _, f := range files {
-		diffBuilder.WriteString(fmt.Sprintf("\n--- %s ---\n", f.Filename))
-		diffBuilder.WriteString(f.Patch)
-		diffBuilder.WriteString("\n")
+		diffBuilder.WriteString(fileSection(f))
 	}
 
-	messages := []ai.Message{
-		ai.SystemMessage(GetFirstPassPrompt()),
-		ai.UserMessage(diffBuilder.String()),
+	systemPrompt := GetFirstPassPrompt()
+	if len(fpc.LinkedIssues) > 0 {
+		systemPrompt += "\n\n" + GetLinkedIssuePrompt()
 	}
-
-	response, err := a.aiClient.Chat(messages)
-	if err != nil {
-		return nil, fmt.Errorf("AI first pass failed: %w", err)
+	if fpc.NewCodeOnly {
+		systemPrompt += "\n\n" + GetNewCodeOnlyPrompt()
+	}
+	if fpc.Guidelines != "" {
+		systemPrompt += "\n\n" + GetGuidelinesPrompt(fpc.Guidelines)
 	}
 
-	// Parse JSON response
-	response = extractJSON(response)
-	var result FirstPassResult
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse first pass result: %w (response: %s)", err, response)
+	return []ai.Message{
+		ai.SystemMessage(systemPrompt),
+		ai.UserMessage(diffBuilder.String()),
 	}
+}
+
+// errNoRepository stands in for file fetches in local diff reviews
+var errNoRepository = errors.New("no repository to fetch from")
 
-	return &result, nil
+// stopsAnalysis reports whether a failed fetch means every other fetch will
+// fail too, so analyzing without the content would only waste tokens
+func stopsAnalysis(err error) bool {
+	return errors.Is(err, github.ErrBadCredentials) || errors.Is(err, github.ErrRateLimited)
 }
 
-// DeepAnalyze performs deep analysis on a specific issue
-func (a *Analyzer) DeepAnalyze(issue Issue, ref *github.PRReference, pr *github.PullRequest) (*DeepAnalysisResult, error) {
-	// Get full file content
-	fullContent, err := a.githubClient.GetFileContent(ref.Owner, ref.Repo, issue.File, pr.GetHead().GetSHA())
+// DeepAnalyze performs deep analysis on a specific issue, reading file context at
+// the given commit. The changed file's patch is used as a fallback when the full
+// content can't be included. file may be nil if the issue's file isn't in the diff,
+// and ref is nil when reviewing a local diff. The redactions, secrets found in
+// the diff, are stripped from fetched file content before it goes into the prompt.
+func (a *Analyzer) DeepAnalyze(issue Issue, ref *github.PRReference, sha string, file *github.FileChange, redactions []string) (*DeepAnalysisResult, error) {
+	var patch, previousName string
+	if file != nil {
+		patch, previousName = file.Patch, file.PreviousName
+	}
+
+	// Get full file content. Local diff reviews (nil ref) only have the patch.
+	fullContent, err := "", errNoRepository
+	if ref != nil {
+		fullContent, err = a.githubClient.GetFileContent(ref.Owner, ref.Repo, issue.File, sha)
+	}
+	if stopsAnalysis(err) {
+		return nil, err
+	}
 	if err != nil {
 		// If we can't get the file, still try with available info
-		fullContent = "(File content unavailable)"
+		switch {
+		case errors.Is(err, github.ErrFileTooLarge) && patch != "":
+			fullContent = "(File too large to include in full - only the diff is available)\n" + patch
+		case patch != "":
+			fullContent = "(File content unavailable - only the diff is available)\n" + patch
+		default:
+			fullContent = "(File content unavailable)"
+		}
 	}
 
-	// Get related files
-	related, _ := a.githubClient.GetRelatedFiles(ref.Owner, ref.Repo, issue.File, pr.GetHead().GetSHA())
+	// Get related files, most relevant (same-basename tests) first
+	var related []string
+	if ref != nil {
+		related, err = a.githubClient.GetRelatedFiles(ref.Owner, ref.Repo, issue.File, previousName, sha)
+		if stopsAnalysis(err) {
+			return nil, err
+		}
+	}
 	var relatedContent strings.Builder
+	included, remaining := 0, a.maxRelatedBytes
 	for _, r := range related {
-		content, err := a.githubClient.GetFileContent(ref.Owner, ref.Repo, r, pr.GetHead().GetSHA())
-		if err == nil {
-			relatedContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", r, content))
+		if a.maxRelatedFiles > 0 && included == a.maxRelatedFiles {
+			break
 		}
+		if a.maxRelatedBytes > 0 && remaining <= 0 {
+			break
+		}
+		content, err := a.githubClient.GetFileContent(ref.Owner, ref.Repo, r, sha)
+		if stopsAnalysis(err) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		if a.maxRelatedBytes > 0 && len(content) > remaining {
+			content = cutAtRune(content, remaining) + "\n... (truncated)"
+		}
+		remaining -= len(content)
+		included++
+		relatedContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", r, content))
 	}
 
-	issueDesc := fmt.Sprintf("File: %s, Line: %d\nCode: %s\nIssue: %s",
-		issue.File, issue.Line, issue.Code, issue.Issue)
+	lines := strconv.Itoa(issue.Line)
+	if issue.EndLine > issue.Line {
+		lines = fmt.Sprintf("%d-%d", issue.Line, issue.EndLine)
+	}
+	issueDesc := fmt.Sprintf("File: %s, Line: %s\nCode: %s\nIssue: %s",
+		issue.File, lines, issue.Code, issue.Issue)
+	if issue.Severity != "" {
+		issueDesc += "\nSeverity: " + issue.Severity
+	}
+	if issue.SuggestedReplacement != "" {
+		issueDesc += "\nSuggested replacement:\n" + issue.SuggestedReplacement
+	}
 
-	prompt := GetDeepAnalysisPrompt(issueDesc, fullContent, relatedContent.String())
+	prompt := GetDeepAnalysisPrompt(issueDesc,
+		redactSecrets(fullContent, redactions), redactSecrets(relatedContent.String(), redactions))
 
 	messages := []ai.Message{
 		ai.SystemMessage("You are a thoughtful code reviewer who considers context before judging."),
 		ai.UserMessage(prompt),
 	}
 
-	response, err := a.aiClient.Chat(messages)
+	response, err := a.aiClient.ChatJSON(messages)
 	if err != nil {
 		return nil, fmt.Errorf("AI deep analysis failed: %w", err)
 	}
 
-	response = extractJSON(response)
-	var result DeepAnalysisResult
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse deep analysis: %w", err)
+	result, err := jsonx.Parse[DeepAnalysisResult](response, "deep analysis", a.out)
+	if err != nil {
+		return nil, err
 	}
+	result.Confidence = min(max(result.Confidence, 0), 100)
 
-	return &result, nil
+	return result, nil
 }
 
 // GenerateExtraNitpicks creates additional nitpicky comments
@@ -150,29 +291,22 @@ func (a *Analyzer) GenerateExtraNitpicks(files []*github.FileChange, existingCom
 		ai.UserMessage(prompt),
 	}
 
-	response, err := a.aiClient.Chat(messages)
+	response, err := a.aiClient.ChatJSON(messages)
 	if err != nil {
 		return nil, fmt.Errorf("AI nitpick generation failed: %w", err)
 	}
 
-	response = extractJSON(response)
-	var result NitpickResult
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse nitpicks: %w", err)
-	}
-
-	return &result, nil
+	return jsonx.Parse[NitpickResult](response, "nitpicks", a.out)
 }
 
-// extractJSON tries to extract JSON from a response that might have extra text
-func extractJSON(response string) string {
-	// Find the first { and last }
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start != -1 && end != -1 && end > start {
-		return response[start : end+1]
+// cutAtRune cuts s to at most n bytes, backing up to the start of a rune so
+// a multi-byte character isn't split
+func cutAtRune(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
-
-	return response
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
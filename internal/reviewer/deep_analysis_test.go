@@ -0,0 +1,23 @@
+package reviewer
+
+import "testing"
+
+func TestCutAtRune(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"}, // é is two bytes, the cut would split it
+		{"héllo", 3, "hé"},
+		{"🧂🧂", 5, "🧂"},
+		{"🧂", 2, ""},
+	}
+	for _, tt := range tests {
+		if got := cutAtRune(tt.s, tt.n); got != tt.want {
+			t.Errorf("cutAtRune(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
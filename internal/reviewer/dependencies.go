@@ -0,0 +1,271 @@
+package reviewer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// DependencyChange is a dependency added, removed or re-versioned by the PR
+type DependencyChange struct {
+	File      string
+	Name      string
+	From      string // "" when the dependency was added
+	To        string // "" when the dependency was removed
+	MajorBump bool   // Version change that may break compatibility
+	Untrusted bool   // Added from a source outside trusted_dependency_sources
+}
+
+// dependencyFiles are lockfiles, which are generated, and the manifests the
+// dependency note covers. Neither is worth reviewing line by line.
+var dependencyFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"go.mod":            true,
+	"package.json":      true,
+}
+
+var (
+	goModRequire   = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v\d\S*)\s*(?://.*)?$`)
+	packageJSONDep = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([^"]*)"\s*,?\s*$`)
+	packageSection = regexp.MustCompile(`^\s*"(\w+)"\s*:\s*\{`)
+
+	// githubShorthand is npm's "user/repo#ref" form of a GitHub dependency
+	githubShorthand = regexp.MustCompile(`^[\w.-]+/[\w.-]+(#\S*)?$`)
+
+	// goMajorSuffix is the major version a Go module path ends in from v2 on,
+	// like example.com/mod/v2 or gopkg.in/yaml.v3
+	goMajorSuffix = regexp.MustCompile(`[/.]v\d+$`)
+)
+
+func isDependencyFile(filename string) bool {
+	return dependencyFiles[path.Base(filename)]
+}
+
+// analyzeDependencies lists the dependency changes in go.mod and package.json
+// patches. trusted lists allowed source prefixes (module paths, npm scopes);
+// when it's empty only non-registry npm sources count as untrusted.
+func analyzeDependencies(files []*github.FileChange, trusted []string) []DependencyChange {
+	var changes []DependencyChange
+	for _, f := range files {
+		var removed, added map[string]string
+		switch path.Base(f.Filename) {
+		case "go.mod":
+			removed, added = parseGoModPatch(f.Patch)
+			matchMajorVersionPaths(removed, added)
+		case "package.json":
+			removed, added = parsePackageJSONPatch(f.Patch)
+		default:
+			continue
+		}
+		changes = append(changes, compareDependencies(f.Filename, removed, added, trusted)...)
+	}
+	return changes
+}
+
+// parseGoModPatch collects the require lines removed and added by a go.mod patch
+func parseGoModPatch(patch string) (removed, added map[string]string) {
+	return collectDependencyLines(patch, func(content string, _ string) (string, string, bool) {
+		m := goModRequire.FindStringSubmatch(content)
+		if m == nil || m[1] == "module" || m[1] == "go" || m[1] == "toolchain" {
+			return "", "", false
+		}
+		return m[1], m[2], true
+	})
+}
+
+// matchMajorVersionPaths re-keys a removed Go module under the path it was
+// added back as, when only the major version suffix of the path changed
+// (example.com/mod to example.com/mod/v2), so it reads as a version bump
+func matchMajorVersionPaths(removed, added map[string]string) {
+	for name := range added {
+		if _, ok := removed[name]; ok {
+			continue
+		}
+		base := goMajorSuffix.ReplaceAllString(name, "")
+		for old, version := range removed {
+			if _, kept := added[old]; kept || old == name || goMajorSuffix.ReplaceAllString(old, "") != base {
+				continue
+			}
+			delete(removed, old)
+			removed[name] = version
+			break
+		}
+	}
+}
+
+// parsePackageJSONPatch collects the dependency entries removed and added by a
+// package.json patch. Entries are only taken from *dependencies sections when the
+// section is visible in the hunk, and must look like a version spec otherwise.
+func parsePackageJSONPatch(patch string) (removed, added map[string]string) {
+	return collectDependencyLines(patch, func(content string, section string) (string, string, bool) {
+		if section != "" && !strings.HasSuffix(strings.ToLower(section), "dependencies") {
+			return "", "", false
+		}
+		m := packageJSONDep.FindStringSubmatch(content)
+		if m == nil || !looksLikeVersionSpec(m[2]) {
+			return "", "", false
+		}
+		return m[1], m[2], true
+	})
+}
+
+// collectDependencyLines runs parse over the removed and added lines of a patch.
+// section is the enclosing JSON object key, if the hunk shows it.
+func collectDependencyLines(patch string, parse func(content, section string) (string, string, bool)) (removed, added map[string]string) {
+	removed, added = make(map[string]string), make(map[string]string)
+
+	hunks, err := diff.ParsePatch(patch)
+	if err != nil {
+		return removed, added
+	}
+
+	for _, h := range hunks {
+		section := ""
+		for _, line := range h.Lines {
+			if m := packageSection.FindStringSubmatch(line.Content); m != nil {
+				section = m[1]
+				continue
+			}
+			if strings.TrimSpace(line.Content) == "}" || strings.TrimSpace(line.Content) == "}," {
+				section = ""
+				continue
+			}
+
+			name, version, ok := parse(line.Content, section)
+			if !ok {
+				continue
+			}
+			switch line.Kind {
+			case diff.Removed:
+				removed[name] = version
+			case diff.Added:
+				added[name] = version
+			}
+		}
+	}
+
+	return removed, added
+}
+
+func looksLikeVersionSpec(spec string) bool {
+	if spec == "" {
+		return false
+	}
+	if spec == "*" || spec == "latest" || isNonRegistrySpec(spec) ||
+		strings.HasPrefix(spec, "npm:") || strings.HasPrefix(spec, "workspace:") {
+		return true
+	}
+	return strings.ContainsAny(spec[:1], "0123456789^~<>=v")
+}
+
+// isNonRegistrySpec reports whether an npm version spec pulls from somewhere
+// other than the registry (git, a URL or the local filesystem)
+func isNonRegistrySpec(spec string) bool {
+	for _, prefix := range []string{"git", "http:", "https:", "file:", "link:"} {
+		if strings.HasPrefix(spec, prefix) {
+			return true
+		}
+	}
+	return githubShorthand.MatchString(spec)
+}
+
+func compareDependencies(file string, removed, added map[string]string, trusted []string) []DependencyChange {
+	var changes []DependencyChange
+
+	for name, to := range added {
+		from, existed := removed[name]
+		if existed && from == to {
+			continue // Line moved or reformatted
+		}
+		change := DependencyChange{File: file, Name: name, From: from, To: to}
+		if existed {
+			change.MajorBump = isMajorBump(from, to)
+		} else {
+			change.Untrusted = isUntrustedSource(name, to, trusted)
+		}
+		changes = append(changes, change)
+	}
+	for name, from := range removed {
+		if _, ok := added[name]; !ok {
+			changes = append(changes, DependencyChange{File: file, Name: name, From: from})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+func isUntrustedSource(name, version string, trusted []string) bool {
+	if isNonRegistrySpec(version) {
+		return true
+	}
+	if len(trusted) == 0 {
+		return false
+	}
+	for _, prefix := range trusted {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isMajorBump compares the leading version numbers of two specs. For 0.x
+// versions a minor bump counts as major, following semver.
+func isMajorBump(from, to string) bool {
+	fromMajor, fromMinor, ok1 := leadingVersion(from)
+	toMajor, toMinor, ok2 := leadingVersion(to)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if fromMajor != toMajor {
+		return true
+	}
+	return fromMajor == 0 && fromMinor != toMinor
+}
+
+func leadingVersion(spec string) (major, minor int, ok bool) {
+	spec = strings.TrimLeft(spec, "v^~<>= ")
+	parts := strings.SplitN(spec, ".", 3)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor, true
+}
+
+// writeDependencyNote adds the dependency summary to a review body
+func writeDependencyNote(sb *strings.Builder, changes []DependencyChange) {
+	sb.WriteString("### 📦 Dependencies\n\n")
+	for _, c := range changes {
+		var line string
+		switch {
+		case c.From == "":
+			line = fmt.Sprintf("- ➕ `%s` %s", c.Name, c.To)
+		case c.To == "":
+			line = fmt.Sprintf("- ➖ `%s` %s", c.Name, c.From)
+		default:
+			line = fmt.Sprintf("- 🔼 `%s` %s → %s", c.Name, c.From, c.To)
+		}
+		if c.MajorBump {
+			line += " ⚠️ **major version bump** - check the changelog for breaking changes"
+		}
+		if c.Untrusted {
+			line += " ⚠️ **untrusted source**"
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,45 @@
+package reviewer
+
+import (
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+func TestAnalyzeDependenciesGoMajorPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		wantName  string
+		wantFrom  string
+		wantMajor bool
+	}{
+		{"path suffix", "@@ -3,3 +3,3 @@ require (\n" +
+			" \tgolang.org/x/oauth2 v0.15.0\n" +
+			"-\tgithub.com/google/go-github/v56 v56.0.0\n" +
+			"+\tgithub.com/google/go-github/v57 v57.0.0\n" +
+			" )\n", "github.com/google/go-github/v57", "v56.0.0", true},
+		{"v1 to v2", "@@ -3,2 +3,2 @@ require (\n" +
+			"-\texample.com/mod v1.4.0\n" +
+			"+\texample.com/mod/v2 v2.0.0\n", "example.com/mod/v2", "v1.4.0", true},
+		{"gopkg.in", "@@ -3,2 +3,2 @@ require (\n" +
+			"-\tgopkg.in/yaml.v2 v2.4.0\n" +
+			"+\tgopkg.in/yaml.v3 v3.0.1\n", "gopkg.in/yaml.v3", "v2.4.0", true},
+		{"minor bump", "@@ -3,2 +3,2 @@ require (\n" +
+			"-\texample.com/mod v1.4.0\n" +
+			"+\texample.com/mod v1.5.0\n", "example.com/mod", "v1.4.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := analyzeDependencies([]*github.FileChange{{Filename: "go.mod", Patch: tt.patch}}, nil)
+			if len(changes) != 1 {
+				t.Fatalf("got %d changes, want 1: %+v", len(changes), changes)
+			}
+			c := changes[0]
+			if c.Name != tt.wantName || c.From != tt.wantFrom || c.MajorBump != tt.wantMajor {
+				t.Errorf("got %s from %s (major %t), want %s from %s (major %t)",
+					c.Name, c.From, c.MajorBump, tt.wantName, tt.wantFrom, tt.wantMajor)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package reviewer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/signature"
+)
+
+// digestTitleChars is how much of a comment's first line the digest shows
+const digestTitleChars = 100
+
+// postDigest posts a conversation comment listing every inline comment of
+// the reviews just posted, with a permalink, its severity and its category. The review is
+// already up, so failures are only reported.
+func (r *Reviewer) postDigest(ref *github.PRReference, reviewIDs []int64, result *ReviewResult) {
+	var posted []*github.PRComment
+	for _, id := range reviewIDs {
+		comments, err := r.githubClient.ListReviewComments(ref, id)
+		if err != nil {
+			r.out.Warnf("   ⚠️  Could not build review digest: %v", err)
+			return
+		}
+		posted = append(posted, comments...)
+	}
+	if len(posted) == 0 {
+		return
+	}
+
+	body := signature.Sign(buildDigest(ref, posted, result), signature.ModeDigest, r.config.ReviewSignature)
+	if err := r.githubClient.PostIssueComment(ref, body); err != nil {
+		r.out.Warnf("   ⚠️  Could not post review digest: %v", err)
+		return
+	}
+	fmt.Fprintf(r.out, "🧭 Posted a digest of %d comments\n", len(posted))
+}
+
+// buildDigest lists posted comments by file and line. Severities come from
+// the comments as they were submitted, matched by position, and categories
+// from the findings behind them.
+func buildDigest(ref *github.PRReference, posted []*github.PRComment, result *ReviewResult) string {
+	severities := make(map[string][]string)
+	for _, c := range result.Comments {
+		key := fmt.Sprintf("%s:%d", c.Path, c.Line)
+		severities[key] = append(severities[key], c.Severity)
+	}
+	categories := make(map[string]string, len(result.Findings))
+	for _, f := range result.Findings {
+		categories[fmt.Sprintf("%s:%d:%s", f.Original.File, f.commentLine(), f.Severity)] = f.Original.Category
+	}
+
+	sorted := append([]*github.PRComment(nil), posted...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+
+	var sb strings.Builder
+	sb.WriteString("### 🧭 Review digest\n\n")
+	sb.WriteString(fmt.Sprintf("%s in this review:\n\n", plural(len(sorted), "comment", "comments")))
+	sb.WriteString("| | Where | Severity | Category | Comment |\n|---|---|---|---|---|\n")
+	for i, c := range sorted {
+		key := fmt.Sprintf("%s:%d", c.Path, c.Line)
+		severity, category := "-", "-"
+		if s := severities[key]; len(s) > 0 {
+			if cat := categories[key+":"+s[0]]; cat != "" {
+				category = strings.ReplaceAll(cat, "|", "\\|")
+			}
+			if s[0] != "" {
+				severity = s[0]
+			}
+			severities[key] = s[1:]
+		}
+		sb.WriteString(fmt.Sprintf("| %d | [`%s`](%s) | %s | %s | %s |\n",
+			i+1, key, ref.CommentPermalink(c.ID), severity, category, digestTitle(c.Body)))
+	}
+	return sb.String()
+}
+
+// digestTitle is the first meaningful line of a comment, shortened and made
+// safe for a table cell
+func digestTitle(body string) string {
+	title := ""
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "```") {
+			title = line
+			break
+		}
+	}
+	title = strings.ReplaceAll(title, "|", "\\|")
+	if runes := []rune(title); len(runes) > digestTitleChars {
+		title = string(runes[:digestTitleChars]) + "…"
+	}
+	return title
+}
@@ -0,0 +1,36 @@
+package reviewer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+func TestBuildDigestCategories(t *testing.T) {
+	ref := &github.PRReference{Owner: "o", Repo: "r", Number: 1}
+	result := &ReviewResult{
+		Comments: []*github.ReviewComment{
+			{Path: "main.go", Line: 3, Severity: string(SeverityCritical)},
+			{Path: "main.go", Line: 9, Severity: string(SeverityNit)},
+		},
+		Findings: []AnalyzedIssue{
+			{Original: Issue{File: "main.go", Line: 3, Category: "security"}, Severity: SeverityCritical},
+		},
+	}
+	posted := []*github.PRComment{
+		{ID: 2, Path: "main.go", Line: 9, Body: "Extra nitpick"},
+		{ID: 1, Path: "main.go", Line: 3, Body: "SQL injection"},
+	}
+
+	digest := buildDigest(ref, posted, result)
+	for _, want := range []string{
+		"| Where | Severity | Category | Comment |",
+		"| critical | security | SQL injection |",
+		"| nit | - | Extra nitpick |",
+	} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest is missing %q:\n%s", want, digest)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package reviewer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// maxGuidelinesChars keeps a long contributing guide from crowding out the diff
+const maxGuidelinesChars = 20000
+
+// fetchGuidelines loads guidelines_file from the repository at sha, caching
+// it per repo and commit. A missing or unreadable file just means the review
+// goes ahead without it.
+func (r *Reviewer) fetchGuidelines(ref *github.PRReference, sha string) string {
+	path := r.config.GuidelinesFile
+	if path == "" {
+		return ""
+	}
+
+	key := fmt.Sprintf("%s/%s@%s", ref.Owner, ref.Repo, sha)
+	if content, ok := r.guidelines[key]; ok {
+		return content
+	}
+
+	content, err := r.githubClient.GetFileContent(ref.Owner, ref.Repo, path, sha)
+	if err != nil {
+		fmt.Fprintf(r.out, "📘 No %s in %s/%s - reviewing without project guidelines\n", path, ref.Owner, ref.Repo)
+		content = ""
+	} else {
+		content = trimGuidelines(content)
+		fmt.Fprintf(r.out, "📘 Following the project guidelines in %s\n", path)
+	}
+
+	r.guidelines[key] = content
+	return content
+}
+
+// localGuidelines reads guidelines_file from the working directory, for
+// reviews of a local diff
+func (r *Reviewer) localGuidelines() string {
+	path := r.config.GuidelinesFile
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(r.out, "📘 No %s here - reviewing without project guidelines\n", path)
+		return ""
+	}
+	fmt.Fprintf(r.out, "📘 Following the project guidelines in %s\n", path)
+	return trimGuidelines(string(data))
+}
+
+func trimGuidelines(content string) string {
+	if runes := []rune(content); len(runes) > maxGuidelinesChars {
+		return string(runes[:maxGuidelinesChars]) + "\n\n(guidelines truncated)"
+	}
+	return content
+}
@@ -0,0 +1,81 @@
+package reviewer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// largeImageBytes is the size above which an image is called out as probably
+// unoptimized
+const largeImageBytes = 500 * 1024
+
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".bmp": true, ".ico": true, ".tif": true, ".tiff": true, ".svg": true,
+}
+
+// ImageChange is an added or modified image in the PR
+type ImageChange struct {
+	Filename string
+	Status   string
+	Size     int // Bytes, -1 if it couldn't be fetched
+}
+
+func isImage(filename string) bool {
+	return imageExtensions[strings.ToLower(path.Ext(filename))]
+}
+
+// collectImageChanges finds the added and modified images among files and
+// looks up their sizes at the given commit. Removed images are ignored.
+func (r *Reviewer) collectImageChanges(ref *github.PRReference, sha string, files []*github.FileChange) []ImageChange {
+	var images []ImageChange
+	for _, f := range files {
+		if !isImage(f.Filename) || f.Status == "removed" {
+			continue
+		}
+
+		size, err := r.githubClient.GetFileSize(ref.Owner, ref.Repo, f.Filename, sha)
+		if err != nil {
+			// A rejected token fails every lookup; the review reports it once
+			if !errors.Is(err, github.ErrBadCredentials) {
+				r.out.Warnf("   ⚠️  Couldn't get size of %s: %v", f.Filename, err)
+			}
+			size = -1
+		}
+		images = append(images, ImageChange{Filename: f.Filename, Status: f.Status, Size: size})
+	}
+	return images
+}
+
+// writeImageNote adds the image summary to a review body
+func writeImageNote(sb *strings.Builder, images []ImageChange) {
+	sb.WriteString("### 🖼️ Images\n\n")
+	sb.WriteString("This PR adds or changes the following images. Please confirm they're optimized:\n\n")
+	for _, img := range images {
+		size := "unknown size"
+		if img.Size >= 0 {
+			size = formatBytes(img.Size)
+		}
+		warning := ""
+		if img.Size > largeImageBytes {
+			warning = " ⚠️"
+		}
+		sb.WriteString(fmt.Sprintf("- `%s` (%s, %s)%s\n", img.Filename, img.Status, size, warning))
+	}
+	sb.WriteString("\n")
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,80 @@
+package reviewer
+
+import (
+	"fmt"
+	"io"
+)
+
+// relatedFileProbes is how many candidate test files GetRelatedFiles looks for
+// per analyzed file
+const relatedFileProbes = 5
+
+// ReviewPlan is the projected number of API calls a review would make, worked
+// out after the first pass without running deep analysis or formatting
+type ReviewPlan struct {
+	FirstPassCalls    int
+	DeepAnalysisCalls int
+	FormattingCalls   int // Upper bound: only confirmed issues are formatted
+	NitpickCalls      int
+	ContentFetches    int // Lower bound: each related file found is fetched again
+	ReviewPosts       int
+}
+
+// AICalls is the projected number of AI requests
+func (p *ReviewPlan) AICalls() int {
+	return p.FirstPassCalls + p.DeepAnalysisCalls + p.FormattingCalls + p.NitpickCalls
+}
+
+// GitHubCalls is the projected number of GitHub requests still to come
+func (p *ReviewPlan) GitHubCalls() int {
+	return p.ContentFetches + p.ReviewPosts
+}
+
+// add accumulates another plan into p
+func (p *ReviewPlan) add(other *ReviewPlan) {
+	p.FirstPassCalls += other.FirstPassCalls
+	p.DeepAnalysisCalls += other.DeepAnalysisCalls
+	p.FormattingCalls += other.FormattingCalls
+	p.NitpickCalls += other.NitpickCalls
+	p.ContentFetches += other.ContentFetches
+	p.ReviewPosts += other.ReviewPosts
+}
+
+// print writes the plan in the same shape as the review progress output
+func (p *ReviewPlan) print(w io.Writer) {
+	fmt.Fprintln(w, "\n🗺️  Review plan:")
+	fmt.Fprintf(w, "   AI calls:     %d\n", p.AICalls())
+	fmt.Fprintf(w, "     first pass:     %d\n", p.FirstPassCalls)
+	fmt.Fprintf(w, "     deep analysis:  %d\n", p.DeepAnalysisCalls)
+	fmt.Fprintf(w, "     formatting:     up to %d\n", p.FormattingCalls)
+	if p.NitpickCalls > 0 {
+		fmt.Fprintf(w, "     extra nitpicks: %d\n", p.NitpickCalls)
+	}
+	fmt.Fprintf(w, "   GitHub calls: at least %d\n", p.GitHubCalls())
+	fmt.Fprintf(w, "     content fetches: at least %d\n", p.ContentFetches)
+	fmt.Fprintf(w, "     review posts:    %d\n", p.ReviewPosts)
+}
+
+// planReview projects the rest of a review from the first-pass issues
+func (r *Reviewer) planReview(firstPass *FirstPassResult, author string, effectiveNitpicky int, opts ReviewOptions) *ReviewPlan {
+	issues := firstPass.Issues
+	plan := &ReviewPlan{
+		FirstPassCalls: firstPass.Batches, // 0 when every file was cached
+		ReviewPosts:    1,
+	}
+
+	if opts.Fast {
+		plan.FormattingCalls = len(r.shallowConfirm(issues, effectiveNitpicky, nil))
+	} else {
+		// Each deep analysis fetches the file itself and probes for related files
+		plan.DeepAnalysisCalls = len(issues)
+		plan.FormattingCalls = len(issues)
+		plan.ContentFetches = len(issues) * (1 + relatedFileProbes)
+	}
+
+	if r.config.IsDislikedReviewer(author) {
+		plan.NitpickCalls = 1
+	}
+
+	return plan
+}
@@ -0,0 +1,153 @@
+package reviewer
+
+import (
+	"fmt"
+
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/signature"
+)
+
+// postReview posts the review's comments in batches of
+// max_comments_per_review, since GitHub rejects reviews with too many. The
+// first review carries the summary and the event, the others only say
+// they continue it. It returns the IDs of the reviews posted. The review is
+// pinned to commitID, the commit reviewed, so comments land on the code they
+// are about even if the PR moved on; an empty commitID anchors it to the head.
+// Once the lead review is up, failures are warned about rather than
+// returned, so the review isn't posted twice by a retry.
+func (r *Reviewer) postReview(ref *github.PRReference, commitID string, result *ReviewResult, event string) ([]int64, error) {
+	batches := commentBatches(result.Comments, r.config.MaxCommentsPerReview)
+
+	reviewID, err := r.githubClient.PostReviewAtCommit(ref, commitID, result.Summary, event, batches[0])
+	// A review of the whole PR pinned to the head it read is only rejected if
+	// that head was force-pushed away; per-commit reviews aren't re-mapped
+	if err != nil && commitID != "" && commitID == result.headSHA && r.config.RemapOutdatedComments && github.IsLineNotInDiff(err) {
+		r.out.Warnf("   ⚠️  A comment line isn't in the reviewed diff any more - the PR was probably force-pushed")
+		commitID, err = r.remapToHead(ref, result)
+		if err != nil {
+			return nil, err
+		}
+		batches = commentBatches(result.Comments, r.config.MaxCommentsPerReview)
+		reviewID, err = r.githubClient.PostReviewAtCommit(ref, commitID, result.Summary, event, batches[0])
+	}
+	reviewIDs, posted := []int64{reviewID}, len(batches[0])
+	if github.IsLineNotInDiff(err) {
+		reviewIDs, posted, err = r.postRejected(ref, commitID, result.Summary, event, batches[0], true, result)
+		if err != nil && len(reviewIDs) > 0 {
+			// The lead review is up, so the review isn't lost, and failing
+			// here would have a retry post it a second time
+			r.out.Warnf("   ⚠️  Could not post all of comment batch 1/%d: %v", len(batches), err)
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, batch := range batches[1:] {
+		body := signature.Sign(fmt.Sprintf("🧂 Review continued (%d/%d)", i+2, len(batches)), signature.ModeReview, r.config.ReviewSignature)
+		ids, n, err := r.postSkippingRejected(ref, commitID, body, "COMMENT", batch, false, result)
+		reviewIDs = append(reviewIDs, ids...)
+		posted += n
+		if err != nil {
+			// The summary is up, so later batches failing doesn't lose it
+			r.out.Warnf("   ⚠️  Could not post all of comment batch %d/%d: %v", i+2, len(batches), err)
+		}
+	}
+
+	result.Stats.CommentsPosted = posted
+	if len(reviewIDs) > 1 {
+		fmt.Fprintf(r.out, "📦 Posted the comments in %d reviews of up to %d\n", len(reviewIDs), r.config.MaxCommentsPerReview)
+	}
+	return reviewIDs, nil
+}
+
+// postSkippingRejected posts a batch as one review, leaving out the comments
+// GitHub rejects because their line isn't in the diff (see postRejected). It
+// returns the IDs of the reviews posted and how many comments made it, also
+// along with an error.
+func (r *Reviewer) postSkippingRejected(ref *github.PRReference, commitID, body, event string, batch []*github.ReviewComment, lead bool, result *ReviewResult) ([]int64, int, error) {
+	id, err := r.githubClient.PostReviewAtCommit(ref, commitID, body, event, batch)
+	if err == nil {
+		return []int64{id}, len(batch), nil
+	}
+	if !github.IsLineNotInDiff(err) {
+		return nil, 0, err
+	}
+	return r.postRejected(ref, commitID, body, event, batch, lead, result)
+}
+
+// postRejected posts a batch GitHub just rejected because a comment's line
+// isn't in the diff. The batch is split in half until the rejected comments
+// are found, and those are left out. The lead review, carrying the summary
+// and the event, goes up with the first comments that make it, or on its own
+// if none do. It returns the IDs of the reviews posted, the lead's first,
+// and how many comments made it, also when an error stops it part way.
+func (r *Reviewer) postRejected(ref *github.PRReference, commitID, body, event string, batch []*github.ReviewComment, lead bool, result *ReviewResult) ([]int64, int, error) {
+	ids, posted, err := r.postAccepted(ref, commitID, body, event, batch, lead, result)
+	if err != nil || !lead || len(ids) > 0 {
+		return ids, posted, err
+	}
+	// Requesting changes without saying what to change isn't a review
+	if event == "REQUEST_CHANGES" {
+		event = "COMMENT"
+	}
+	id, err := r.githubClient.PostReviewAtCommit(ref, commitID, body, event, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []int64{id}, 0, nil
+}
+
+// postAccepted posts the comments of a rejected batch GitHub accepts, by
+// halves, and nothing if it accepts none of them. The lead's body and event
+// go with the first review posted; the rest continue it. On an error it
+// returns what was posted before it.
+func (r *Reviewer) postAccepted(ref *github.PRReference, commitID, body, event string, batch []*github.ReviewComment, lead bool, result *ReviewResult) ([]int64, int, error) {
+	if len(batch) <= 1 {
+		for _, c := range batch {
+			r.out.Warnf("   ⚠️  Skipping the comment on %s - GitHub says its line isn't in the diff", commentLocation(c))
+			result.Stats.CommentsRejected++
+		}
+		return nil, 0, nil
+	}
+
+	var ids []int64
+	posted := 0
+	half := len(batch) / 2
+	for _, part := range [][]*github.ReviewComment{batch[:half], batch[half:]} {
+		if lead && len(ids) > 0 {
+			body, event, lead = signature.Sign("🧂 Review continued", signature.ModeReview, r.config.ReviewSignature), "COMMENT", false
+		}
+		id, err := r.githubClient.PostReviewAtCommit(ref, commitID, body, event, part)
+		if err == nil {
+			ids = append(ids, id)
+			posted += len(part)
+			continue
+		}
+		if !github.IsLineNotInDiff(err) {
+			return ids, posted, err
+		}
+		partIDs, n, err := r.postAccepted(ref, commitID, body, event, part, lead, result)
+		ids = append(ids, partIDs...)
+		posted += n
+		if err != nil {
+			return ids, posted, err
+		}
+	}
+	return ids, posted, nil
+}
+
+// commentBatches splits comments into batches of at most size (0 = one
+// batch). There is always at least one batch, if only an empty one.
+func commentBatches(comments []*github.ReviewComment, size int) [][]*github.ReviewComment {
+	if size <= 0 || len(comments) <= size {
+		return [][]*github.ReviewComment{comments}
+	}
+	var batches [][]*github.ReviewComment
+	for len(comments) > size {
+		batches = append(batches, comments[:size])
+		comments = comments[size:]
+	}
+	return append(batches, comments)
+}
@@ -0,0 +1,117 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/logging"
+)
+
+// postedReview is a create review request the stub received
+type postedReview struct {
+	CommitID string `json:"commit_id"`
+	Event    string `json:"event"`
+	Comments []struct {
+		Line int `json:"line"`
+	} `json:"comments"`
+}
+
+// reviewStub accepts create review requests unless a comment is on one of
+// the rejected lines, which GitHub answers with a 422, or one of the broken
+// lines, which it fails with a 502 unless it rejected them first
+type reviewStub struct {
+	rejected map[int]bool
+	broken   map[int]bool
+	requests []postedReview
+}
+
+func (s *reviewStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	var review postedReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+		return nil, err
+	}
+	s.requests = append(s.requests, review)
+
+	status, body := http.StatusOK, fmt.Sprintf(`{"id": %d}`, len(s.requests))
+	for _, c := range review.Comments {
+		if s.rejected[c.Line] {
+			status, body = http.StatusUnprocessableEntity, `{"message": "Unprocessable Entity", "errors": ["Line could not be resolved"]}`
+		}
+		if s.broken[c.Line] && status == http.StatusOK {
+			status, body = http.StatusBadGateway, `{"message": "Server Error"}`
+		}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestPostReviewSkipsRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []int
+		rejected     map[int]bool
+		broken       map[int]bool
+		wantRequests int
+		wantPosted   int
+		wantEvent    string // of the lead review that went up
+	}{
+		// [1 2 3 4] and [1 2] rejected, [1] as the lead, [2] rejected, [3 4]
+		// continuing the lead. The rejected batch is never sent again as is.
+		{"one bad comment", []int{1, 2, 3, 4}, map[int]bool{2: true}, nil, 5, 3, "REQUEST_CHANGES"},
+		{"every comment bad", []int{1}, map[int]bool{1: true}, nil, 2, 0, "COMMENT"},
+		{"all fine", []int{1, 2}, nil, nil, 1, 2, "REQUEST_CHANGES"},
+		// The lead went up with [1] before [3 4] failed, so the review
+		// counts as posted rather than failing and being posted again
+		{"error after the lead", []int{1, 2, 3, 4}, map[int]bool{2: true}, map[int]bool{4: true}, 5, 1, "REQUEST_CHANGES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &reviewStub{rejected: tt.rejected, broken: tt.broken}
+			cfg := config.DefaultConfig()
+			cfg.RemapOutdatedComments = false
+			r := &Reviewer{
+				config:       cfg,
+				githubClient: github.NewClient("token", github.WithTransport(stub)),
+				out:          logging.New(io.Discard, config.VerbosityNormal),
+			}
+			result := &ReviewResult{Summary: "summary"}
+			for _, line := range tt.lines {
+				result.Comments = append(result.Comments, &github.ReviewComment{Path: "main.go", Line: line, Body: "fix"})
+			}
+
+			ref := &github.PRReference{Owner: "o", Repo: "r", Number: 1}
+			ids, err := r.postReview(ref, "abc", result, "REQUEST_CHANGES")
+			if err != nil {
+				t.Fatalf("postReview: %v", err)
+			}
+			if len(stub.requests) != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", len(stub.requests), tt.wantRequests)
+			}
+			if result.Stats.CommentsPosted != tt.wantPosted {
+				t.Errorf("posted %d comments, want %d", result.Stats.CommentsPosted, tt.wantPosted)
+			}
+			if len(ids) == 0 {
+				t.Fatal("no review posted")
+			}
+			for _, req := range stub.requests {
+				if req.CommitID != "abc" {
+					t.Errorf("review posted at %q, want the reviewed commit abc", req.CommitID)
+				}
+			}
+			lead := stub.requests[ids[0]-1]
+			if lead.Event != tt.wantEvent {
+				t.Errorf("lead event = %s, want %s", lead.Event, tt.wantEvent)
+			}
+		})
+	}
+}
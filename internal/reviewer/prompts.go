@@ -6,8 +6,8 @@ import (
 	"github.com/user/salty-reviewer/internal/config"
 )
 
-// GetSystemPrompt returns the system prompt based on writing style
-func GetSystemPrompt(style config.WritingStyle, nitpickyLevel int) string {
+// GetSystemPrompt returns the system prompt based on cfg's writing style
+func GetSystemPrompt(cfg *config.Config, nitpickyLevel int) string {
 	basePrompt := `You are a senior code reviewer. Your job is to review pull requests thoroughly and provide constructive feedback.
 
 IMPORTANT GUIDELINES:
@@ -18,13 +18,24 @@ IMPORTANT GUIDELINES:
 
 `
 
-	stylePrompt := getStylePrompt(style)
+	stylePrompt := getStylePrompt(cfg)
 	nitpickyPrompt := getNitpickyPrompt(nitpickyLevel)
 
 	return basePrompt + stylePrompt + "\n\n" + nitpickyPrompt
 }
 
-func getStylePrompt(style config.WritingStyle) string {
+// getStylePrompt returns the custom style's review prompt, or the built-in
+// prompt for other styles and when the custom one can't be read
+func getStylePrompt(cfg *config.Config) string {
+	if cfg.WritingStyle == config.StyleCustom {
+		if custom := cfg.CustomStyle(); custom != nil {
+			return custom.Review
+		}
+	}
+	return builtinStylePrompt(cfg.WritingStyle)
+}
+
+func builtinStylePrompt(style config.WritingStyle) string {
 	switch style {
 	case config.StyleCorporate:
 		return `WRITING STYLE: Corporate Professional
@@ -74,7 +85,7 @@ func getStylePrompt(style config.WritingStyle) string {
 - Question methodology: "The epistemological basis for this approach..."`
 
 	default:
-		return getStylePrompt(config.StylePassiveAggressive)
+		return builtinStylePrompt(config.StylePassiveAggressive)
 	}
 }
 
@@ -140,14 +151,76 @@ Format your response as JSON:
       "code": "the problematic code",
       "issue": "description of the issue",
       "confidence": 7,
-      "might_be_intentional": "reason it could be intentional"
+      "might_be_intentional": "reason it could be intentional",
+      "side": "RIGHT",
+      "category": "bug",
+      "severity": "major"
     }
   ]
 }
 
+Use "side": "RIGHT" for issues in added or unchanged code, with "line" being the
+line number in the new file. Use "side": "LEFT" only when the issue is about code
+the PR removes (e.g. dropped validation), with "line" being the removed line's
+number in the old file.
+
+When an issue spans several lines (a whole block or function call), set "line"
+to the first of them and add "end_line" with the last, on the same side. Leave
+"end_line" out for single-line issues.
+
+For simple fixes, add "suggested_replacement" with the corrected code for the
+whole of lines "line" to "end_line" (or just "line"), indentation included,
+exactly as it should appear in the file. Leave it out when the fix needs
+changes elsewhere or isn't obvious.
+
+Set "category" to the kind of issue: bug, security, performance, error_handling,
+maintainability or style.
+
+Set "severity" to how much the issue matters:
+- critical: breaks things or is a security hole (crashes, data loss, nil dereferences)
+- major: a real bug or risk that should be fixed before merging
+- minor: worth fixing, but not blocking
+- nit: naming, style and other polish
+
 Be thorough but fair. Consider that the author might have reasons for their choices.`
 }
 
+// GetLinkedIssuePrompt returns the first-pass addendum used when the PR says it resolves an issue
+func GetLinkedIssuePrompt() string {
+	return `This PR claims to resolve the LINKED ISSUE(s) shown before the diff.
+Check whether the changes plausibly address everything the issue asks for.
+For each requirement the PR appears to miss or only partially address, add a short
+description to an "issue_gaps" array alongside "issues":
+
+{
+  "issues": [...],
+  "issue_gaps": ["the issue asks for X but the PR only does Y"]
+}
+
+Leave "issue_gaps" empty if the PR looks like it covers the issue.`
+}
+
+// GetNewCodeOnlyPrompt is added to the first pass when moved code has been
+// stripped from the diff
+func GetNewCodeOnlyPrompt() string {
+	return `This PR is largely a reorganization. Code that was only moved or renamed has
+been left out of the diff, so what remains is (approximately) the genuinely new
+logic. Review only that. Don't comment on code having been relocated, on file
+organization, or on anything that merely looks like it was carried over.`
+}
+
+// GetGuidelinesPrompt is added to the first pass when the project documents
+// its own conventions
+func GetGuidelinesPrompt(guidelines string) string {
+	return `This project documents its conventions. Where they apply, judge the code by
+these rules rather than general preferences: flag clear violations of them, and
+don't flag things they explicitly allow.
+
+=== PROJECT GUIDELINES ===
+` + guidelines + `
+=== END PROJECT GUIDELINES ===`
+}
+
 // GetDeepAnalysisPrompt returns the prompt for analyzing a specific issue
 func GetDeepAnalysisPrompt(issue string, fullFileContent string, relatedCode string) string {
 	return fmt.Sprintf(`You previously identified this potential issue:
@@ -169,18 +242,29 @@ Now analyze more deeply:
 Respond with JSON:
 {
   "still_an_issue": true/false,
-  "confidence": 1-10,
+  "confidence": 0-100,
   "reasoning": "your analysis",
   "possible_author_intent": "why they might have done this",
-  "final_verdict": "COMMENT" or "SKIP"
+  "final_verdict": "COMMENT" or "SKIP",
+  "severity": "critical", "major", "minor" or "nit",
+  "suggested_replacement": "corrected code for the issue's line(s), or empty"
 }
 
-Only say "COMMENT" if you're at least 80%% confident this is a real issue.`, issue, fullFileContent, relatedCode)
+"confidence" is how sure you are, in percent, that this is a real issue.
+"severity" is how much it matters if it is: critical for crashes, data loss
+or security holes, major for real bugs that should block the merge, minor for
+things worth fixing that shouldn't, nit for naming, style and polish. Correct
+the severity given above if your deeper look changes it.
+Give "suggested_replacement" only for simple fixes confined to the issue's
+line(s): the full corrected lines, indentation included. If a replacement was
+suggested above and it's right, repeat it; fix it if it isn't.
+Say "COMMENT" if you still think it is one and "SKIP" if not; the confidence
+decides whether a comment is actually posted.`, issue, fullFileContent, relatedCode)
 }
 
 // GetCommentFormattingPrompt returns the prompt for formatting a final comment
-func GetCommentFormattingPrompt(issue string, analysis string, style config.WritingStyle) string {
-	styleGuide := getStylePrompt(style)
+func GetCommentFormattingPrompt(issue string, analysis string, cfg *config.Config) string {
+	styleGuide := getStylePrompt(cfg)
 
 	return fmt.Sprintf(`Format this code review comment according to the style guide.
 
@@ -196,7 +280,9 @@ Style Guide:
 Write the final comment that will be posted on the PR.
 Keep it concise but include the key points.
 Match the writing style exactly.
-Do not include any JSON formatting - just write the comment text.`, issue, analysis, styleGuide)
+Do not include any JSON formatting - just write the comment text.
+If the issue has a suggested replacement, it is attached below your comment
+as a one-click suggestion; refer to it, but don't repeat the code.`, issue, analysis, styleGuide)
 }
 
 // GetExtraNitpickPrompt returns the prompt for generating extra nitpicks for disliked reviewers
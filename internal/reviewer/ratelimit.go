@@ -0,0 +1,58 @@
+package reviewer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// rateLimitSlack covers GitHub calls made outside deep analysis, like posting
+// the review and applying labels
+const rateLimitSlack = 10
+
+// checkRateLimit makes sure enough GitHub quota is left to deep-analyze
+// issues in files distinct files. When it isn't, it waits for the reset if
+// opts.WaitForRateLimit is set, and otherwise stops with
+// github.ErrRateLimited. If the quota can't be read the review goes ahead.
+func (r *Reviewer) checkRateLimit(ref *github.PRReference, files int, opts ReviewOptions) error {
+	if ref == nil || files == 0 {
+		return nil
+	}
+
+	limit, err := r.githubClient.RateLimit()
+	if err != nil {
+		r.out.Warnf("   ⚠️  Could not check the GitHub rate limit: %v", err)
+		return nil
+	}
+
+	// Each file is fetched once and probed for related files
+	needed := files*(1+relatedFileProbes) + rateLimitSlack
+	if limit.Remaining >= needed {
+		return nil
+	}
+
+	reset := limit.Reset.Local().Format("15:04:05")
+	if !opts.WaitForRateLimit {
+		return fmt.Errorf("%w: %d requests left, deep analysis needs about %d; the quota resets at %s (use --wait-for-rate-limit to wait)",
+			github.ErrRateLimited, limit.Remaining, needed, reset)
+	}
+
+	clk := r.githubClient.Clock()
+	wait := limit.Reset.Sub(clk.Now()) + time.Second
+	if wait > 0 {
+		fmt.Fprintf(r.out, "⏳ %d GitHub requests left, deep analysis needs about %d - waiting until %s\n",
+			limit.Remaining, needed, reset)
+		clk.Sleep(wait)
+	}
+	return nil
+}
+
+// countIssueFiles is how many distinct files the issues are in
+func countIssueFiles(issues []Issue) int {
+	files := make(map[string]bool)
+	for _, issue := range issues {
+		files[issue.File] = true
+	}
+	return len(files)
+}
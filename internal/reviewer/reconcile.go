@@ -0,0 +1,187 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// Reconciliation outcomes for a first-pass issue's line
+const (
+	lineOK         = iota // The line is in the diff (and holds the code, if quoted)
+	lineReanchored        // Moved to the nearest diff line holding the quoted code
+	lineDropped           // Not in the diff and the code couldn't be found
+)
+
+// lineIndex holds the commentable lines of each file's diff
+type lineIndex map[string][]diff.Line
+
+func newLineIndex(files []*github.FileChange) lineIndex {
+	idx := make(lineIndex, len(files))
+	for _, f := range files {
+		hunks, err := diff.ParsePatch(f.Patch)
+		if err != nil {
+			continue
+		}
+		idx[f.Filename] = diff.Lines(hunks)
+	}
+	return idx
+}
+
+// reconcile checks an issue's line against the diff, since models sometimes
+// make line numbers up. An issue whose line isn't in the diff, or doesn't
+// hold the code it quotes, is moved to the nearest diff line that does -
+// preferring changed lines over context. It is dropped if its line isn't in
+// the diff and the code can't be found. Removed-code issues are matched
+// against removed lines only, everything else against the new file's lines.
+// A line range moves along with its first line, and becomes a single line if
+// it doesn't fit in one hunk - losing its suggested replacement, which was
+// written for all of it.
+func (idx lineIndex) reconcile(issue Issue) (Issue, int) {
+	from := issue.Line
+	issue, outcome := idx.reconcileLine(issue)
+	if issue.EndLine != 0 {
+		issue.EndLine += issue.Line - from
+		if !idx.inOneHunk(issue) {
+			issue.EndLine = 0
+			issue.SuggestedReplacement = ""
+		}
+	}
+	return issue, outcome
+}
+
+// inOneHunk reports whether every line from issue.Line to issue.EndLine can
+// be commented on, on the issue's side. Hunks don't share line numbers, so
+// that also keeps the range in one hunk, as GitHub requires.
+func (idx lineIndex) inOneHunk(issue Issue) bool {
+	if issue.EndLine <= issue.Line {
+		return false
+	}
+	left := strings.EqualFold(issue.Side, SideLeft)
+	commentable := make(map[int]bool)
+	for _, l := range idx[issue.File] {
+		switch {
+		case left && l.Kind == diff.Removed:
+			commentable[l.OldLine] = true
+		case !left && l.Kind != diff.Removed:
+			commentable[l.NewLine] = true
+		}
+	}
+	for n := issue.Line; n <= issue.EndLine; n++ {
+		if !commentable[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileLine is reconcile for the issue's first line
+func (idx lineIndex) reconcileLine(issue Issue) (Issue, int) {
+	lines, ok := idx[issue.File]
+	if !ok {
+		return issue, lineDropped
+	}
+
+	left := strings.EqualFold(issue.Side, SideLeft)
+	lineNo := func(l diff.Line) int {
+		if left {
+			return l.OldLine
+		}
+		return l.NewLine
+	}
+	commentable := func(l diff.Line) bool {
+		if left {
+			return l.Kind == diff.Removed
+		}
+		return l.Kind != diff.Removed
+	}
+
+	code := quotedCode(issue.Code)
+	inDiff, holdsCode := false, false
+	for _, l := range lines {
+		if commentable(l) && lineNo(l) == issue.Line {
+			inDiff = true
+			holdsCode = code == "" || strings.Contains(normalizeSpace(l.Content), code)
+			break
+		}
+	}
+	if inDiff && holdsCode {
+		return issue, lineOK
+	}
+
+	if code != "" {
+		best, bestChanged, bestDist := 0, false, -1
+		for _, l := range lines {
+			if !commentable(l) || !strings.Contains(normalizeSpace(l.Content), code) {
+				continue
+			}
+			changed := l.Kind != diff.Context
+			dist := lineNo(l) - issue.Line
+			if dist < 0 {
+				dist = -dist
+			}
+			if bestDist == -1 || (changed && !bestChanged) || (changed == bestChanged && dist < bestDist) {
+				best, bestChanged, bestDist = lineNo(l), changed, dist
+			}
+		}
+		if bestDist != -1 {
+			issue.Line = best
+			return issue, lineReanchored
+		}
+	}
+
+	// A real line whose code was paraphrased is still worth a comment
+	if inDiff {
+		return issue, lineOK
+	}
+	return issue, lineDropped
+}
+
+// quotedCode is the first non-blank line of the code an issue quotes, with
+// whitespace normalized for matching
+func quotedCode(code string) string {
+	for _, line := range strings.Split(code, "\n") {
+		if line = normalizeSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// reconcileIssues reconciles every issue's line, returning the issues that
+// survive and decisions for the ones that were dropped
+func (r *Reviewer) reconcileIssues(issues []Issue, idx lineIndex, threshold int, stats *ReviewStats) ([]Issue, []Decision) {
+	var kept []Issue
+	var dropped []Decision
+	for _, issue := range issues {
+		reconciled, outcome := idx.reconcile(issue)
+		switch outcome {
+		case lineDropped:
+			dropped = append(dropped, droppedDecision(issue, threshold))
+			stats.IssuesDropped++
+			continue
+		case lineReanchored:
+			stats.IssuesReanchored++
+		}
+		kept = append(kept, reconciled)
+	}
+
+	if stats.IssuesReanchored > 0 || stats.IssuesDropped > 0 {
+		fmt.Fprintf(r.out, "   📐 Re-anchored %d issue(s), dropped %d whose line isn't in the diff\n",
+			stats.IssuesReanchored, stats.IssuesDropped)
+	}
+	return kept, dropped
+}
+
+// droppedDecision records an issue dropped by reconciliation
+func droppedDecision(issue Issue, threshold int) Decision {
+	d := newDecisions([]Issue{issue}, threshold)[0]
+	d.Outcome = OutcomeLineNotInDiff
+	return d
+}
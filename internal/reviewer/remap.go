@@ -0,0 +1,108 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/signature"
+)
+
+// code returns the content of a diff line, looked up by the line number on
+// the given side, or "" if the line isn't in the diff
+func (idx lineIndex) code(file, side string, line int) string {
+	left := strings.EqualFold(side, SideLeft)
+	for _, l := range idx[file] {
+		if left && l.Kind == diff.Removed && l.OldLine == line {
+			return l.Content
+		}
+		if !left && l.Kind != diff.Removed && l.NewLine == line {
+			return l.Content
+		}
+	}
+	return ""
+}
+
+// remapComments moves comments placed on the reviewed diff onto the current
+// one. A comment follows the code it was on; comments whose code can't be
+// found in the current diff are returned as lost.
+func remapComments(comments []*github.ReviewComment, reviewed, current lineIndex) (kept, lost []*github.ReviewComment, moved int) {
+	for _, c := range comments {
+		code := reviewed.code(c.Path, c.Side, c.Line)
+		issue, outcome := current.reconcile(Issue{File: c.Path, Line: c.Line, Side: c.Side, Code: code})
+
+		// reconcile keeps a line that is still in the diff even if the code
+		// changed; here that means the comment would land on the wrong code
+		if outcome == lineOK && quotedCode(code) != "" {
+			now := current.code(c.Path, c.Side, issue.Line)
+			if !strings.Contains(normalizeSpace(now), quotedCode(code)) {
+				outcome = lineDropped
+			}
+		}
+
+		switch outcome {
+		case lineDropped:
+			lost = append(lost, c)
+			continue
+		case lineReanchored:
+			moved++
+		}
+		remapped := *c
+		remapped.Line = issue.Line
+		if c.StartLine > 0 {
+			// Keep the range's length; the comment is anchored on its last line
+			remapped.StartLine += issue.Line - c.Line
+		}
+		kept = append(kept, &remapped)
+	}
+	return kept, lost, moved
+}
+
+// remapToHead re-maps result's comments onto the PR's current diff after
+// GitHub rejected them, returning the head SHA the comments now belong to so
+// the retry can be pinned to it. Comments that couldn't be re-mapped are
+// listed in the summary so the findings aren't lost.
+func (r *Reviewer) remapToHead(ref *github.PRReference, result *ReviewResult) (string, error) {
+	pr, err := r.githubClient.GetPR(ref)
+	if err != nil {
+		return "", err
+	}
+	files, err := r.githubClient.GetPRFiles(ref)
+	if err != nil {
+		return "", err
+	}
+
+	kept, lost, moved := remapComments(result.Comments, result.reviewed, newLineIndex(files))
+	result.Comments = kept
+	result.Stats.CommentsRemapped = moved
+	result.Stats.CommentsUnmapped = len(lost)
+	fmt.Fprintf(r.out, "   📐 Re-mapped to the new head: moved %d comment(s), %d couldn't be placed\n",
+		moved, len(lost))
+
+	if len(lost) > 0 {
+		for _, c := range lost {
+			fmt.Fprintf(r.out, "      ✗ %s:%d\n", c.Path, c.Line)
+		}
+
+		// The summary is already signed; put the note above the signature
+		sigSuffix := signature.Sign("", signature.ModeReview, r.config.ReviewSignature)
+		var sb strings.Builder
+		sb.WriteString(strings.TrimSuffix(result.Summary, sigSuffix))
+		sb.WriteString("\n\n")
+		writeUnmappedNote(&sb, lost, sigSuffix)
+		result.Summary = signature.Sign(sb.String(), signature.ModeReview, r.config.ReviewSignature)
+	}
+
+	return pr.GetHead().GetSHA(), nil
+}
+
+// writeUnmappedNote lists comments whose code changed under them mid-review
+func writeUnmappedNote(sb *strings.Builder, lost []*github.ReviewComment, sigSuffix string) {
+	sb.WriteString("### 🏃 Code moved while I was reviewing\n\n")
+	sb.WriteString("New commits landed during the review and these comments no longer match the diff:\n\n")
+	for _, c := range lost {
+		first, _, _ := strings.Cut(strings.TrimSpace(strings.TrimSuffix(c.Body, sigSuffix)), "\n")
+		sb.WriteString(fmt.Sprintf("- `%s:%d`: %s\n", c.Path, c.Line, first))
+	}
+}
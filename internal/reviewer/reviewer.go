@@ -1,12 +1,26 @@
 package reviewer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/aiconfig"
+	"github.com/user/salty-reviewer/internal/condense"
 	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/diff"
 	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/hook"
+	"github.com/user/salty-reviewer/internal/live"
+	"github.com/user/salty-reviewer/internal/logging"
+	"github.com/user/salty-reviewer/internal/signature"
+	"github.com/user/salty-reviewer/internal/state"
 )
 
 // ReviewResult is the final output of a review
@@ -14,15 +28,116 @@ type ReviewResult struct {
 	Summary  string
 	Comments []*github.ReviewComment
 	Stats    ReviewStats
+
+	// Plan is set instead of comments when ReviewOptions.Plan is used
+	Plan *ReviewPlan
+
+	// Draft is set when the PR reviewed is a draft
+	Draft bool
+
+	// SquashBase is the base branch of a squash-merge preview review
+	SquashBase string
+
+	// Findings are the confirmed issues that made it into Comments
+	Findings []AnalyzedIssue
+
+	// Decisions explains what happened to every first-pass issue
+	Decisions []Decision
+
+	// Images added or changed by the PR, when review_images is on
+	Images []ImageChange
+
+	// Dependencies added, removed or upgraded, when analyze_dependencies is on
+	Dependencies []DependencyChange
+
+	// Issues the PR says it resolves, and requirements of theirs it seems to miss
+	LinkedIssues []*github.Issue
+	IssueGaps    []string
+
+	// Comments held back by max_comments_per_file and max_comments, per file
+	Rollups []FileRollup
+
+	confirmed  []AnalyzedIssue // Confirmed issues before formatting, for style comparison
+	secrets    []string        // Detected secrets, redacted from anything shown
+	redactions []string        // Secrets to strip from file content sent to the AI
+	reviewed   lineIndex       // The diff comments were placed on, for re-mapping
+	headSHA    string          // The PR head a whole-PR review was of, which it is posted at
+
+	checks map[*github.ReviewComment]bool // Comments from salty's own checks, which a cap keeps first
 }
 
 // ReviewStats tracks review statistics
 type ReviewStats struct {
-	FilesReviewed    int
-	IssuesFound      int
-	IssuesAfterDeep  int
-	NitpicksAdded    int
-	CommentsPosted   int
+	FilesReviewed   int `json:"files_reviewed"`
+	IssuesFound     int `json:"issues_found"`
+	IssuesAfterDeep int `json:"issues_after_deep"`
+
+	// Changed files left out by respect_codeowners, --author, include_globs,
+	// exclude_globs or as unreviewable, e.g. binary or over max_file_diff_lines
+	FilesSkipped int `json:"files_skipped"`
+
+	// First-pass issues whose line was moved to where their code really is,
+	// or dropped because it isn't in the diff at all
+	IssuesReanchored int `json:"issues_reanchored"`
+	IssuesDropped    int `json:"issues_dropped"`
+
+	// Comments moved to the current diff after the head moved mid-review,
+	// and ones whose code was gone so they went into the summary instead
+	CommentsRemapped int `json:"comments_remapped"`
+	CommentsUnmapped int `json:"comments_unmapped"`
+
+	NitpicksAdded   int `json:"nitpicks_added"`
+	ConflictMarkers int `json:"conflict_markers"`
+	SecretsFound    int `json:"secrets_found"`
+	TodoMarkers     int `json:"todo_markers"`
+	CommentsPosted  int `json:"comments_posted"`
+
+	// Comments left out when asked about them with --interactive
+	CommentsSkipped int `json:"comments_skipped"`
+
+	// Comments folded into another one on the same line
+	CommentsMerged int `json:"comments_merged"`
+
+	// Comments GitHub rejected because their line isn't in the diff
+	CommentsRejected int `json:"comments_rejected"`
+
+	// DeepAnalysisSkipped marks a fast review, where IssuesAfterDeep counts
+	// issues that passed the first-pass threshold instead
+	DeepAnalysisSkipped bool `json:"deep_analysis_skipped"`
+
+	// BudgetExceeded marks a review cut short by the token budget, after
+	// deep-analyzing IssuesAnalyzed of IssuesFound issues
+	BudgetExceeded bool `json:"budget_exceeded"`
+	IssuesAnalyzed int  `json:"issues_analyzed"`
+
+	// AI tokens the run used, and what they cost if prices are configured
+	TokensUsed       int     `json:"tokens_used"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+}
+
+// add accumulates another set of stats into s
+func (s *ReviewStats) add(other ReviewStats) {
+	s.FilesReviewed += other.FilesReviewed
+	s.IssuesFound += other.IssuesFound
+	s.IssuesAfterDeep += other.IssuesAfterDeep
+	s.IssuesReanchored += other.IssuesReanchored
+	s.FilesSkipped += other.FilesSkipped
+	s.IssuesDropped += other.IssuesDropped
+	s.CommentsRemapped += other.CommentsRemapped
+	s.CommentsUnmapped += other.CommentsUnmapped
+	s.NitpicksAdded += other.NitpicksAdded
+	s.ConflictMarkers += other.ConflictMarkers
+	s.SecretsFound += other.SecretsFound
+	s.TodoMarkers += other.TodoMarkers
+	s.CommentsPosted += other.CommentsPosted
+	s.CommentsSkipped += other.CommentsSkipped
+	s.CommentsMerged += other.CommentsMerged
+	s.CommentsRejected += other.CommentsRejected
+	s.DeepAnalysisSkipped = s.DeepAnalysisSkipped || other.DeepAnalysisSkipped
+	s.BudgetExceeded = s.BudgetExceeded || other.BudgetExceeded
+	s.IssuesAnalyzed += other.IssuesAnalyzed
+	s.TokensUsed += other.TokensUsed
+	s.EstimatedCostUSD += other.EstimatedCostUSD
 }
 
 // Reviewer orchestrates the code review process
@@ -31,128 +146,655 @@ type Reviewer struct {
 	githubClient *github.Client
 	aiClient     *ai.Client
 	analyzer     *Analyzer
+	out          *logging.Logger // progress output, os.Stdout unless overridden
+	live         bool            // stream comments to out as they're written
+
+	guidelines map[string]string // guidelines_file content by owner/repo@sha
+	cache      *reviewCache      // results of earlier runs on the PR, nil if off
 }
 
 // NewReviewer creates a new reviewer instance
 func NewReviewer(cfg *config.Config) *Reviewer {
-	ghClient := github.NewClient(cfg.GitHubToken)
-	aiClient := ai.NewClient(cfg.AIApiURL, cfg.AIApiKey, cfg.AIModel)
-	analyzer := NewAnalyzer(aiClient, ghClient)
-
-	return &Reviewer{
+	r := &Reviewer{
 		config:       cfg,
-		githubClient: ghClient,
-		aiClient:     aiClient,
-		analyzer:     analyzer,
+		githubClient: github.NewClient(cfg.GitHubToken, github.WithMaxFileBytes(cfg.MaxFileBytes)),
+		out:          logging.New(os.Stdout, cfg.Verbosity),
+		guidelines:   make(map[string]string),
 	}
+	r.aiClient = aiconfig.NewClient(cfg, ai.WithDebugLog(func(format string, args ...interface{}) {
+		r.out.Debugf(format, args...)
+	}))
+	r.analyzer = NewAnalyzer(r.aiClient, r.githubClient, cfg)
+	r.analyzer.out = r.out
+	return r
+}
+
+// SetOutput redirects progress output, filtered by the config's verbosity.
+// Library callers that only want the returned result can pass io.Discard.
+func (r *Reviewer) SetOutput(w io.Writer) {
+	r.out = logging.New(w, r.config.Verbosity)
+	r.analyzer.out = r.out
+}
+
+// SetLiveOutput streams each comment to the progress output while the AI
+// writes it, so long formatting calls don't look stuck. Meant for terminals.
+func (r *Reviewer) SetLiveOutput(enabled bool) {
+	r.live = enabled
+}
+
+// ReviewOptions controls how a review is run
+type ReviewOptions struct {
+	DryRun    bool // Show what would be posted without posting
+	PerCommit bool // Review and post each of the PR's commits separately
+	Fast      bool // Skip deep analysis and post first-pass issues directly
+	Partial   bool // Post what was confirmed even if the token budget ran out
+	Plan      bool // Stop after the first pass and report the projected API calls
+	Squash    bool // Review the combined base...head diff as a pre-merge check
+
+	// SinceLast only reviews what changed since salty's last review of the PR
+	SinceLast bool
+
+	// NewCodeOnly strips moved code from the diff so only new logic is reviewed
+	NewCodeOnly bool
+
+	// Author limits the review to files this GitHub user contributed commits to
+	Author string
+
+	// RequestReviewers are asked to re-review after requesting changes, in
+	// addition to the ones in config
+	RequestReviewers []string
+
+	// AllowRequestChanges skips the confirm_request_changes check
+	AllowRequestChanges bool
+
+	// ApproveClean approves a review without comments, like approve_clean_prs
+	ApproveClean bool
+
+	// AllowDraft reviews draft PRs, like review_drafts
+	AllowDraft bool
+
+	// ShowPayload prints the JSON request GitHub would get in dry-run mode
+	ShowPayload bool
+
+	// NoCache analyzes every file again instead of reusing the results of
+	// the last review of the PR for unchanged files
+	NoCache bool
+
+	// ConfirmRequestChanges asks the user whether a review may request
+	// changes when confirm_request_changes is on. Nil means nobody can be
+	// asked, and the review is downgraded to a comment.
+	ConfirmRequestChanges func(result *ReviewResult) bool
+
+	// MinSeverity leaves out findings less severe than this, e.g. nits
+	MinSeverity Severity
+
+	// ConfirmComment is asked about each comment before the review is
+	// posted, for --interactive. It returns the body to post, possibly
+	// edited, or false to leave the comment out. Nil posts every comment.
+	ConfirmComment func(c *github.ReviewComment) (string, bool)
+
+	// WaitForRateLimit sleeps until the GitHub quota resets when there isn't
+	// enough left for deep analysis, instead of stopping the review
+	WaitForRateLimit bool
+
+	// CompareStyles analyzes once, then prints the comments as written in
+	// each of these styles instead of posting a review
+	CompareStyles []config.WritingStyle
 }
 
 // Review performs a full code review on a PR
-func (r *Reviewer) Review(prRef string, dryRun bool) (*ReviewResult, error) {
+func (r *Reviewer) Review(prRef string, opts ReviewOptions) (*ReviewResult, error) {
+	before := r.aiClient.Usage()
+	result, err := r.review(prRef, opts)
+	if result != nil {
+		r.recordUsage(result, before)
+	}
+	return result, err
+}
+
+func (r *Reviewer) review(prRef string, opts ReviewOptions) (*ReviewResult, error) {
 	ref, err := github.ParsePRReference(prRef)
 	if err != nil {
 		return nil, err
 	}
+	r = r.forRepo(ref.Owner, ref.Repo)
+	r.warnStyle()
 
-	fmt.Printf("🔍 Fetching PR #%d from %s/%s...\n", ref.Number, ref.Owner, ref.Repo)
+	fmt.Fprintf(r.out, "🔍 Fetching PR #%d from %s/%s...\n", ref.Number, ref.Owner, ref.Repo)
 
 	// Get PR details
 	pr, err := r.githubClient.GetPR(ref)
 	if err != nil {
 		return nil, err
 	}
+	if pr.GetDraft() && !r.config.ReviewDrafts && !opts.AllowDraft {
+		return nil, fmt.Errorf("PR #%d is a draft - pass --allow-draft or set review_drafts to review it anyway", ref.Number)
+	}
+
+	result, err := r.reviewPR(ref, pr, opts)
+	if result != nil {
+		result.Draft = pr.GetDraft()
+	}
+	return result, err
+}
 
+// reviewPR reviews a fetched PR
+func (r *Reviewer) reviewPR(ref *github.PRReference, pr *github.PullRequest, opts ReviewOptions) (*ReviewResult, error) {
 	author := pr.GetUser().GetLogin()
-	fmt.Printf("📝 PR by @%s: %s\n", author, pr.GetTitle())
+	fmt.Fprintf(r.out, "📝 PR by @%s: %s\n", author, pr.GetTitle())
+
+	if !opts.NoCache {
+		cached := *r
+		cached.cache = r.loadReviewCache(ref, pr.GetHead().GetSHA())
+		r = &cached
+		defer r.cache.save(r)
+	}
 
-	// Calculate effective nitpicky level based on author
-	effectiveNitpicky := r.config.NitpickyLevel + r.config.GetReviewerBias(author)
+	// Calculate effective nitpicky level based on the target branch and author
+	baseNitpicky, pattern := r.config.BaseBranchNitpicky(pr.GetBase().GetRef(), r.config.NitpickyLevel)
+	if pattern != "" {
+		fmt.Fprintf(r.out, "🎯 Base branch %s matches %q - nitpicky level %d\n", pr.GetBase().GetRef(), pattern, baseNitpicky)
+	}
+	requestedNitpicky := baseNitpicky + r.config.GetReviewerBias(author)
+	effectiveNitpicky := requestedNitpicky
 	if effectiveNitpicky < 1 {
 		effectiveNitpicky = 1
 	}
-	if effectiveNitpicky > 10 {
-		effectiveNitpicky = 10
+	if limit := r.config.MaxNitpicky(); effectiveNitpicky > limit {
+		effectiveNitpicky = limit
+	}
+	if effectiveNitpicky != requestedNitpicky {
+		fmt.Fprintf(r.out, "📏 Nitpicky level %d (base %d, bias %+d) is out of range - using %d\n",
+			requestedNitpicky, baseNitpicky, requestedNitpicky-baseNitpicky, effectiveNitpicky)
 	}
 
 	if r.config.IsLikedReviewer(author) {
-		fmt.Printf("💚 Author is liked - going easy (nitpicky: %d)\n", effectiveNitpicky)
+		fmt.Fprintf(r.out, "💚 Author is liked - going easy (nitpicky: %d)\n", effectiveNitpicky)
 	} else if r.config.IsDislikedReviewer(author) {
-		fmt.Printf("🔴 Author is disliked - extra scrutiny (nitpicky: %d)\n", effectiveNitpicky)
+		fmt.Fprintf(r.out, "🔴 Author is disliked - extra scrutiny (nitpicky: %d)\n", effectiveNitpicky)
+	}
+
+	if opts.PerCommit && opts.Squash {
+		return nil, fmt.Errorf("per-commit and squash reviews can't be combined")
+	}
+	if opts.SinceLast && (opts.PerCommit || opts.Squash) {
+		return nil, fmt.Errorf("--since-last can't be combined with per-commit or squash reviews")
+	}
+	if len(opts.CompareStyles) > 0 && (opts.PerCommit || opts.Plan) {
+		return nil, fmt.Errorf("style comparison can't be combined with per-commit or plan reviews")
+	}
+
+	if opts.PerCommit {
+		return r.reviewPerCommit(ref, pr, effectiveNitpicky, opts)
 	}
 
 	// Get changed files
-	files, err := r.githubClient.GetPRFiles(ref)
+	var files []*github.FileChange
+	var err error
+	switch {
+	case opts.Squash:
+		base := pr.GetBase().GetRef()
+		fmt.Fprintf(r.out, "🧮 Squash preview: comparing %s...%s\n", base, pr.GetHead().GetRef())
+		files, err = r.githubClient.CompareCommits(ref.Owner, ref.Repo, base, pr.GetHead().GetSHA())
+		if len(files) >= github.CompareFilesLimit {
+			r.out.Warnf("⚠️  GitHub compares at most %d files - the squash preview may be missing some", github.CompareFilesLimit)
+		}
+	case opts.SinceLast:
+		files, err = r.filesSinceLastReview(ref, pr)
+	default:
+		files, err = r.githubClient.GetPRFiles(ref)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if opts.SinceLast && len(files) == 0 {
+		fmt.Fprintln(r.out, "✅ Nothing new to review since the last review")
+		return &ReviewResult{}, nil
+	}
+	changed := len(files)
+
+	if r.config.RespectCodeowners {
+		own, err := r.loadOwnership(ref, pr)
+		if err != nil {
+			return nil, err
+		}
+		files = r.filterOwnedFiles(own, files)
+	}
+
+	if opts.Author != "" {
+		files, err = r.filterAuthoredFiles(ref, files, opts.Author)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files = r.filterGlobFiles(files)
 
-	fmt.Printf("📁 Reviewing %d changed files...\n", len(files))
+	fmt.Fprintf(r.out, "📁 Reviewing %d changed files...\n", len(files))
+
+	// Guidelines come from the base branch, so a PR can't rewrite the rules
+	// it is reviewed against
+	fpc := FirstPassContext{
+		LinkedIssues: r.fetchLinkedIssues(ref, pr),
+		Guidelines:   r.fetchGuidelines(ref, pr.GetBase().GetSHA()),
+	}
+
+	result, err := r.reviewFiles(ref, pr, pr.GetHead().GetSHA(), files, effectiveNitpicky, fpc, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.headSHA = pr.GetHead().GetSHA()
+	result.Stats.FilesSkipped += changed - len(files)
+
+	if opts.Plan {
+		result.Plan.print(r.out.Result())
+		return result, nil
+	}
+
+	if opts.Squash {
+		result.SquashBase = pr.GetBase().GetRef()
+	}
+
+	if len(opts.CompareStyles) > 0 {
+		opts.DryRun = true // never prompt, nothing gets posted
+		r.compareStyles(result, pr, r.decideEvent(result, pr.GetUser().GetLogin(), opts), opts.CompareStyles)
+		return result, nil
+	}
+
+	r.confirmComments(result, opts)
+
+	// Generate summary
+	event := r.decideEvent(result, pr.GetUser().GetLogin(), opts)
+	result.Summary = r.generateSummary(result, pr, event)
+
+	if err := r.publish(ref, pr, result.headSHA, result, event, opts); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReviewDiff reviews a local diff without talking to GitHub, e.g. from a
+// pre-push hook. Nothing is posted; deep analysis only sees the patches.
+func (r *Reviewer) ReviewDiff(fileDiffs []diff.FileDiff, opts ReviewOptions) (*ReviewResult, error) {
+	before := r.aiClient.Usage()
+	files := make([]*github.FileChange, 0, len(fileDiffs))
+	for _, fd := range fileDiffs {
+		files = append(files, &github.FileChange{
+			Filename:     fd.Filename,
+			Status:       fd.Status,
+			Additions:    fd.Additions,
+			Deletions:    fd.Deletions,
+			Patch:        fd.Patch,
+			PreviousName: fd.PreviousName,
+		})
+	}
+	changed := len(files)
+	files = r.filterGlobFiles(files)
+	r.warnStyle()
+
+	fmt.Fprintf(r.out, "📁 Reviewing %d changed files from the local diff...\n", len(files))
+
+	fpc := FirstPassContext{Guidelines: r.localGuidelines()}
+
+	result, err := r.reviewFiles(nil, nil, "", files, r.config.NitpickyLevel, fpc, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Stats.FilesSkipped += changed - len(files)
+	defer r.recordUsage(result, before)
+	if opts.Plan {
+		result.Plan.print(r.out.Result())
+		return result, nil
+	}
+
+	// Only used for the verdict, so never ask for confirmation
+	opts.DryRun = true
+	event := r.decideEvent(result, "", opts)
+	if len(opts.CompareStyles) > 0 {
+		r.compareStyles(result, nil, event, opts.CompareStyles)
+		return result, nil
+	}
+	result.Summary = r.generateSummary(result, nil, event)
+
+	return result, nil
+}
+
+// reviewPerCommit reviews each commit of the PR on its own and posts one review
+// per commit, anchored to that commit. The returned result aggregates all of them.
+func (r *Reviewer) reviewPerCommit(ref *github.PRReference, pr *github.PullRequest, effectiveNitpicky int, opts ReviewOptions) (*ReviewResult, error) {
+	commits, err := r.githubClient.ListPRCommits(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every commit is filtered against the same CODEOWNERS file at the PR's base
+	var own *ownership
+	if r.config.RespectCodeowners {
+		own, err = r.loadOwnership(ref, pr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Fprintf(r.out, "🧩 Reviewing %d commits individually...\n", len(commits))
+
+	total := &ReviewResult{}
+	var summaries []string
+
+	for i, commit := range commits {
+		fmt.Fprintf(r.out, "\n🧩 [%d/%d] Commit %s: %s\n", i+1, len(commits), commit.ShortSHA(), commit.Title())
+
+		if opts.Author != "" && !strings.EqualFold(commit.Author, opts.Author) {
+			fmt.Fprintf(r.out, "   Skipping - authored by @%s, not @%s\n", commit.Author, opts.Author)
+			continue
+		}
+
+		files, err := r.githubClient.GetCommitFiles(ref.Owner, ref.Repo, commit.SHA)
+		if errors.Is(err, github.ErrBadCredentials) {
+			return nil, fmt.Errorf("stopped after reviewing %d of %d commits: %w", i, len(commits), err)
+		}
+		if err != nil {
+			r.out.Warnf("   ⚠️  %v", err)
+			continue
+		}
+		changed := len(files)
+
+		files = r.filterGlobFiles(r.filterOwnedFiles(own, files))
+
+		if len(files) == 0 {
+			fmt.Fprintln(r.out, "   Nothing to review in this commit")
+			continue
+		}
+
+		fmt.Fprintf(r.out, "📁 Reviewing %d changed files...\n", len(files))
+
+		fpc := FirstPassContext{Guidelines: r.fetchGuidelines(ref, pr.GetBase().GetSHA())}
+		result, err := r.reviewFiles(ref, pr, commit.SHA, files, effectiveNitpicky, fpc, opts)
+		if errors.Is(err, github.ErrBadCredentials) {
+			return nil, fmt.Errorf("stopped after reviewing %d of %d commits: %w", i, len(commits), err)
+		}
+		if err != nil {
+			r.out.Warnf("   ⚠️  Review of commit %s failed: %v", commit.ShortSHA(), err)
+			continue
+		}
+		result.Stats.FilesSkipped += changed - len(files)
+
+		if opts.Plan {
+			if total.Plan == nil {
+				total.Plan = &ReviewPlan{}
+			}
+			total.Plan.add(result.Plan)
+			total.Stats.add(result.Stats)
+			continue
+		}
+
+		r.confirmComments(result, opts)
+		event := r.decideEvent(result, pr.GetUser().GetLogin(), opts)
+		result.Summary = fmt.Sprintf("### 🧩 Commit `%s`: %s\n\n", commit.ShortSHA(), commit.Title()) +
+			r.generateSummary(result, pr, event)
+
+		if err := r.publish(ref, pr, commit.SHA, result, event, opts); err != nil {
+			if errors.Is(err, github.ErrBadCredentials) {
+				return nil, fmt.Errorf("stopped after reviewing %d of %d commits: %w", i, len(commits), err)
+			}
+			r.out.Warnf("   ⚠️  %v", err)
+			if errors.Is(err, ai.ErrBudgetExceeded) {
+				break
+			}
+			continue
+		}
+
+		for j := range result.Decisions {
+			result.Decisions[j].Commit = commit.SHA
+		}
+		total.Decisions = append(total.Decisions, result.Decisions...)
+		total.Comments = append(total.Comments, result.Comments...)
+		total.Stats.add(result.Stats)
+		summaries = append(summaries, result.Summary)
+
+		if result.Stats.BudgetExceeded {
+			fmt.Fprintf(r.out, "💸 Stopping after %d of %d commits\n", i+1, len(commits))
+			break
+		}
+	}
+
+	if opts.Plan {
+		if total.Plan != nil {
+			total.Plan.print(r.out.Result())
+		}
+		return total, nil
+	}
+
+	total.Summary = strings.Join(summaries, "\n\n---\n\n")
+	fmt.Fprintf(r.out, "\n📊 Reviewed %d commits: %d files, %d comments\n",
+		len(commits), total.Stats.FilesReviewed, len(total.Comments))
+
+	return total, nil
+}
+
+// reviewFiles runs the analysis pipeline over a set of changed files at the given
+// commit and returns the resulting comments. It does not build the summary or post.
+func (r *Reviewer) reviewFiles(ref *github.PRReference, pr *github.PullRequest, sha string, files []*github.FileChange, effectiveNitpicky int, fpc FirstPassContext, opts ReviewOptions) (*ReviewResult, error) {
+	author := pr.GetUser().GetLogin()
+
+	var dependencies []DependencyChange
+	if r.config.AnalyzeDependencies {
+		dependencies = analyzeDependencies(files, r.config.TrustedDependencySources)
+		if len(dependencies) > 0 {
+			fmt.Fprintf(r.out, "📦 Found %d dependency change(s)\n", len(dependencies))
+		}
+
+		// Lockfiles and manifests are covered by the dependency note, not
+		// line-by-line review
+		var reviewable []*github.FileChange
+		for _, f := range files {
+			if !isDependencyFile(f.Filename) {
+				reviewable = append(reviewable, f)
+			}
+		}
+		files = reviewable
+	}
 
 	result := &ReviewResult{
+		Dependencies: dependencies,
 		Stats: ReviewStats{
 			FilesReviewed: len(files),
 		},
+		reviewed: newLineIndex(files),
+	}
+
+	// Conflict markers trump everything else and don't need the AI to spot
+	markers := FindConflictMarkers(files)
+	if len(markers) > 0 {
+		fmt.Fprintf(r.out, "🚨 Found %d unresolved merge conflict(s)!\n", len(markers))
+		result.Stats.ConflictMarkers = len(markers)
+		addChecks(result, conflictComments(markers)...)
+	}
+
+	// Secrets are found before the AI sees the diff, so they can be kept out
+	// of prompts as well as out of everything salty posts or logs
+	var secrets []string
+	if r.config.DetectSecrets {
+		matches := FindSecrets(files)
+		if len(matches) > 0 {
+			fmt.Fprintf(r.out, "🔐 Found %d possible secret(s)!\n", len(matches))
+			result.Stats.SecretsFound = len(matches)
+			findings, comments := secretFindings(matches)
+			result.Findings = append(result.Findings, findings...)
+			addChecks(result, comments...)
+
+			secrets = secretValues(matches)
+			if r.config.RedactSecretsInPrompts {
+				files = redactFiles(files, secrets)
+				result.redactions = secrets
+			}
+		}
+	}
+	result.secrets = secrets
+	defer redactResult(result, secrets)
+
+	if opts.NewCodeOnly {
+		files = stripMovedCode(files)
+		fpc.NewCodeOnly = true
+		fmt.Fprintf(r.out, "✂️  New code only: %d file(s) left after stripping moved code\n", len(files))
+	}
+
+	// New TODOs are flagged deterministically too
+	if r.config.FlagTodos {
+		todos := FindTodoMarkers(files, r.config.TodoKeywords)
+		if len(todos) > 0 {
+			fmt.Fprintf(r.out, "📝 Found %d new TODO-style marker(s)\n", len(todos))
+			result.Stats.TodoMarkers = len(todos)
+			result.Findings = append(result.Findings, todoFindings(todos)...)
+			for _, m := range todos {
+				addChecks(result, todoComment(m))
+			}
+		}
+	}
+
+	if r.config.ReviewImages && ref != nil {
+		result.Images = r.collectImageChanges(ref, sha, files)
+		if len(result.Images) > 0 {
+			fmt.Fprintf(r.out, "🖼️  Found %d added or changed image(s)\n", len(result.Images))
+		}
 	}
 
-	// First pass: identify potential issues
-	fmt.Println("🔎 First pass: identifying potential issues...")
-	firstPass, err := r.analyzer.FirstPass(files)
+	files, result.Stats.FilesSkipped = r.filterReviewableFiles(files)
+	result.Stats.FilesReviewed = len(files)
+
+	// First pass: identify potential issues. When streaming, deep analysis
+	// runs on each issue as it arrives.
+	streaming := r.config.StreamFirstPass && !opts.Plan && !opts.Fast
+	var firstPass *FirstPassResult
+	var confirmedIssues []AnalyzedIssue
+	var err error
+	if streaming {
+		if err := r.checkRateLimit(ref, len(files), opts); err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(r.out, "🔎 First pass: streaming potential issues into deep analysis...")
+		firstPass, confirmedIssues, err = r.streamConfirm(ref, sha, files, fpc, effectiveNitpicky, result)
+	} else {
+		fmt.Fprintln(r.out, "🔎 First pass: identifying potential issues...")
+		firstPass, err = r.firstPass(files, fpc)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("first pass failed: %w", err)
 	}
 
+	result.LinkedIssues = fpc.LinkedIssues
+	result.IssueGaps = firstPass.IssueGaps
+	if len(firstPass.IssueGaps) > 0 {
+		fmt.Fprintf(r.out, "🔗 PR may miss %d requirement(s) of its linked issue(s)\n", len(firstPass.IssueGaps))
+	}
+
 	result.Stats.IssuesFound = len(firstPass.Issues)
-	fmt.Printf("   Found %d potential issues\n", len(firstPass.Issues))
+	if firstPass.Batches > 1 {
+		fmt.Fprintf(r.out, "   Split the diff into %d batches to fit max_first_pass_tokens\n", firstPass.Batches)
+	}
+	fmt.Fprintf(r.out, "   Found %d potential issues\n", len(firstPass.Issues))
 
-	// Deep analysis for each issue
-	fmt.Println("🔬 Deep analysis: verifying each issue...")
-	var confirmedIssues []AnalyzedIssue
+	// Models sometimes make line numbers up; fix those before spending
+	// anything on them (streaming already did this as issues arrived)
+	var droppedDecisions []Decision
+	if !streaming {
+		firstPass.Issues, droppedDecisions = r.reconcileIssues(firstPass.Issues, newLineIndex(files),
+			confidenceThreshold(effectiveNitpicky), &result.Stats)
+	}
 
-	for i, issue := range firstPass.Issues {
-		fmt.Printf("   [%d/%d] Analyzing: %s (line %d)...\n", i+1, len(firstPass.Issues), issue.File, issue.Line)
+	if opts.Plan {
+		result.Plan = r.planReview(firstPass, author, effectiveNitpicky, opts)
+		return result, nil
+	}
 
-		analysis, err := r.analyzer.DeepAnalyze(issue, ref, pr)
-		if err != nil {
-			fmt.Printf("      ⚠️  Deep analysis failed: %v\n", err)
-			continue
+	switch {
+	case streaming:
+		// Decisions and stats were recorded while streaming
+	case opts.Fast:
+		result.Decisions = newDecisions(firstPass.Issues, confidenceThreshold(effectiveNitpicky))
+		fmt.Fprintln(r.out, "⚡ Fast mode: skipping deep analysis")
+		confirmedIssues = r.shallowConfirm(firstPass.Issues, effectiveNitpicky, result.Decisions)
+		result.Stats.DeepAnalysisSkipped = true
+		result.Stats.IssuesAnalyzed = len(firstPass.Issues)
+	default:
+		result.Decisions = newDecisions(firstPass.Issues, confidenceThreshold(effectiveNitpicky))
+		if err := r.checkRateLimit(ref, countIssueFiles(firstPass.Issues), opts); err != nil {
+			return nil, err
 		}
-
-		// Apply confidence threshold based on nitpicky level
-		threshold := 90 - (effectiveNitpicky * 5) // Level 1 = 85%, Level 10 = 40%
-		if analysis.Confidence >= threshold && analysis.FinalVerdict == "COMMENT" {
-			confirmedIssues = append(confirmedIssues, AnalyzedIssue{
-				Original: issue,
-				Analysis: *analysis,
-			})
-			fmt.Printf("      ✓ Confirmed (confidence: %d%%)\n", analysis.Confidence)
-		} else {
-			fmt.Printf("      ✗ Skipped (confidence: %d%%, threshold: %d%%)\n", analysis.Confidence, threshold)
+		fmt.Fprintln(r.out, "🔬 Deep analysis: verifying each issue...")
+		confirmedIssues, result.Stats.IssuesAnalyzed, err = r.deepConfirm(ref, sha, files, firstPass.Issues, effectiveNitpicky, result.redactions, result.Decisions)
+		if errors.Is(err, ai.ErrBudgetExceeded) {
+			result.Stats.BudgetExceeded = true
+			fmt.Fprintf(r.out, "💸 Token budget of %d exhausted after analyzing %d of %d issues\n",
+				r.aiClient.TokenBudget(), result.Stats.IssuesAnalyzed, len(firstPass.Issues))
+		} else if err != nil {
+			return nil, err
 		}
 	}
 
+	// Dropped issues go last so the others keep their decision index
+	result.Decisions = append(result.Decisions, droppedDecisions...)
+
 	result.Stats.IssuesAfterDeep = len(confirmedIssues)
-	fmt.Printf("   %d issues confirmed after deep analysis\n", len(confirmedIssues))
+	if opts.Fast {
+		fmt.Fprintf(r.out, "   %d issues passed the first-pass threshold\n", len(confirmedIssues))
+	} else {
+		fmt.Fprintf(r.out, "   %d issues confirmed after deep analysis\n", len(confirmedIssues))
+	}
+
+	// Style comparison formats the confirmed issues itself, once per style
+	if len(opts.CompareStyles) > 0 {
+		result.confirmed = confirmedIssues
+		return result, nil
+	}
+
+	patches := make(map[string]string, len(files))
+	for _, f := range files {
+		patches[f.Filename] = f.Patch
+	}
 
 	// Generate comments with proper styling
-	fmt.Println("✍️  Formatting comments...")
+	fmt.Fprintln(r.out, "✍️  Formatting comments...")
 	for _, ci := range confirmedIssues {
+		decision := &result.Decisions[ci.index]
+		if belowSeverity(ci.Severity, opts.MinSeverity) {
+			decision.Outcome = OutcomeBelowSeverity
+			continue
+		}
 		comment, err := r.formatComment(ci)
+		if errors.Is(err, ai.ErrBudgetExceeded) {
+			result.Stats.BudgetExceeded = true
+			fmt.Fprintln(r.out, "💸 Token budget exhausted while formatting comments")
+			break
+		}
 		if err != nil {
-			fmt.Printf("   ⚠️  Failed to format comment: %v\n", err)
+			r.out.Warnf("   ⚠️  Failed to format comment: %v", err)
+			decision.Outcome = OutcomeFormatFailed
+			decision.Error = err.Error()
 			continue
 		}
+		decision.Outcome = OutcomeCommented
+		result.Findings = append(result.Findings, ci)
 
-		result.Comments = append(result.Comments, &github.ReviewComment{
-			Path: ci.Original.File,
-			Line: ci.Original.Line,
-			Body: comment,
-			Side: "RIGHT",
-		})
+		rc := &github.ReviewComment{
+			Path:     ci.Original.File,
+			Line:     ci.Original.Line,
+			Body:     comment,
+			Side:     commentSide(ci.Original, patches[ci.Original.File]),
+			Severity: string(ci.Severity),
+		}
+		if ci.Original.EndLine > ci.Original.Line {
+			// GitHub anchors a multi-line comment on its last line
+			rc.StartLine, rc.StartSide = ci.Original.Line, rc.Side
+			rc.Line = ci.commentLine()
+		}
+		result.Comments = append(result.Comments, rc)
 	}
 
 	// Extra nitpicks for disliked reviewers
-	if r.config.IsDislikedReviewer(author) {
-		fmt.Println("😈 Generating extra nitpicks for disliked reviewer...")
+	if r.config.IsDislikedReviewer(author) && !result.Stats.BudgetExceeded && !belowSeverity(SeverityNit, opts.MinSeverity) {
+		fmt.Fprintln(r.out, "😈 Generating extra nitpicks for disliked reviewer...")
 		existingCommentBodies := make([]string, len(result.Comments))
 		for i, c := range result.Comments {
 			existingCommentBodies[i] = c.Body
@@ -162,63 +804,740 @@ func (r *Reviewer) Review(prRef string, dryRun bool) (*ReviewResult, error) {
 		if err == nil && nitpicks != nil {
 			for _, np := range nitpicks.Nitpicks {
 				result.Comments = append(result.Comments, &github.ReviewComment{
-					Path: np.File,
-					Line: np.Line,
-					Body: np.Comment,
-					Side: "RIGHT",
+					Path:     np.File,
+					Line:     np.Line,
+					Body:     r.finishComment(np.Comment, np.File, np.Line, SeverityNit),
+					Side:     SideRight,
+					Severity: string(SeverityNit),
 				})
 				result.Stats.NitpicksAdded++
 			}
-			fmt.Printf("   Added %d extra nitpicks\n", len(nitpicks.Nitpicks))
+			fmt.Fprintf(r.out, "   Added %d extra nitpicks\n", len(nitpicks.Nitpicks))
 		}
 	}
 
-	// Generate summary
-	result.Summary = r.generateSummary(result, pr)
+	// Salty's own checks and the extra nitpicks have severities too
+	r.dropBelowSeverity(result, opts.MinSeverity)
+	r.mergeSameLineComments(result)
 
-	// Post the review (unless dry run)
-	if dryRun {
-		fmt.Println("\n📋 DRY RUN - Would post the following review:")
-		fmt.Println("─────────────────────────────────────────")
-		fmt.Println(result.Summary)
-		for _, c := range result.Comments {
-			fmt.Printf("\n📍 %s:%d\n%s\n", c.Path, c.Line, c.Body)
+	capCommentsPerFile(result, r.config.MaxCommentsPerFile)
+	capComments(result, r.config.MaxComments)
+	if len(result.Rollups) > 0 {
+		fmt.Fprintln(r.out, "🗂️  Rolled up comments beyond max_comments_per_file or max_comments:")
+		for _, rollup := range result.Rollups {
+			fmt.Fprintf(r.out, "   %s: %d left out\n", rollup.File, len(rollup.Lines))
+		}
+	}
+
+	return result, nil
+}
+
+// fetchLinkedIssues loads the issues the PR body says it resolves. Issues that
+// can't be fetched are skipped with a warning.
+func (r *Reviewer) fetchLinkedIssues(ref *github.PRReference, pr *github.PullRequest) []*github.Issue {
+	const maxLinkedIssues = 3
+
+	var issues []*github.Issue
+	for _, ir := range github.ParseIssueReferences(pr.GetBody(), ref) {
+		if len(issues) == maxLinkedIssues {
+			break
+		}
+		issue, err := r.githubClient.GetIssue(ir.Owner, ir.Repo, ir.Number)
+		if err != nil {
+			r.out.Warnf("⚠️  Skipping linked issue: %v", err)
+			continue
+		}
+		fmt.Fprintf(r.out, "🔗 Linked issue #%d: %s\n", issue.Number, issue.Title)
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// confidenceThreshold is the minimum confidence (in percent) an issue needs to be posted
+func confidenceThreshold(effectiveNitpicky int) int {
+	return 90 - (effectiveNitpicky * 5) // Level 1 = 85%, Level 10 = 40%
+}
+
+// deepConfirm runs deep analysis on each first-pass issue and keeps those that
+// pass the confidence threshold for the effective nitpicky level. Up to
+// config.Concurrency issues are analyzed at once; each one's progress is
+// buffered and printed in order, and confirmed issues keep the input order.
+// It stops starting new issues with ai.ErrBudgetExceeded when the token budget
+// runs out, github.ErrBadCredentials when the token stops working or
+// github.ErrRateLimited when GitHub throttles the review, returning
+// what was confirmed so far and how many issues were analyzed. The outcome of
+// each issue is recorded in decisions, which is parallel to issues. The
+// redactions are stripped from file content before it goes into a prompt.
+func (r *Reviewer) deepConfirm(ref *github.PRReference, sha string, files []*github.FileChange, issues []Issue, effectiveNitpicky int, redactions []string, decisions []Decision) ([]AnalyzedIssue, int, error) {
+	byName := make(map[string]*github.FileChange, len(files))
+	for _, f := range files {
+		byName[f.Filename] = f
+	}
+
+	concurrency := r.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type outcome struct {
+		confirmed *AnalyzedIssue
+		err       error
+		started   bool
+		out       bytes.Buffer
+		done      chan struct{}
+	}
+	outcomes := make([]outcome, len(issues))
+	for i := range outcomes {
+		outcomes[i].done = make(chan struct{})
+	}
+
+	var stopped atomic.Bool
+	go func() {
+		sem := make(chan struct{}, concurrency)
+		for i, issue := range issues {
+			sem <- struct{}{}
+			o := &outcomes[i]
+			if stopped.Load() {
+				<-sem
+				close(o.done)
+				continue
+			}
+			o.started = true
+			go func(i int, issue Issue) {
+				defer func() { <-sem }()
+				defer close(o.done)
+
+				// Everything the worker logs, AI debug output and dumps
+				// included, goes to its buffer
+				worker := *r
+				worker.out = r.out.To(&o.out)
+				worker.aiClient = r.aiClient.LoggingTo(worker.out.Debugf)
+				analyzer := *r.analyzer
+				analyzer.aiClient, analyzer.out = worker.aiClient, worker.out
+				worker.analyzer = &analyzer
+				fmt.Fprintf(&o.out, "   [%d/%d] Analyzing: %s (line %d)...\n", i+1, len(issues), issue.File, issue.Line)
+				o.confirmed, o.err = worker.confirmIssue(i, issue, ref, sha, byName[issue.File], effectiveNitpicky, redactions, &decisions[i])
+				if o.err != nil {
+					stopped.Store(true)
+				}
+			}(i, issue)
+		}
+	}()
+
+	var confirmedIssues []AnalyzedIssue
+	var firstErr error
+	analyzed := 0
+	for i := range outcomes {
+		o := &outcomes[i]
+		<-o.done
+		r.out.Result().Write(o.out.Bytes())
+		if !o.started {
+			continue
+		}
+		if o.err != nil {
+			if firstErr == nil {
+				firstErr = o.err
+			}
+			continue
+		}
+		analyzed++
+		if o.confirmed != nil {
+			confirmedIssues = append(confirmedIssues, *o.confirmed)
 		}
-		fmt.Println("─────────────────────────────────────────")
+	}
+
+	return confirmedIssues, analyzed, firstErr
+}
+
+// confirmIssue deep-analyzes the i-th first-pass issue and records the outcome
+// in decision. It returns the issue if it passes the threshold, nil otherwise.
+// Only budget, GitHub credential and rate limit errors are returned, since
+// they stop the review; other failures are recorded and skipped.
+func (r *Reviewer) confirmIssue(i int, issue Issue, ref *github.PRReference, sha string, file *github.FileChange, effectiveNitpicky int, redactions []string, decision *Decision) (*AnalyzedIssue, error) {
+	analysis, cached := r.cache.analysis(issue, file)
+	var err error
+	if !cached {
+		analysis, err = r.analyzer.DeepAnalyze(issue, ref, sha, file, redactions)
+	}
+	if errors.Is(err, ai.ErrBudgetExceeded) || errors.Is(err, github.ErrBadCredentials) || errors.Is(err, github.ErrRateLimited) {
+		return nil, err
+	}
+	if err != nil {
+		r.out.Warnf("      ⚠️  Deep analysis failed: %v", err)
+		decision.Outcome = OutcomeAnalysisFailed
+		decision.Error = err.Error()
+		return nil, nil
+	}
+
+	if !cached {
+		r.cache.storeAnalysis(issue, file, analysis)
+	}
+
+	decision.DeepAnalyzed = true
+	decision.Confidence = analysis.Confidence
+	decision.Verdict = analysis.FinalVerdict
+
+	// Apply confidence threshold based on nitpicky level
+	threshold := confidenceThreshold(effectiveNitpicky)
+	if analysis.Confidence < threshold || analysis.FinalVerdict != "COMMENT" {
+		decision.Outcome = OutcomeBelowThreshold
+		fmt.Fprintf(r.out, "      ✗ Skipped (confidence: %d%%, threshold: %d%%)\n", analysis.Confidence, threshold)
+		return nil, nil
+	}
+
+	severity := r.resolveSeverity(issue, analysis)
+	decision.PassedThreshold = true
+	decision.Severity = string(severity)
+	if severity != "" {
+		fmt.Fprintf(r.out, "      ✓ Confirmed (confidence: %d%%, severity: %s)\n", analysis.Confidence, severity)
 	} else {
-		fmt.Println("📤 Posting review...")
-		event := "COMMENT"
-		if len(result.Comments) > 0 && effectiveNitpicky >= 7 {
-			event = "REQUEST_CHANGES"
+		fmt.Fprintf(r.out, "      ✓ Confirmed (confidence: %d%%)\n", analysis.Confidence)
+	}
+	return &AnalyzedIssue{
+		Original: issue,
+		Analysis: *analysis,
+		Severity: severity,
+		index:    i,
+	}, nil
+}
+
+// shallowConfirm keeps first-pass issues whose own confidence passes the
+// threshold, without any deep analysis. First-pass confidence is on a 1-10 scale.
+// Outcomes are recorded in decisions unless it is nil.
+func (r *Reviewer) shallowConfirm(issues []Issue, effectiveNitpicky int, decisions []Decision) []AnalyzedIssue {
+	var confirmedIssues []AnalyzedIssue
+	threshold := confidenceThreshold(effectiveNitpicky)
+
+	for i, issue := range issues {
+		confidence := issue.Confidence * 10
+		if decisions != nil {
+			decisions[i].Confidence = confidence
+		}
+		if confidence < threshold {
+			if decisions != nil {
+				decisions[i].Outcome = OutcomeBelowThreshold
+			}
+			continue
 		}
 
-		if err := r.githubClient.PostReview(ref, result.Summary, event, result.Comments); err != nil {
-			return nil, fmt.Errorf("failed to post review: %w", err)
+		analysis := DeepAnalysisResult{
+			StillAnIssue: true,
+			Confidence:   confidence,
+			Reasoning:    issue.Issue,
+			FinalVerdict: "COMMENT",
+		}
+		severity := r.resolveSeverity(issue, &analysis)
+		if decisions != nil {
+			decisions[i].PassedThreshold = true
+			decisions[i].Severity = string(severity)
 		}
-		result.Stats.CommentsPosted = len(result.Comments)
-		fmt.Printf("✅ Review posted with %d comments\n", len(result.Comments))
+		confirmedIssues = append(confirmedIssues, AnalyzedIssue{
+			Original: issue,
+			Analysis: analysis,
+			Severity: severity,
+			index:    i,
+		})
 	}
 
-	return result, nil
+	return confirmedIssues
+}
+
+// recordUsage puts the AI tokens used since before, and their estimated cost,
+// into the result's stats and prints them
+func (r *Reviewer) recordUsage(result *ReviewResult, before ai.Usage) {
+	used := r.aiClient.Usage().Sub(before)
+	result.Stats.TokensUsed = used.TotalTokens
+	result.Stats.EstimatedCostUSD = r.config.EstimateCostUSD(used.PromptTokens, used.CompletionTokens, used.TotalTokens)
+
+	if result.Stats.EstimatedCostUSD > 0 {
+		fmt.Fprintf(r.out, "🪙 %d AI tokens used (~$%.2f)\n", used.TotalTokens, result.Stats.EstimatedCostUSD)
+	} else {
+		fmt.Fprintf(r.out, "🪙 %d AI tokens used\n", used.TotalTokens)
+	}
+}
+
+// publish posts the review, or prints it in dry-run mode. An empty commitID
+// anchors the review to the PR head.
+func (r *Reviewer) publish(ref *github.PRReference, pr *github.PullRequest, commitID string, result *ReviewResult, event string, opts ReviewOptions) error {
+	r.sign(result)
+
+	if opts.DryRun {
+		w := r.out.Result()
+		fmt.Fprintln(w, "\n📋 DRY RUN - Would post the following review:")
+		fmt.Fprintln(w, "─────────────────────────────────────────")
+		fmt.Fprintf(w, "Event: %s\n\n", event)
+		fmt.Fprintln(w, result.Summary)
+		for _, c := range result.Comments {
+			fmt.Fprintf(w, "\n📍 %s\n%s\n", commentLocation(c), c.Body)
+		}
+		fmt.Fprintln(w, "─────────────────────────────────────────")
+		if opts.ShowPayload {
+			r.printPayload(commitID, result, event)
+		}
+		return nil
+	}
+
+	if result.Stats.BudgetExceeded && !opts.Partial {
+		fmt.Fprintln(r.out, "💸 Not posting a partial review (use --partial to post what was confirmed)")
+		return fmt.Errorf("review stopped early: %w", ai.ErrBudgetExceeded)
+	}
+
+	if r.config.PostMode == config.PostModeChecks {
+		if err := r.publishCheckRun(ref, pr, commitID, result, event, opts.RequestReviewers); err != nil {
+			return err
+		}
+		r.setCommitStatus(ref, pr, commitID, result, event)
+		return nil
+	}
+
+	fmt.Fprintln(r.out, "📤 Posting review...")
+
+	// GitHub rejects a review with neither a body nor comments
+	if strings.TrimSpace(result.Summary) == "" {
+		result.Summary = r.noIssuesText()
+	}
+
+	reviewIDs, err := r.postReview(ref, commitID, result, event)
+	if err != nil {
+		return fmt.Errorf("failed to post review: %w", err)
+	}
+	fmt.Fprintf(r.out.Result(), "✅ Review posted with %d comments\n", result.Stats.CommentsPosted)
+
+	if r.config.PostDigest && result.Stats.CommentsPosted > 0 {
+		r.postDigest(ref, reviewIDs, result)
+	}
+
+	r.applyLabels(ref, event, len(result.Comments) == 0)
+
+	if event == "REQUEST_CHANGES" {
+		r.requestReviewers(ref, pr.GetUser().GetLogin(), opts.RequestReviewers)
+	}
+
+	r.setCommitStatus(ref, pr, commitID, result, event)
+
+	return nil
+}
+
+// printPayload prints the request body publish would send to GitHub's create
+// review endpoint
+func (r *Reviewer) printPayload(commitID string, result *ReviewResult, event string) {
+	if r.config.PostMode == config.PostModeChecks {
+		r.out.Warnf("⚠️  post_mode is checks, so no review request would be sent")
+		return
+	}
+	summary := result.Summary
+	if strings.TrimSpace(summary) == "" {
+		summary = r.noIssuesText()
+	}
+	payload, err := github.ReviewPayload(commitID, summary, event, result.Comments)
+	if err != nil {
+		r.out.Warnf("⚠️  Could not encode the review request: %v", err)
+		return
+	}
+	fmt.Fprintln(r.out.Result(), "\n📦 GitHub review request:")
+	fmt.Fprintln(r.out.Result(), string(payload))
+}
+
+// confirmComments asks opts.ConfirmComment about each comment, before the
+// summary and event are worked out from what is left. Nothing is asked on
+// a dry run, since nothing gets posted.
+func (r *Reviewer) confirmComments(result *ReviewResult, opts ReviewOptions) {
+	if opts.ConfirmComment == nil || opts.DryRun || len(result.Comments) == 0 {
+		return
+	}
+
+	var kept []*github.ReviewComment
+	for _, c := range result.Comments {
+		body, ok := opts.ConfirmComment(c)
+		if !ok {
+			dropFinding(result, c, OutcomeSkippedByUser)
+			result.Stats.CommentsSkipped++
+			continue
+		}
+		c.Body = body
+		kept = append(kept, c)
+	}
+	result.Comments = kept
+
+	if result.Stats.CommentsSkipped > 0 {
+		fmt.Fprintf(r.out, "🙅 Left out %d of %d comment(s)\n",
+			result.Stats.CommentsSkipped, result.Stats.CommentsSkipped+len(kept))
+	}
+}
+
+// sign adds the review signature to the summary and every comment
+func (r *Reviewer) sign(result *ReviewResult) {
+	result.Summary = signature.Sign(result.Summary, signature.ModeReview, r.config.ReviewSignature)
+	for _, c := range result.Comments {
+		c.Body = signature.Sign(c.Body, signature.ModeReview, r.config.ReviewSignature)
+	}
+}
+
+// decideEvent picks the review event: REQUEST_CHANGES when a comment is of
+// major or critical severity, secrets outside test code included, or there
+// are merge conflicts, COMMENT otherwise. With
+// approve_if_only_nits set, a review with nothing above nit severity approves
+// instead (unless author is us - GitHub won't let you approve your own PR).
+// With approve_clean_prs set, a posted review without comments approves too,
+// unless the author is a disliked reviewer. With confirm_request_changes set,
+// requesting changes needs the user's go-ahead.
+func (r *Reviewer) decideEvent(result *ReviewResult, author string, opts ReviewOptions) string {
+	event := "COMMENT"
+	if hasBlocking(result) || result.Stats.ConflictMarkers > 0 {
+		event = "REQUEST_CHANGES"
+	}
+	// Requesting changes without saying what to change isn't a review
+	if len(result.Comments) == 0 {
+		event = "COMMENT"
+	}
+
+	if r.config.ApproveIfOnlyNits && onlyNits(result) {
+		if r.isSelf(author) {
+			event = "COMMENT"
+		} else {
+			event = "APPROVE"
+		}
+	}
+
+	// A budget-stopped review found nothing because it stopped looking, and
+	// one that skipped files didn't read them
+	approveClean := r.config.ApproveCleanPRs || opts.ApproveClean
+	if approveClean && len(result.Comments) == 0 && !opts.DryRun && !result.Stats.BudgetExceeded &&
+		result.Stats.FilesReviewed > 0 && result.Stats.FilesSkipped == 0 &&
+		result.Stats.ConflictMarkers == 0 && !r.config.IsDislikedReviewer(author) && !r.isSelf(author) {
+		event = "APPROVE"
+	}
+
+	// Nothing will be posted in a dry run or a budget-stopped run, so don't ask
+	willPost := !opts.DryRun && (!result.Stats.BudgetExceeded || opts.Partial)
+	if event == "REQUEST_CHANGES" && willPost && r.config.ConfirmRequestChanges && !opts.AllowRequestChanges {
+		if opts.ConfirmRequestChanges == nil || !opts.ConfirmRequestChanges(result) {
+			fmt.Fprintln(r.out, "🤝 Not confirmed - posting as a comment instead of requesting changes")
+			event = "COMMENT"
+		}
+	}
+
+	return event
+}
+
+// hasBlocking reports whether any comment is of major or critical severity
+func hasBlocking(result *ReviewResult) bool {
+	for _, c := range result.Comments {
+		if Severity(c.Severity).Rank() >= SeverityMajor.Rank() {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyNits reports whether a review has comments and all of them are nit or
+// info severity. Comments without a severity count as blocking.
+func onlyNits(result *ReviewResult) bool {
+	if len(result.Comments) == 0 {
+		return false
+	}
+	for _, c := range result.Comments {
+		switch Severity(c.Severity) {
+		case SeverityNit, SeverityInfo:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isSelf reports whether author is the authenticated user. If that can't be
+// told (no author, or the lookup fails) it errs on the side of yes.
+func (r *Reviewer) isSelf(author string) bool {
+	if author == "" {
+		return true
+	}
+	me, err := state.ResolveUsername(r.config.GitHubToken, r.aiClient.Clock(), r.githubClient.GetAuthenticatedUser)
+	if err != nil {
+		r.out.Warnf("⚠️  Could not tell who you are, so not approving: %v", err)
+		return true
+	}
+	return strings.EqualFold(me, author)
+}
+
+// requestReviewers asks the configured teammates for a re-review. Invalid
+// usernames and the PR author are skipped; failures don't fail the review.
+func (r *Reviewer) requestReviewers(ref *github.PRReference, author string, extra []string) {
+	var valid []string
+	for _, u := range append(append([]string{}, r.config.RequestReviewers...), extra...) {
+		u = strings.TrimPrefix(strings.TrimSpace(u), "@")
+		switch {
+		case u == "":
+			continue
+		case !github.IsValidUsername(u):
+			r.out.Warnf("⚠️  Not requesting review from %q: not a valid GitHub username", u)
+		case strings.EqualFold(u, author):
+			r.out.Warnf("⚠️  Not requesting review from @%s: they wrote the PR", u)
+		case !containsFold(valid, u):
+			valid = append(valid, u)
+		}
+	}
+	if len(valid) == 0 {
+		return
+	}
+
+	if err := r.githubClient.RequestReviewers(ref, valid); err != nil {
+		r.out.Warnf("⚠️  %v", err)
+		return
+	}
+	fmt.Fprintf(r.out, "👀 Requested re-review from @%s\n", strings.Join(valid, ", @"))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLabels swaps the configured changes/approve labels to match the review
+// outcome. Label failures are reported but don't fail the review.
+func (r *Reviewer) applyLabels(ref *github.PRReference, event string, clean bool) {
+	var add, remove string
+	switch {
+	case event == "REQUEST_CHANGES":
+		add, remove = r.config.LabelOnChanges, r.config.LabelOnApprove
+	case clean || event == "APPROVE":
+		add, remove = r.config.LabelOnApprove, r.config.LabelOnChanges
+	default:
+		return
+	}
+
+	if remove != "" {
+		if err := r.githubClient.RemoveLabels(ref, []string{remove}); err != nil {
+			r.out.Warnf("⚠️  %v", err)
+		}
+	}
+	if add != "" {
+		if err := r.githubClient.AddLabels(ref, []string{add}); err != nil {
+			r.out.Warnf("⚠️  %v", err)
+			return
+		}
+		fmt.Fprintf(r.out, "🏷️  Labeled PR with %q\n", add)
+	}
+}
+
+// ownership is what filterOwnedFiles needs to know who owns which files
+type ownership struct {
+	codeowners *github.Codeowners
+	me         string // the authenticated user
+}
+
+// loadOwnership fetches the CODEOWNERS file at the PR's base and looks up the
+// authenticated user. It returns nil, after warning, if there is no CODEOWNERS
+// file, in which case every file is reviewed.
+func (r *Reviewer) loadOwnership(ref *github.PRReference, pr *github.PullRequest) (*ownership, error) {
+	codeowners, err := r.githubClient.GetCodeowners(ref.Owner, ref.Repo, pr.GetBase().GetSHA())
+	if err != nil {
+		return nil, err
+	}
+	if codeowners == nil {
+		r.out.Warnf("⚠️  respect_codeowners is set but no CODEOWNERS file was found - reviewing all files")
+		return nil, nil
+	}
+
+	me, err := state.ResolveUsername(r.config.GitHubToken, r.aiClient.Clock(), r.githubClient.GetAuthenticatedUser)
+	if err != nil {
+		return nil, err
+	}
+	return &ownership{codeowners: codeowners, me: me}, nil
+}
+
+// filterOwnedFiles keeps only the files the authenticated user owns per CODEOWNERS
+func (r *Reviewer) filterOwnedFiles(own *ownership, files []*github.FileChange) []*github.FileChange {
+	if own == nil {
+		return files
+	}
+
+	var owned []*github.FileChange
+	for _, f := range files {
+		if r.githubClient.IsOwner(own.me, own.codeowners.OwnersOf(f.Filename)) {
+			owned = append(owned, f)
+		}
+	}
+
+	fmt.Fprintf(r.out, "👑 CODEOWNERS: @%s owns %d of %d changed files (skipping %d)\n",
+		own.me, len(owned), len(files), len(files)-len(owned))
+	return owned
+}
+
+// filterGlobFiles drops files left out by include_globs and exclude_globs
+func (r *Reviewer) filterGlobFiles(files []*github.FileChange) []*github.FileChange {
+	if len(r.config.IncludeGlobs) == 0 && len(r.config.ExcludeGlobs) == 0 {
+		return files
+	}
+
+	var kept []*github.FileChange
+	for _, f := range files {
+		if r.config.ReviewsFile(f.Filename) {
+			kept = append(kept, f)
+		}
+	}
+
+	if skipped := len(files) - len(kept); skipped > 0 {
+		fmt.Fprintf(r.out, "🙈 Skipping %d of %d changed files (include_globs/exclude_globs)\n", skipped, len(files))
+	}
+	return kept
+}
+
+// filterReviewableFiles drops files the AI can't make sense of: binary and
+// removed files, and diffs bigger than max_file_diff_lines. It also returns
+// how many files were skipped with changes left unread; removed and
+// unchanged files have none.
+func (r *Reviewer) filterReviewableFiles(files []*github.FileChange) ([]*github.FileChange, int) {
+	var kept []*github.FileChange
+	skipped := 0
+	for _, f := range files {
+		if reason := unreviewableReason(f, r.config.MaxFileDiffLines); reason != "" {
+			fmt.Fprintf(r.out, "⏭️  Skipping %s (%s)\n", f.Filename, reason)
+			if f.Status != "removed" && f.Status != "unchanged" {
+				skipped++
+			}
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, skipped
+}
+
+// unreviewableReason says why a file shouldn't go to the AI, or returns ""
+func unreviewableReason(f *github.FileChange, maxLines int) string {
+	switch {
+	case f.Status == "removed" || f.Status == "unchanged":
+		return f.Status
+	case strings.TrimSpace(f.Patch) == "":
+		// GitHub and git send no patch for binary files
+		return "binary or no diff"
+	case strings.ContainsRune(f.Patch, 0):
+		return "binary"
+	case maxLines > 0 && f.Additions+f.Deletions > maxLines:
+		return fmt.Sprintf("%d changed lines, max_file_diff_lines is %d", f.Additions+f.Deletions, maxLines)
+	}
+	return ""
+}
+
+// filterAuthoredFiles keeps only the files that author touched in at least one
+// of the PR's commits. Files changed only by other committers are skipped.
+func (r *Reviewer) filterAuthoredFiles(ref *github.PRReference, files []*github.FileChange, author string) ([]*github.FileChange, error) {
+	commits, err := r.githubClient.ListPRCommits(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	touched := make(map[string]bool)
+	for _, commit := range commits {
+		if !strings.EqualFold(commit.Author, author) {
+			continue
+		}
+		commitFiles, err := r.githubClient.GetCommitFiles(ref.Owner, ref.Repo, commit.SHA)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range commitFiles {
+			touched[f.Filename] = true
+		}
+	}
+
+	var authored []*github.FileChange
+	for _, f := range files {
+		if touched[f.Filename] {
+			authored = append(authored, f)
+		}
+	}
+
+	fmt.Fprintf(r.out, "✍️  @%s contributed to %d of %d changed files (skipping %d)\n",
+		author, len(authored), len(files), len(files)-len(authored))
+	return authored, nil
 }
 
 func (r *Reviewer) formatComment(issue AnalyzedIssue) (string, error) {
 	issueDesc := fmt.Sprintf("Issue: %s\nCode: %s", issue.Original.Issue, issue.Original.Code)
+	replacement := suggestion(issue)
+	if replacement != "" {
+		issueDesc += "\nSuggested replacement:\n" + replacement
+	}
 	analysisDesc := fmt.Sprintf("Reasoning: %s", issue.Analysis.Reasoning)
 
-	prompt := GetCommentFormattingPrompt(issueDesc, analysisDesc, r.config.WritingStyle)
+	prompt := GetCommentFormattingPrompt(issueDesc, analysisDesc, r.config)
 
 	messages := []ai.Message{
-		ai.SystemMessage(GetSystemPrompt(r.config.WritingStyle, r.config.NitpickyLevel)),
+		ai.SystemMessage(GetSystemPrompt(r.config, r.config.NitpickyLevel)),
 		ai.UserMessage(prompt),
 	}
 
-	return r.aiClient.Chat(messages)
+	comment, err := r.chatFormatting(messages)
+	if err != nil {
+		return "", err
+	}
+
+	comment = r.finishComment(comment, issue.Original.File, issue.Original.Line, issue.Severity)
+
+	// Added last so condensing can't cut into the code
+	if replacement != "" {
+		comment += "\n\n" + suggestionBlock(replacement)
+	}
+	return comment, nil
+}
+
+// finishComment runs a comment the AI wrote for file:line through the
+// comment hook, condenses it to max_comment_chars and adds its severity badge
+func (r *Reviewer) finishComment(comment, file string, line int, severity Severity) string {
+	comment, err := hook.Run(r.config.CommentHook, r.config.CommentHookTimeout(), comment, map[string]string{
+		"SALTY_MODE": "review",
+		"SALTY_FILE": file,
+		"SALTY_LINE": strconv.Itoa(line),
+	})
+	if err != nil {
+		r.out.Warnf("   ⚠️  %v - keeping the comment as is", err)
+	}
+
+	comment, err = condense.Condense(r.aiClient, comment, r.config.MaxCommentChars)
+	if err != nil {
+		r.out.Warnf("   ⚠️  %v - truncating instead", err)
+	}
+
+	if badge := severity.Badge(); badge != "" {
+		comment = badge + " " + comment
+	}
+	return comment
+}
+
+// chatFormatting is ChatFormatting, streamed to the progress output when live
+// output is on
+func (r *Reviewer) chatFormatting(messages []ai.Message) (string, error) {
+	if !r.live {
+		return r.aiClient.ChatFormatting(messages)
+	}
+
+	printer := live.NewPrinter(r.out)
+	comment, err := r.aiClient.ChatFormattingStream(messages, printer.Print)
+	printer.End()
+	if errors.Is(err, ai.ErrStreamingUnsupported) {
+		return r.aiClient.ChatFormatting(messages)
+	}
+	return comment, err
 }
 
-func (r *Reviewer) generateSummary(result *ReviewResult, pr *github.PullRequest) string {
+func (r *Reviewer) generateSummary(result *ReviewResult, pr *github.PullRequest, event string) string {
 	var sb strings.Builder
 
+	if r.config.ShowVerdict {
+		sb.WriteString(r.verdictLine(result, event))
+		sb.WriteString("\n\n")
+	}
+
+	if r.config.SummaryGreeting != "" {
+		sb.WriteString(expandSummaryTemplate(r.config.SummaryGreeting, pr))
+		sb.WriteString("\n\n")
+	}
+
 	switch r.config.WritingStyle {
 	case config.StyleCorporate:
 		sb.WriteString("## Code Review Summary\n\n")
@@ -238,21 +1557,123 @@ func (r *Reviewer) generateSummary(result *ReviewResult, pr *github.PullRequest)
 		sb.WriteString("several observations warrant discussion.\n\n")
 	}
 
+	if result.Stats.ConflictMarkers > 0 {
+		sb.WriteString(fmt.Sprintf("> 🚨 **This PR contains %d unresolved merge conflict(s).** ", result.Stats.ConflictMarkers))
+		sb.WriteString("Resolve them before addressing anything else.\n\n")
+	}
+
+	if result.Stats.SecretsFound > 0 {
+		sb.WriteString(fmt.Sprintf("> 🔐 **This PR appears to add %d secret(s).** ", result.Stats.SecretsFound))
+		sb.WriteString("Remove and rotate them - they're in the git history now.\n\n")
+	}
+
+	if result.Stats.BudgetExceeded {
+		sb.WriteString(fmt.Sprintf("> 💸 **Partial review:** the token budget ran out after analyzing %d of %d potential issues.\n\n",
+			result.Stats.IssuesAnalyzed, result.Stats.IssuesFound))
+	}
+
+	if result.SquashBase != "" {
+		sb.WriteString(fmt.Sprintf("> 🧮 **Pre-merge review:** this covers the combined diff as it will land on `%s` when squash-merged.\n\n",
+			result.SquashBase))
+	}
+
+	if result.Stats.DeepAnalysisSkipped {
+		sb.WriteString("> ⚡ **Fast review:** this is a shallow first pass without deep analysis, ")
+		sb.WriteString("so some comments may lack context.\n\n")
+	}
+
+	if len(result.IssueGaps) > 0 {
+		sb.WriteString("### 🔗 Linked issue coverage\n\n")
+		sb.WriteString("This PR may not fully address ")
+		for i, issue := range result.LinkedIssues {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(fmt.Sprintf("#%d", issue.Number))
+		}
+		sb.WriteString(":\n")
+		for _, gap := range result.IssueGaps {
+			sb.WriteString(fmt.Sprintf("- %s\n", gap))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(result.Images) > 0 {
+		writeImageNote(&sb, result.Images)
+	}
+
+	if len(result.Dependencies) > 0 {
+		writeDependencyNote(&sb, result.Dependencies)
+	}
+
+	if len(result.Rollups) > 0 {
+		writeRollupNote(&sb, result.Rollups)
+	}
+
 	sb.WriteString(fmt.Sprintf("**Files reviewed:** %d\n", result.Stats.FilesReviewed))
+	if result.Stats.FilesSkipped > 0 {
+		sb.WriteString(fmt.Sprintf("**Files skipped:** %d\n", result.Stats.FilesSkipped))
+	}
 	sb.WriteString(fmt.Sprintf("**Comments:** %d\n\n", len(result.Comments)))
 
 	if len(result.Comments) == 0 {
-		switch r.config.WritingStyle {
-		case config.StyleCorporate:
-			sb.WriteString("No significant issues identified at this time. Approved pending standard verification procedures.")
-		case config.StylePassiveAggressive:
-			sb.WriteString("I couldn't find anything to comment on. I'm sure it's fine. Probably.")
-		case config.StyleTechBro:
-			sb.WriteString("LGTM! Ship it. 🚀")
-		case config.StyleAcademic:
-			sb.WriteString("The implementation appears sound. No substantive concerns identified.")
-		}
+		sb.WriteString(r.noIssuesText())
+	}
+
+	if r.config.SummarySignoff != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(expandSummaryTemplate(r.config.SummarySignoff, pr))
 	}
 
 	return sb.String()
 }
+
+// expandSummaryTemplate fills in the {author} and {pr_title} variables of a
+// greeting or sign-off. pr is nil for local diff reviews.
+func expandSummaryTemplate(tmpl string, pr *github.PullRequest) string {
+	author := "there"
+	if login := pr.GetUser().GetLogin(); login != "" {
+		author = "@" + login
+	}
+	return strings.NewReplacer(
+		"{author}", author,
+		"{pr_title}", pr.GetTitle(),
+	).Replace(tmpl)
+}
+
+// noIssuesText is the per-style note for a review that found nothing
+func (r *Reviewer) noIssuesText() string {
+	switch r.config.WritingStyle {
+	case config.StyleCorporate:
+		return "No significant issues identified at this time. Approved pending standard verification procedures."
+	case config.StylePassiveAggressive:
+		return "I couldn't find anything to comment on. I'm sure it's fine. Probably."
+	case config.StyleTechBro:
+		return "LGTM! Ship it. 🚀"
+	case config.StyleAcademic:
+		return "The implementation appears sound. No substantive concerns identified."
+	default:
+		return "No issues found."
+	}
+}
+
+// stripMovedCode returns copies of the files with moved blocks removed from
+// their patches, leaving out files with no new code at all
+func stripMovedCode(files []*github.FileChange) []*github.FileChange {
+	patches := make(map[string]string, len(files))
+	for _, f := range files {
+		patches[f.Filename] = f.Patch
+	}
+	stripped, _ := diff.StripMoves(patches)
+
+	var kept []*github.FileChange
+	for _, f := range files {
+		if stripped[f.Filename] == "" {
+			continue
+		}
+		c := *f
+		c.Patch = stripped[f.Filename]
+		kept = append(kept, &c)
+	}
+	return kept
+}
@@ -0,0 +1,246 @@
+package reviewer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/logging"
+)
+
+func TestConfidenceThreshold(t *testing.T) {
+	tests := []struct {
+		nitpicky   int
+		confidence int
+		want       bool
+	}{
+		{10, 70, true},
+		{1, 70, false},
+		{1, 85, true},
+		{10, 39, false},
+		{10, 40, true},
+	}
+	for _, tt := range tests {
+		if got := tt.confidence >= confidenceThreshold(tt.nitpicky); got != tt.want {
+			t.Errorf("confidence %d at nitpicky level %d passes = %t, want %t", tt.confidence, tt.nitpicky, got, tt.want)
+		}
+	}
+}
+
+func TestShallowConfirmThreshold(t *testing.T) {
+	r := &Reviewer{config: config.DefaultConfig(), out: logging.New(io.Discard, config.VerbosityNormal)}
+	// A first-pass confidence of 7 is 70 on the 0-100 scale
+	issues := []Issue{{File: "main.go", Line: 3, Issue: "maybe nil", Confidence: 7}}
+
+	for _, tt := range []struct {
+		nitpicky int
+		want     int
+	}{{10, 1}, {1, 0}} {
+		decisions := newDecisions(issues, confidenceThreshold(tt.nitpicky))
+		confirmed := r.shallowConfirm(issues, tt.nitpicky, decisions)
+		if len(confirmed) != tt.want {
+			t.Errorf("nitpicky level %d confirmed %d issues, want %d", tt.nitpicky, len(confirmed), tt.want)
+		}
+		if decisions[0].Confidence != 70 {
+			t.Errorf("decision confidence = %d, want 70", decisions[0].Confidence)
+		}
+	}
+}
+
+func TestFilterReviewableFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		file github.FileChange
+		want bool // the file is kept
+	}{
+		{"text diff", github.FileChange{Filename: "main.go", Status: "modified", Patch: "@@ -1 +1 @@\n-a\n+b", Additions: 1, Deletions: 1}, true},
+		{"NUL byte in patch", github.FileChange{Filename: "logo.png", Status: "modified", Patch: "@@ -1 +1 @@\n+\x00PNG", Additions: 1}, false},
+		{"no patch", github.FileChange{Filename: "app.wasm", Status: "added"}, false},
+		{"whitespace patch", github.FileChange{Filename: "blank.txt", Status: "modified", Patch: " \n"}, false},
+		{"removed", github.FileChange{Filename: "old.go", Status: "removed", Patch: "@@ -1 +0,0 @@\n-a", Deletions: 1}, false},
+		{"at max_file_diff_lines", github.FileChange{Filename: "big.go", Status: "modified", Patch: "+a", Additions: 60, Deletions: 40}, true},
+		{"over max_file_diff_lines", github.FileChange{Filename: "huge.go", Status: "modified", Patch: "+a", Additions: 60, Deletions: 41}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.MaxFileDiffLines = 100
+			r := &Reviewer{config: cfg, out: logging.New(io.Discard, config.VerbosityNormal)}
+
+			file := tt.file
+			kept, _ := r.filterReviewableFiles([]*github.FileChange{&file})
+			if got := len(kept) == 1; got != tt.want {
+				t.Errorf("kept = %t, want %t (reason %q)", got, tt.want, unreviewableReason(&file, cfg.MaxFileDiffLines))
+			}
+		})
+	}
+}
+
+// userStub answers GitHub's authenticated user endpoint as @me
+type userStub struct{}
+
+func (userStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"login": "me"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestDecideEventApproveClean(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tests := []struct {
+		name     string
+		reviewed int
+		skipped  int
+		want     string
+	}{
+		{"every file read", 3, 0, "APPROVE"},
+		{"every file filtered out", 0, 0, "COMMENT"},
+		{"some files skipped", 2, 1, "COMMENT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reviewer{
+				config:       config.DefaultConfig(),
+				githubClient: github.NewClient("token", github.WithTransport(userStub{})),
+				aiClient:     ai.NewClient("", "", ""),
+				out:          logging.New(io.Discard, config.VerbosityNormal),
+			}
+			result := &ReviewResult{Stats: ReviewStats{FilesReviewed: tt.reviewed, FilesSkipped: tt.skipped}}
+			if got := r.decideEvent(result, "author", ReviewOptions{ApproveClean: true}); got != tt.want {
+				t.Errorf("event = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeClock is stopped at now and records how long it was asked to sleep
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time        { return c.now }
+func (c *fakeClock) Sleep(d time.Duration) { c.slept += d }
+
+// rateLimitStub answers GitHub's rate limit endpoint
+type rateLimitStub struct {
+	remaining int
+	reset     time.Time
+}
+
+func (s rateLimitStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := fmt.Sprintf(`{"resources": {"core": {"limit": 5000, "remaining": %d, "reset": %d}}}`, s.remaining, s.reset.Unix())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestCheckRateLimitWaits(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name      string
+		remaining int
+		want      time.Duration
+	}{
+		{"enough quota", 5000, 0},
+		{"quota spent", 0, time.Minute + time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := &fakeClock{now: now}
+			stub := rateLimitStub{remaining: tt.remaining, reset: now.Add(time.Minute)}
+			r := &Reviewer{
+				config:       config.DefaultConfig(),
+				githubClient: github.NewClient("token", github.WithTransport(stub), github.WithClock(clk)),
+				out:          logging.New(io.Discard, config.VerbosityNormal),
+			}
+			ref := &github.PRReference{Owner: "o", Repo: "r", Number: 1}
+			if err := r.checkRateLimit(ref, 3, ReviewOptions{WaitForRateLimit: true}); err != nil {
+				t.Fatalf("checkRateLimit: %v", err)
+			}
+			if clk.slept != tt.want {
+				t.Errorf("slept %v, want %v", clk.slept, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretFindingsInTests(t *testing.T) {
+	tests := []struct {
+		file string
+		want Severity
+	}{
+		{"internal/auth/client.go", SeverityCritical},
+		{"internal/auth/client_test.go", SeverityMinor},
+		{"internal/auth/testdata/creds.json", SeverityMinor},
+		{"src/__tests__/api.js", SeverityMinor},
+		{"src/api.spec.ts", SeverityMinor},
+		{"tests/test_api.py", SeverityMinor},
+		{"src/contest.go", SeverityCritical},
+	}
+	for _, tt := range tests {
+		_, comments := secretFindings([]SecretMatch{{File: tt.file, Line: 1, Kind: "GitHub token"}})
+		if got := Severity(comments[0].Severity); got != tt.want {
+			t.Errorf("secret in %s is %s, want %s", tt.file, got, tt.want)
+		}
+	}
+}
+
+// commitsStub answers GitHub's endpoints for a PR of commits that each change
+// main.go, in a repository without a CODEOWNERS file
+type commitsStub struct {
+	shas []string
+}
+
+func (s commitsStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, `{"message": "Not Found"}`
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/pulls/1/commits"):
+		var commits []string
+		for _, sha := range s.shas {
+			commits = append(commits, fmt.Sprintf(`{"sha": %q, "commit": {"message": "change"}}`, sha))
+		}
+		status, body = http.StatusOK, "["+strings.Join(commits, ",")+"]"
+	case strings.Contains(req.URL.Path, "/commits/"):
+		status, body = http.StatusOK, `{"files": [{"filename": "main.go", "status": "modified", "patch": "@@ -1 +1 @@\n-a\n+b"}]}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestReviewPerCommitLoadsCodeownersOnce(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RespectCodeowners = true
+	cfg.ExcludeGlobs = []string{"*"} // nothing reaches the AI
+	var out strings.Builder
+	r := &Reviewer{
+		config:       cfg,
+		githubClient: github.NewClient("token", github.WithTransport(commitsStub{shas: []string{"aaa1111", "bbb2222", "ccc3333"}})),
+		aiClient:     ai.NewClient("", "", ""),
+		out:          logging.New(&out, config.VerbosityNormal),
+	}
+
+	ref := &github.PRReference{Owner: "o", Repo: "r", Number: 1}
+	if _, err := r.reviewPerCommit(ref, &github.PullRequest{}, 5, ReviewOptions{}); err != nil {
+		t.Fatalf("reviewPerCommit: %v", err)
+	}
+	if got := strings.Count(out.String(), "no CODEOWNERS file was found"); got != 1 {
+		t.Errorf("warned about the missing CODEOWNERS file %d times, want 1\n%s", got, out.String())
+	}
+}
@@ -0,0 +1,183 @@
+package reviewer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// FileRollup is what max_comments_per_file and max_comments left out of the
+// review for one file
+type FileRollup struct {
+	File  string
+	Lines []int // Lines of the comments that weren't posted
+}
+
+// capCommentsPerFile keeps the limit most important comments of each file
+// (0 = no limit) and rolls the rest up into result.Rollups for the summary.
+// Findings and decisions of dropped comments are updated to match.
+func capCommentsPerFile(result *ReviewResult, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	byFile := make(map[string][]*github.ReviewComment)
+	var order []string
+	for _, c := range result.Comments {
+		if _, seen := byFile[c.Path]; !seen {
+			order = append(order, c.Path)
+		}
+		byFile[c.Path] = append(byFile[c.Path], c)
+	}
+
+	moreImportant := byImportance(result)
+	kept := make(map[*github.ReviewComment]bool, len(result.Comments))
+	for _, file := range order {
+		comments := byFile[file]
+		sort.SliceStable(comments, func(i, j int) bool { return moreImportant(comments[i], comments[j]) })
+		for i, c := range comments {
+			if i < limit {
+				kept[c] = true
+				continue
+			}
+			rollUp(result, c)
+		}
+	}
+	keepComments(result, kept)
+}
+
+// capComments keeps the limit most important comments of the whole review
+// (0 = no limit), ranked like capCommentsPerFile, and rolls the rest up into
+// the rollups of their files
+func capComments(result *ReviewResult, limit int) {
+	if limit <= 0 || len(result.Comments) <= limit {
+		return
+	}
+
+	ranked := append([]*github.ReviewComment(nil), result.Comments...)
+	moreImportant := byImportance(result)
+	sort.SliceStable(ranked, func(i, j int) bool { return moreImportant(ranked[i], ranked[j]) })
+
+	kept := make(map[*github.ReviewComment]bool, limit)
+	for i, c := range ranked {
+		if i < limit {
+			kept[c] = true
+			continue
+		}
+		rollUp(result, c)
+	}
+	keepComments(result, kept)
+}
+
+// byImportance orders comments most severe first. Ties go to the more
+// confident comment: salty's own checks count as certain, and comments
+// without a finding to say how sure it was, like extra nitpicks, as the least
+// sure. Comments still tied keep their order under a stable sort, so merge
+// conflicts and secrets, which come first, always survive a cap.
+func byImportance(result *ReviewResult) func(a, b *github.ReviewComment) bool {
+	type anchor struct {
+		path, severity string
+		line           int
+	}
+	findingConfidence := make(map[anchor]int, len(result.Findings))
+	for _, f := range result.Findings {
+		findingConfidence[anchor{f.Original.File, string(f.Severity), f.commentLine()}] = f.Analysis.Confidence
+	}
+	confidence := func(c *github.ReviewComment) int {
+		if result.checks[c] {
+			return 100
+		}
+		return findingConfidence[anchor{c.Path, c.Severity, c.Line}]
+	}
+
+	return func(a, b *github.ReviewComment) bool {
+		ra, rb := Severity(a.Severity).Rank(), Severity(b.Severity).Rank()
+		if ra != rb {
+			return ra > rb
+		}
+		return confidence(a) > confidence(b)
+	}
+}
+
+// rollUp leaves a comment out of the review, listing its line in the rollup
+// of its file instead
+func rollUp(result *ReviewResult, c *github.ReviewComment) {
+	dropFinding(result, c, OutcomeDroppedByCap)
+	for i := range result.Rollups {
+		if rollup := &result.Rollups[i]; rollup.File == c.Path {
+			rollup.Lines = append(rollup.Lines, c.Line)
+			sort.Ints(rollup.Lines)
+			return
+		}
+	}
+	result.Rollups = append(result.Rollups, FileRollup{File: c.Path, Lines: []int{c.Line}})
+}
+
+// keepComments leaves only the kept comments in the result, in their order
+func keepComments(result *ReviewResult, kept map[*github.ReviewComment]bool) {
+	var comments []*github.ReviewComment
+	for _, c := range result.Comments {
+		if kept[c] {
+			comments = append(comments, c)
+		}
+	}
+	result.Comments = comments
+}
+
+// addChecks adds comments from salty's own checks, which are certain of
+// what they found
+func addChecks(result *ReviewResult, comments ...*github.ReviewComment) {
+	if result.checks == nil {
+		result.checks = make(map[*github.ReviewComment]bool)
+	}
+	for _, c := range comments {
+		result.checks[c] = true
+	}
+	result.Comments = append(result.Comments, comments...)
+}
+
+// commentLine is the line a finding's comment is anchored on: the last line
+// of a range, as GitHub wants multi-line comments
+func (ai AnalyzedIssue) commentLine() int {
+	return max(ai.Original.EndLine, ai.Original.Line)
+}
+
+// dropFinding removes the finding behind a comment that won't be posted and
+// marks its decision with outcome
+func dropFinding(result *ReviewResult, c *github.ReviewComment, outcome string) {
+	for i, f := range result.Findings {
+		if f.Original.File == c.Path && f.commentLine() == c.Line && string(f.Severity) == c.Severity {
+			result.Findings = append(result.Findings[:i], result.Findings[i+1:]...)
+			break
+		}
+	}
+	// Decisions keep the first line of the issue, where a range starts
+	start := c.Line
+	if c.StartLine > 0 {
+		start = c.StartLine
+	}
+	for i := range result.Decisions {
+		d := &result.Decisions[i]
+		if d.Outcome == OutcomeCommented && d.File == c.Path && d.Line == start && (d.Severity == "" || d.Severity == c.Severity) {
+			d.Outcome = outcome
+			break
+		}
+	}
+}
+
+// writeRollupNote lists the comments max_comments_per_file and max_comments
+// held back
+func writeRollupNote(sb *strings.Builder, rollups []FileRollup) {
+	sb.WriteString("### 🗂️ More in these files\n\n")
+	for _, r := range rollups {
+		lines := make([]string, len(r.Lines))
+		for i, l := range r.Lines {
+			lines[i] = fmt.Sprint(l)
+		}
+		sb.WriteString(fmt.Sprintf("- `%s`: and %s (lines %s)\n",
+			r.File, plural(len(r.Lines), "more minor issue", "more minor issues"), strings.Join(lines, ", ")))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,117 @@
+package reviewer
+
+import (
+	"testing"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+func TestCapCommentsPerFileRange(t *testing.T) {
+	// A minor issue on lines 10-14, anchored on 14 like formatComments does,
+	// and a more confident minor one on line 20
+	rangeFinding := AnalyzedIssue{
+		Original: Issue{File: "main.go", Line: 10, EndLine: 14},
+		Analysis: DeepAnalysisResult{Confidence: 60},
+		Severity: SeverityMinor,
+	}
+	lineFinding := AnalyzedIssue{
+		Original: Issue{File: "main.go", Line: 20},
+		Analysis: DeepAnalysisResult{Confidence: 95},
+		Severity: SeverityMinor,
+	}
+	rangeComment := &github.ReviewComment{Path: "main.go", StartLine: 10, Line: 14, Severity: string(SeverityMinor)}
+	lineComment := &github.ReviewComment{Path: "main.go", Line: 20, Severity: string(SeverityMinor)}
+
+	result := &ReviewResult{
+		Comments: []*github.ReviewComment{rangeComment, lineComment},
+		Findings: []AnalyzedIssue{rangeFinding, lineFinding},
+		Decisions: []Decision{
+			{File: "main.go", Line: 10, Outcome: OutcomeCommented},
+			{File: "main.go", Line: 20, Outcome: OutcomeCommented},
+		},
+	}
+	capCommentsPerFile(result, 1)
+
+	if len(result.Comments) != 1 || result.Comments[0] != lineComment {
+		t.Fatalf("kept %+v, want only the line 20 comment", result.Comments)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Original.Line != 20 {
+		t.Errorf("findings = %+v, want only the line 20 one", result.Findings)
+	}
+	if got := result.Decisions[0].Outcome; got != OutcomeDroppedByCap {
+		t.Errorf("range decision outcome = %q, want %q", got, OutcomeDroppedByCap)
+	}
+	if got := result.Decisions[1].Outcome; got != OutcomeCommented {
+		t.Errorf("line decision outcome = %q, want %q", got, OutcomeCommented)
+	}
+	if len(result.Rollups) != 1 || len(result.Rollups[0].Lines) != 1 || result.Rollups[0].Lines[0] != 14 {
+		t.Errorf("rollups = %+v, want line 14 of main.go", result.Rollups)
+	}
+}
+
+func TestCapCommentsPerFileTieBreak(t *testing.T) {
+	finding := func(line, confidence int) AnalyzedIssue {
+		return AnalyzedIssue{
+			Original: Issue{File: "main.go", Line: line},
+			Analysis: DeepAnalysisResult{Confidence: confidence},
+			Severity: SeverityNit,
+		}
+	}
+	comment := func(line int) *github.ReviewComment {
+		return &github.ReviewComment{Path: "main.go", Line: line, Severity: string(SeverityNit)}
+	}
+
+	tests := []struct {
+		name     string
+		findings []AnalyzedIssue
+		check    bool // the first comment comes from salty's own checks
+		want     int  // line of the comment kept
+	}{
+		{"more confident finding wins", []AnalyzedIssue{finding(1, 60), finding(2, 95)}, false, 2},
+		{"nitpick without a finding loses", []AnalyzedIssue{finding(2, 40)}, false, 2},
+		{"check beats any finding", []AnalyzedIssue{finding(2, 99)}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ReviewResult{Findings: tt.findings}
+			if tt.check {
+				addChecks(result, comment(1))
+			} else {
+				result.Comments = append(result.Comments, comment(1))
+			}
+			result.Comments = append(result.Comments, comment(2))
+
+			capCommentsPerFile(result, 1)
+			if len(result.Comments) != 1 || result.Comments[0].Line != tt.want {
+				t.Errorf("kept %+v, want the comment on line %d", result.Comments, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapComments(t *testing.T) {
+	comment := func(path string, line int, severity Severity) *github.ReviewComment {
+		return &github.ReviewComment{Path: path, Line: line, Severity: string(severity)}
+	}
+	nit := comment("a.go", 1, SeverityNit)
+	unrated := comment("a.go", 2, "")
+	major := comment("b.go", 3, SeverityMajor)
+	minor := comment("b.go", 4, SeverityMinor)
+
+	result := &ReviewResult{Comments: []*github.ReviewComment{nit, unrated, major, minor}}
+	capComments(result, 3)
+
+	// The unrated comment outranks the nit and the rest keep their order
+	want := []*github.ReviewComment{unrated, major, minor}
+	if len(result.Comments) != len(want) {
+		t.Fatalf("kept %d comments, want %d", len(result.Comments), len(want))
+	}
+	for i, c := range want {
+		if result.Comments[i] != c {
+			t.Errorf("comment %d is on %s:%d, want %s:%d", i, result.Comments[i].Path, result.Comments[i].Line, c.Path, c.Line)
+		}
+	}
+	if len(result.Rollups) != 1 || result.Rollups[0].File != "a.go" || result.Rollups[0].Lines[0] != 1 {
+		t.Errorf("rollups = %+v, want line 1 of a.go", result.Rollups)
+	}
+}
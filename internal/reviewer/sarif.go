@@ -0,0 +1,151 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+type sarifProperties struct {
+	Confidence int    `json:"confidence"`
+	Severity   string `json:"severity,omitempty"`
+	PR         string `json:"pr,omitempty"`
+}
+
+// WriteSARIF writes the confirmed findings of a review as a SARIF 2.1.0 log
+// with one run. Rule ids come from the issue category, e.g. salty/security.
+func WriteSARIF(path string, prRef string, result *ReviewResult) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "salty-reviewer", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	for _, f := range result.Findings {
+		id := sarifRuleID(f.Original.Category)
+		rules[id] = true
+
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.Original.File}}
+		// Removed code has no line in the file as it is now
+		if f.Original.Line > 0 && !strings.EqualFold(f.Original.Side, SideLeft) {
+			loc.Region = &sarifRegion{StartLine: f.Original.Line}
+			if f.Original.EndLine > f.Original.Line {
+				loc.Region.EndLine = f.Original.EndLine
+			}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    id,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: f.Original.Issue},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+			Properties: sarifProperties{
+				Confidence: f.Analysis.Confidence,
+				Severity:   string(f.Severity),
+				PR:         prRef,
+			},
+		})
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: strings.ReplaceAll(strings.TrimPrefix(id, "salty/"), "_", " ") + " issue"},
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode SARIF report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("could not write SARIF report: %w", err)
+	}
+	return nil
+}
+
+// sarifRuleID turns an issue category into a rule id
+func sarifRuleID(category string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	category = strings.NewReplacer(" ", "_", "-", "_").Replace(category)
+	if category == "" {
+		category = "general"
+	}
+	return "salty/" + category
+}
+
+// sarifLevel maps a severity onto the SARIF result levels
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityCritical, SeverityMajor:
+		return "error"
+	case SeverityMinor:
+		return "warning"
+	default:
+		return "note"
+	}
+}
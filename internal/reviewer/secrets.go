@@ -0,0 +1,222 @@
+package reviewer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// secretPattern recognizes one kind of credential. The first capture group is
+// the secret itself, which is what gets redacted.
+type secretPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+var secretPatterns = []secretPattern{
+	{"AWS access key", regexp.MustCompile(`\b(AKIA[0-9A-Z]{16})\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})\b`)},
+	{"Google API key", regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})`)},
+	{"Stripe key", regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{24,})\b`)},
+	{"hardcoded credential", regexp.MustCompile(`(?i)(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']([^"'\s]{8,})["']`)},
+}
+
+var privateKeyHeader = regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)
+
+const secretComment = "🔐 **Critical: this line appears to add a %s.**\n\n" +
+	"Secrets don't belong in source control. Remove it, load it from the environment or a secret store, " +
+	"and **rotate it** - it's in the git history now even if this line goes away. " +
+	"(The value has been redacted from this review.)"
+
+// testSecretComment is for secrets in test code, which are usually fixtures
+// and so don't block the PR
+const testSecretComment = "🔐 **This test file appears to add a %s.**\n\n" +
+	"Fixtures usually are, but if it's a real credential remove it and **rotate it**. " +
+	"Obviously fake values keep scanners quiet. (The value has been redacted from this review.)"
+
+// testDirs are directories whose files are test code or fixtures
+var testDirs = map[string]bool{
+	"test": true, "tests": true, "testdata": true, "__tests__": true,
+	"spec": true, "fixtures": true, "__fixtures__": true,
+}
+
+// isTestPath reports whether path is a test file or under a test or
+// fixtures directory
+func isTestPath(path string) bool {
+	dirs := strings.Split(path, "/")
+	base := dirs[len(dirs)-1]
+	for _, dir := range dirs[:len(dirs)-1] {
+		if testDirs[dir] {
+			return true
+		}
+	}
+	return strings.Contains(base, "_test.") || strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_")
+}
+
+// SecretMatch is an added line that seems to contain a credential
+type SecretMatch struct {
+	File string
+	Line int
+	Kind string
+
+	secrets []string // the matched values, to be redacted everywhere
+}
+
+// FindSecrets scans the added lines of each patch for credentials. One match
+// is reported per line; a private key is reported once at its BEGIN line,
+// with every line of the key body recorded for redaction.
+func FindSecrets(files []*github.FileChange) []SecretMatch {
+	var matches []SecretMatch
+
+	for _, f := range files {
+		hunks, err := diff.ParsePatch(f.Patch)
+		if err != nil {
+			continue
+		}
+
+		var key *SecretMatch
+		for _, line := range diff.Lines(hunks) {
+			if line.Kind != diff.Added {
+				continue
+			}
+
+			if key != nil {
+				if strings.HasPrefix(strings.TrimSpace(line.Content), "-----END") {
+					matches = append(matches, *key)
+					key = nil
+				} else if body := strings.Trim(strings.TrimSpace(line.Content), `"',`); body != "" {
+					key.secrets = append(key.secrets, body)
+				}
+				continue
+			}
+			if privateKeyHeader.MatchString(line.Content) {
+				key = &SecretMatch{File: f.Filename, Line: line.NewLine, Kind: "private key"}
+				continue
+			}
+
+			for _, p := range secretPatterns {
+				m := p.re.FindStringSubmatch(line.Content)
+				if m == nil || isPlaceholderSecret(m[1]) {
+					continue
+				}
+				matches = append(matches, SecretMatch{File: f.Filename, Line: line.NewLine, Kind: p.kind, secrets: []string{m[1]}})
+				break
+			}
+		}
+		if key != nil {
+			matches = append(matches, *key)
+		}
+	}
+
+	return matches
+}
+
+// isPlaceholderSecret filters out the obvious not-really-secrets people put in
+// examples and templates
+func isPlaceholderSecret(s string) bool {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(s, "$") || strings.HasPrefix(s, "<") || strings.HasPrefix(s, "{{") {
+		return true
+	}
+	for _, p := range []string{"example", "your_", "your-", "changeme", "placeholder", "xxxxxxxx", "********"} {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFindings turns secret matches into critical findings and comments
+// without involving the AI, or minor ones in test code, where secrets are
+// mostly fixtures. The comments never include the secret.
+func secretFindings(matches []SecretMatch) ([]AnalyzedIssue, []*github.ReviewComment) {
+	findings := make([]AnalyzedIssue, 0, len(matches))
+	comments := make([]*github.ReviewComment, 0, len(matches))
+	for _, m := range matches {
+		severity, body := SeverityCritical, secretComment
+		if isTestPath(m.File) {
+			severity, body = SeverityMinor, testSecretComment
+		}
+		findings = append(findings, AnalyzedIssue{
+			Original: Issue{
+				File:       m.File,
+				Line:       m.Line,
+				Issue:      "Possible " + m.Kind + " committed",
+				Confidence: 100,
+				Category:   "secret",
+			},
+			Severity: severity,
+		})
+		comments = append(comments, &github.ReviewComment{
+			Path:     m.File,
+			Line:     m.Line,
+			Body:     fmt.Sprintf(body, m.Kind),
+			Side:     SideRight,
+			Severity: string(severity),
+		})
+	}
+	return findings, comments
+}
+
+// secretValues collects every matched value across the matches
+func secretValues(matches []SecretMatch) []string {
+	var values []string
+	for _, m := range matches {
+		values = append(values, m.secrets...)
+	}
+	return values
+}
+
+// redactSecrets replaces every occurrence of the secrets in text. A short
+// prefix of long secrets is kept so people can tell which key it was.
+func redactSecrets(text string, secrets []string) string {
+	for _, s := range secrets {
+		if s == "" || !strings.Contains(text, s) {
+			continue
+		}
+		replacement := "[REDACTED]"
+		if len(s) >= 16 {
+			replacement = s[:4] + "…[REDACTED]"
+		}
+		text = strings.ReplaceAll(text, s, replacement)
+	}
+	return text
+}
+
+// redactFiles returns copies of the files with secrets stripped from their patches
+func redactFiles(files []*github.FileChange, secrets []string) []*github.FileChange {
+	redacted := make([]*github.FileChange, len(files))
+	for i, f := range files {
+		c := *f
+		c.Patch = redactSecrets(f.Patch, secrets)
+		redacted[i] = &c
+	}
+	return redacted
+}
+
+// redactResult strips secrets from everything in a result that ends up posted or logged
+func redactResult(result *ReviewResult, secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+	for _, c := range result.Comments {
+		c.Body = redactSecrets(c.Body, secrets)
+	}
+	for i := range result.Findings {
+		f := &result.Findings[i]
+		f.Original.Code = redactSecrets(f.Original.Code, secrets)
+		f.Original.Issue = redactSecrets(f.Original.Issue, secrets)
+		f.Analysis.Reasoning = redactSecrets(f.Analysis.Reasoning, secrets)
+	}
+	for i := range result.Decisions {
+		result.Decisions[i].Issue = redactSecrets(result.Decisions[i].Issue, secrets)
+	}
+	for i, gap := range result.IssueGaps {
+		result.IssueGaps[i] = redactSecrets(gap, secrets)
+	}
+}
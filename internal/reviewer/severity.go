@@ -0,0 +1,123 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/config"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// Severity ranks how much a finding matters
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityMajor    Severity = "major"
+	SeverityMinor    Severity = "minor"
+	SeverityNit      Severity = "nit"
+	SeverityInfo     Severity = "info"
+)
+
+// Rank orders severities from info (0) to critical (5). Unknown severities,
+// where the model didn't rate the finding, rank between nit and minor: there's
+// no telling it's only a nit.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityCritical:
+		return 5
+	case SeverityMajor:
+		return 4
+	case SeverityMinor:
+		return 3
+	case SeverityNit:
+		return 1
+	case SeverityInfo:
+		return 0
+	default:
+		return 2
+	}
+}
+
+// ParseSeverity normalizes a severity reported by the model. Returns "" if it
+// isn't one we know.
+func ParseSeverity(s string) Severity {
+	switch sev := Severity(strings.ToLower(strings.TrimSpace(s))); sev {
+	case SeverityCritical, SeverityMajor, SeverityMinor, SeverityNit, SeverityInfo:
+		return sev
+	default:
+		return ""
+	}
+}
+
+// SeverityFromConfidence derives a severity from a 0-100 confidence using the
+// configured thresholds. A threshold of 0 disables that level.
+func SeverityFromConfidence(confidence int, m config.SeverityMapping) Severity {
+	switch {
+	case m.Critical > 0 && confidence >= m.Critical:
+		return SeverityCritical
+	case m.Major > 0 && confidence >= m.Major:
+		return SeverityMajor
+	case m.Minor > 0 && confidence >= m.Minor:
+		return SeverityMinor
+	default:
+		return SeverityInfo
+	}
+}
+
+// Badge is the label a comment of this severity starts with, or "" when
+// the severity is unknown
+func (s Severity) Badge() string {
+	switch s {
+	case SeverityCritical:
+		return "🔴 **Critical**"
+	case SeverityMajor:
+		return "🟠 **Major**"
+	case SeverityMinor:
+		return "🟡 **Minor**"
+	case SeverityNit:
+		return "⚪ **Nit**"
+	case SeverityInfo:
+		return "🔵 **Info**"
+	default:
+		return ""
+	}
+}
+
+// belowSeverity reports whether a finding of severity sev is left out by
+// min. Unknown severities are kept, since there's no telling.
+func belowSeverity(sev, min Severity) bool {
+	return min != "" && ParseSeverity(string(sev)) != "" && sev.Rank() < min.Rank()
+}
+
+// dropBelowSeverity leaves out the comments less severe than min
+func (r *Reviewer) dropBelowSeverity(result *ReviewResult, min Severity) {
+	var kept []*github.ReviewComment
+	for _, c := range result.Comments {
+		if belowSeverity(Severity(c.Severity), min) {
+			dropFinding(result, c, OutcomeBelowSeverity)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if dropped := len(result.Comments) - len(kept); dropped > 0 {
+		fmt.Fprintf(r.out, "🔕 Left out %d comment(s) below %s severity\n", dropped, min)
+	}
+	result.Comments = kept
+}
+
+// resolveSeverity picks the severity of a confirmed issue: deep analysis's
+// if it gave a valid one, then the first pass's, otherwise derived from
+// confidence when that's enabled
+func (r *Reviewer) resolveSeverity(issue Issue, analysis *DeepAnalysisResult) Severity {
+	if sev := ParseSeverity(analysis.Severity); sev != "" {
+		return sev
+	}
+	if sev := ParseSeverity(issue.Severity); sev != "" {
+		return sev
+	}
+	if r.config.SeverityFromConfidence {
+		return SeverityFromConfidence(analysis.Confidence, r.config.SeverityMapping)
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// Diff sides a review comment can be anchored to
+const (
+	SideRight = "RIGHT" // Added or unchanged code, new-file line numbers
+	SideLeft  = "LEFT"  // Removed code, old-file line numbers
+)
+
+// commentSide returns the side an issue's comment belongs on. An issue is only
+// put on the LEFT if the model said it's about removed code and its line really
+// is a removed line in the patch; anything else goes on the RIGHT.
+func commentSide(issue Issue, patch string) string {
+	if !strings.EqualFold(issue.Side, SideLeft) {
+		return SideRight
+	}
+
+	hunks, err := diff.ParsePatch(patch)
+	if err != nil {
+		return SideRight
+	}
+	for _, line := range diff.Lines(hunks) {
+		if line.Kind == diff.Removed && line.OldLine == issue.Line {
+			return SideLeft
+		}
+	}
+	return SideRight
+}
+
+// commentLocation is file:line, or file:start-end for a multi-line comment
+func commentLocation(c *github.ReviewComment) string {
+	if c.StartLine > 0 && c.StartLine < c.Line {
+		return fmt.Sprintf("%s:%d-%d", c.Path, c.StartLine, c.Line)
+	}
+	return fmt.Sprintf("%s:%d", c.Path, c.Line)
+}
@@ -0,0 +1,67 @@
+package reviewer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/signature"
+	"github.com/user/salty-reviewer/internal/state"
+)
+
+// filesSinceLastReview returns the PR's files with only the changes pushed
+// since salty last reviewed it. Without a review to go by, e.g. on the first
+// run or after a force push, it falls back to all of the PR's files.
+func (r *Reviewer) filesSinceLastReview(ref *github.PRReference, pr *github.PullRequest) ([]*github.FileChange, error) {
+	last, err := r.lastReview(ref)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		fmt.Fprintln(r.out, "ℹ️  No earlier salty review of this PR - reviewing all of it")
+		return r.githubClient.GetPRFiles(ref)
+	}
+
+	headSHA := pr.GetHead().GetSHA()
+	if last.CommitID == headSHA {
+		return nil, nil
+	}
+	files, err := r.githubClient.GetPRFilesSince(ref, last.CommitID, headSHA)
+	if errors.Is(err, github.ErrNotAncestor) {
+		fmt.Fprintf(r.out, "ℹ️  The PR's history changed since the last review at %s - reviewing all of it\n", last.ShortSHA())
+		return r.githubClient.GetPRFiles(ref)
+	}
+	if errors.Is(err, github.ErrCompareTruncated) {
+		fmt.Fprintf(r.out, "ℹ️  Over %d files changed since the last review at %s, more than GitHub compares - reviewing all of the PR\n",
+			github.CompareFilesLimit, last.ShortSHA())
+		return r.githubClient.GetPRFiles(ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(r.out, "🔁 %d file(s) changed since the last review at %s\n", len(files), last.ShortSHA())
+	return files, nil
+}
+
+// lastReview finds the latest submitted review salty posted on the PR as the
+// authenticated user, or nil if there is none
+func (r *Reviewer) lastReview(ref *github.PRReference) (*github.PRReview, error) {
+	reviews, err := r.githubClient.ListReviews(ref)
+	if err != nil {
+		return nil, err
+	}
+	me, err := state.ResolveUsername(r.config.GitHubToken, r.aiClient.Clock(), r.githubClient.GetAuthenticatedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(reviews) - 1; i >= 0; i-- {
+		review := reviews[i]
+		if review.State != "PENDING" && review.CommitID != "" && review.User == me &&
+			signature.ModeOf(review.Body) == signature.ModeReview {
+			return review, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,53 @@
+package reviewer
+
+import (
+	"fmt"
+
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// statusContext is the commit status context salty reports under
+const statusContext = "salty/review"
+
+// statusDescriptionLimit is GitHub's maximum length of a status description
+const statusDescriptionLimit = 140
+
+// statusState maps a review event to a commit status state
+func statusState(event string) string {
+	if event == "REQUEST_CHANGES" {
+		return "failure"
+	}
+	return "success"
+}
+
+// statusDescription summarizes the findings in one short line
+func statusDescription(result *ReviewResult) string {
+	if len(result.Comments) == 0 {
+		return "Reviewed - no issues found"
+	}
+	desc := "Reviewed - " + verdictCounts(result)
+	if len(desc) > statusDescriptionLimit {
+		desc = desc[:statusDescriptionLimit-3] + "..."
+	}
+	return desc
+}
+
+// setCommitStatus marks the reviewed commit with a salty/review status when
+// set_commit_status is on. Failures only warn, since the review itself is posted.
+func (r *Reviewer) setCommitStatus(ref *github.PRReference, pr *github.PullRequest, commitID string, result *ReviewResult, event string) {
+	if !r.config.SetCommitStatus {
+		return
+	}
+
+	sha := commitID
+	if sha == "" {
+		sha = pr.GetHead().GetSHA()
+	}
+
+	state := statusState(event)
+	if err := r.githubClient.CreateStatus(ref.Owner, ref.Repo, sha, state, statusContext, statusDescription(result)); err != nil {
+		r.out.Warnf("⚠️  Could not set commit status: %v", err)
+		return
+	}
+	fmt.Fprintf(r.out, "🏷️  Set %s status to %s\n", statusContext, state)
+}
@@ -0,0 +1,253 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/ai"
+	"github.com/user/salty-reviewer/internal/github"
+	"github.com/user/salty-reviewer/internal/jsonx"
+)
+
+// FirstPassStream is FirstPass, streamed: each issue is sent on issues as soon
+// as it has fully arrived, so deep analysis can start on it while the rest of
+// the response is still coming in. A split diff is streamed one batch after
+// the other. issues is closed when the first pass ends. Clients that can't
+// stream fall back to FirstPass and send all issues at once.
+func (a *Analyzer) FirstPassStream(files []*github.FileChange, fpc FirstPassContext, issues chan<- Issue) (*FirstPassResult, error) {
+	defer close(issues)
+
+	batches := firstPassBatches(files, fpc, a.maxFirstPassTokens)
+	results := make([]*FirstPassResult, 0, len(batches))
+	for i, batch := range batches {
+		result, err := a.streamBatch(firstPassMessages(batch, fpc.batch(i, len(batches))), issues)
+		if errors.Is(err, ai.ErrStreamingUnsupported) {
+			result, err := a.FirstPass(files, fpc)
+			if err != nil {
+				return nil, err
+			}
+			for _, issue := range result.Issues {
+				issues <- issue
+			}
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return mergeFirstPass(results), nil
+}
+
+// streamBatch streams one first-pass call, sending its issues on issues
+func (a *Analyzer) streamBatch(messages []ai.Message, issues chan<- Issue) (*FirstPassResult, error) {
+	var emitted []Issue
+	response, err := a.aiClient.ChatJSONStream(messages, func(text string) {
+		for _, issue := range completeIssues(text)[len(emitted):] {
+			emitted = append(emitted, issue)
+			issues <- issue
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AI first pass failed: %w", err)
+	}
+
+	result, err := jsonx.Parse[FirstPassResult](response, "first pass result", a.out)
+	if err != nil {
+		return nil, err
+	}
+
+	// The incremental scan only sees what looked complete at the time, so the
+	// full parse is authoritative: issues the scan missed are sent now, and
+	// streamConfirm drops those it sent that the full parse doesn't have
+	missing := notInResponse(result.Issues, emitted)
+	for i, issue := range result.Issues {
+		if missing[i] {
+			issues <- issue
+		}
+	}
+
+	return result, nil
+}
+
+// notInResponse marks the issues that aren't among others, counting
+// duplicates: an issue listed twice needs to be in others twice.
+func notInResponse(issues, others []Issue) []bool {
+	left := make(map[Issue]int, len(others))
+	for _, issue := range others {
+		left[issue]++
+	}
+	missing := make([]bool, len(issues))
+	for i, issue := range issues {
+		if left[issue] > 0 {
+			left[issue]--
+			continue
+		}
+		missing[i] = true
+	}
+	return missing
+}
+
+// completeIssues returns the issue objects that have fully arrived in a
+// partial first-pass response, in order. Objects that don't parse as an
+// issue are skipped.
+func completeIssues(text string) []Issue {
+	key := strings.Index(text, `"issues"`)
+	if key == -1 {
+		return nil
+	}
+	open := strings.IndexByte(text[key:], '[')
+	if open == -1 {
+		return nil
+	}
+
+	var issues []Issue
+	depth, start := 0, -1
+	inString, escaped := false, false
+	for i := key + open + 1; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 && start != -1 {
+				var issue Issue
+				if err := json.Unmarshal([]byte(text[start:i+1]), &issue); err == nil {
+					issues = append(issues, issue)
+				}
+				start = -1
+			}
+		case ']':
+			if depth == 0 {
+				return issues
+			}
+		}
+	}
+	return issues
+}
+
+// streamConfirm runs the first pass streamed and deep-analyzes each issue as
+// soon as it arrives, recording decisions as it goes. A failed first pass or
+// rejected GitHub credentials or a GitHub rate limit are returned as errors; running out of budget
+// during deep analysis is recorded in the result's stats. Either way the
+// first pass keeps streaming, and the issues it still sends are left undecided.
+// Issues sent early that the full first-pass response doesn't have are
+// dropped once it has arrived.
+func (r *Reviewer) streamConfirm(ref *github.PRReference, sha string, files []*github.FileChange, fpc FirstPassContext, effectiveNitpicky int, result *ReviewResult) (*FirstPassResult, []AnalyzedIssue, error) {
+	byName := make(map[string]*github.FileChange, len(files))
+	for _, f := range files {
+		byName[f.Filename] = f
+	}
+
+	type firstPassOutcome struct {
+		result *FirstPassResult
+		err    error
+	}
+	issues := make(chan Issue, 16)
+	done := make(chan firstPassOutcome, 1)
+	go func() {
+		fp, err := r.firstPassStream(files, fpc, issues)
+		done <- firstPassOutcome{fp, err}
+	}()
+
+	threshold := confidenceThreshold(effectiveNitpicky)
+	lines := newLineIndex(files)
+	var confirmedIssues []AnalyzedIssue
+	var received []Issue // as sent, parallel to result.Decisions
+	analyzed := 0
+	var stopErr error
+	for issue := range issues {
+		received = append(received, issue)
+
+		issue, anchor := lines.reconcile(issue)
+		if anchor == lineDropped {
+			result.Stats.IssuesDropped++
+			result.Decisions = append(result.Decisions, droppedDecision(issue, threshold))
+			continue
+		}
+		if anchor == lineReanchored {
+			result.Stats.IssuesReanchored++
+		}
+
+		i := len(result.Decisions)
+		result.Decisions = append(result.Decisions, newDecisions([]Issue{issue}, threshold)...)
+		if result.Stats.BudgetExceeded || stopErr != nil {
+			continue
+		}
+
+		fmt.Fprintf(r.out, "   [%d] Analyzing: %s (line %d)...\n", analyzed+1, issue.File, issue.Line)
+		ci, err := r.confirmIssue(i, issue, ref, sha, byName[issue.File], effectiveNitpicky, result.redactions, &result.Decisions[i])
+		if errors.Is(err, ai.ErrBudgetExceeded) {
+			result.Stats.BudgetExceeded = true
+			continue
+		}
+		if err != nil {
+			stopErr = err
+			continue
+		}
+		analyzed++
+		if ci != nil {
+			confirmedIssues = append(confirmedIssues, *ci)
+		}
+	}
+
+	outcome := <-done
+	if outcome.err != nil {
+		return nil, nil, outcome.err
+	}
+	if stopErr != nil {
+		return nil, nil, stopErr
+	}
+
+	retracted := notInResponse(received, outcome.result.Issues)
+	dropped := 0
+	for i, gone := range retracted {
+		if gone {
+			result.Decisions[i].Outcome = OutcomeNotInResponse
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		kept := confirmedIssues[:0]
+		for _, ci := range confirmedIssues {
+			if !retracted[ci.index] {
+				kept = append(kept, ci)
+			}
+		}
+		confirmedIssues = kept
+		fmt.Fprintf(r.out, "   Dropped %d streamed issue(s) the full first-pass response doesn't have\n", dropped)
+	}
+
+	if result.Stats.IssuesReanchored > 0 || result.Stats.IssuesDropped > 0 {
+		fmt.Fprintf(r.out, "   📐 Re-anchored %d issue(s), dropped %d whose line isn't in the diff\n",
+			result.Stats.IssuesReanchored, result.Stats.IssuesDropped)
+	}
+	result.Stats.IssuesAnalyzed = analyzed
+	if result.Stats.BudgetExceeded {
+		fmt.Fprintf(r.out, "💸 Token budget of %d exhausted after analyzing %d of %d issues\n",
+			r.aiClient.TokenBudget(), analyzed, len(received)-result.Stats.IssuesDropped)
+	}
+
+	return outcome.result, confirmedIssues, nil
+}
@@ -0,0 +1,30 @@
+package reviewer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotInResponse(t *testing.T) {
+	a := Issue{File: "a.go", Line: 1, Issue: "nil deref"}
+	b := Issue{File: "b.go", Line: 2, Issue: "leak"}
+	partial := Issue{File: "b.go", Line: 2, Issue: "lea"}
+	tests := []struct {
+		name   string
+		issues []Issue
+		others []Issue
+		want   []bool
+	}{
+		{"all there", []Issue{a, b}, []Issue{b, a}, []bool{false, false}},
+		{"scan got one wrong", []Issue{a, partial}, []Issue{a, b}, []bool{false, true}},
+		{"scan missed one", []Issue{a, b}, []Issue{a}, []bool{false, true}},
+		{"duplicates count", []Issue{a, a}, []Issue{a}, []bool{false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notInResponse(tt.issues, tt.others); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("notInResponse = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package reviewer
+
+import "strings"
+
+// suggestion returns the replacement code to post as a GitHub suggestion for
+// an issue, or "" if there is none. Deep analysis has the last word over the
+// first pass. Removed lines can't take a suggestion, and code holding a fence
+// would break out of the block.
+func suggestion(issue AnalyzedIssue) string {
+	replacement := issue.Analysis.SuggestedReplacement
+	if replacement == "" {
+		replacement = issue.Original.SuggestedReplacement
+	}
+	replacement = strings.TrimRight(replacement, "\n")
+	if strings.TrimSpace(replacement) == "" ||
+		strings.EqualFold(issue.Original.Side, SideLeft) ||
+		strings.Contains(replacement, "```") {
+		return ""
+	}
+	return replacement
+}
+
+// suggestionBlock wraps replacement code in a block GitHub offers to apply
+// to the commented lines with one click
+func suggestionBlock(replacement string) string {
+	return "```suggestion\n" + replacement + "\n```"
+}
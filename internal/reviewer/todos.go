@@ -0,0 +1,98 @@
+package reviewer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/diff"
+	"github.com/user/salty-reviewer/internal/github"
+)
+
+// TodoMarker is an added line containing a TODO-style keyword
+type TodoMarker struct {
+	File    string
+	Line    int
+	Keyword string
+	Content string
+}
+
+// FindTodoMarkers scans the added lines of each patch for new TODO-style
+// comments. Keywords match as whole words, case-sensitively, so "todos" or
+// "xxxLarge" don't count. At most one marker is reported per line.
+func FindTodoMarkers(files []*github.FileChange, keywords []string) []TodoMarker {
+	pattern := todoPattern(keywords)
+	if pattern == nil {
+		return nil
+	}
+
+	var markers []TodoMarker
+	for _, f := range files {
+		hunks, err := diff.ParsePatch(f.Patch)
+		if err != nil {
+			continue
+		}
+
+		for _, line := range diff.Lines(hunks) {
+			if line.Kind != diff.Added {
+				continue
+			}
+			if m := pattern.FindStringSubmatch(line.Content); m != nil {
+				markers = append(markers, TodoMarker{
+					File:    f.Filename,
+					Line:    line.NewLine,
+					Keyword: m[1],
+					Content: strings.TrimSpace(line.Content),
+				})
+			}
+		}
+	}
+
+	return markers
+}
+
+// todoPattern builds a whole-word regexp for the keywords, or nil if there are none
+func todoPattern(keywords []string) *regexp.Regexp {
+	var quoted []string
+	for _, k := range keywords {
+		if k = strings.TrimSpace(k); k != "" {
+			quoted = append(quoted, regexp.QuoteMeta(k))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?:^|\W)(` + strings.Join(quoted, "|") + `)(?:\W|$)`)
+}
+
+// todoFindings turns TODO markers into nit-level findings without involving
+// the AI, so they are counted and published like any other finding
+func todoFindings(markers []TodoMarker) []AnalyzedIssue {
+	findings := make([]AnalyzedIssue, 0, len(markers))
+	for _, m := range markers {
+		findings = append(findings, AnalyzedIssue{
+			Original: Issue{
+				File:       m.File,
+				Line:       m.Line,
+				Code:       m.Content,
+				Issue:      fmt.Sprintf("New %s added", m.Keyword),
+				Confidence: 100,
+				Category:   "todo",
+			},
+			Severity: SeverityNit,
+		})
+	}
+	return findings
+}
+
+// todoComment is the review comment for a new TODO marker
+func todoComment(m TodoMarker) *github.ReviewComment {
+	return &github.ReviewComment{
+		Path: m.File,
+		Line: m.Line,
+		Body: fmt.Sprintf("📝 **New `%s` added.** Is there an issue tracking this, or should it be done before merging?\n\n```\n%s\n```",
+			m.Keyword, m.Content),
+		Side:     SideRight,
+		Severity: string(SeverityNit),
+	}
+}
@@ -0,0 +1,104 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/salty-reviewer/internal/config"
+)
+
+// verdictLine is the one-line TL;DR at the top of the summary, e.g.
+// "🧂 **Verdict:** Not shippable yet - 3 major, 5 nits"
+func (r *Reviewer) verdictLine(result *ReviewResult, event string) string {
+	verdict := r.verdictText(event, len(result.Comments) > 0)
+
+	counts := verdictCounts(result)
+	if counts == "" {
+		return fmt.Sprintf("🧂 **Verdict:** %s", verdict)
+	}
+	return fmt.Sprintf("🧂 **Verdict:** %s - %s", verdict, counts)
+}
+
+func (r *Reviewer) verdictText(event string, hasComments bool) string {
+	switch {
+	case event == "REQUEST_CHANGES":
+		switch r.config.WritingStyle {
+		case config.StyleCorporate:
+			return "Revisions required"
+		case config.StyleTechBro:
+			return "Not shippable yet"
+		case config.StyleAcademic:
+			return "Major revisions requested"
+		default:
+			return "Just a few things to fix first 🙂"
+		}
+	case hasComments:
+		switch r.config.WritingStyle {
+		case config.StyleCorporate:
+			return "Approved with observations"
+		case config.StyleTechBro:
+			return "Ship it after a few tweaks"
+		case config.StyleAcademic:
+			return "Minor revisions suggested"
+		default:
+			return "Mostly fine, I guess"
+		}
+	default:
+		switch r.config.WritingStyle {
+		case config.StyleCorporate:
+			return "No concerns identified"
+		case config.StyleTechBro:
+			return "LGTM 🚀"
+		case config.StyleAcademic:
+			return "Accepted as submitted"
+		default:
+			return "Fine. Probably."
+		}
+	}
+}
+
+// verdictCounts summarizes the comments by severity, most severe first.
+// Comments without a severity (nitpicks, unrated findings) are counted as
+// "other".
+func verdictCounts(result *ReviewResult) string {
+	bySeverity := make(map[Severity]int)
+	rated := 0
+	for _, f := range result.Findings {
+		if f.Severity != "" {
+			bySeverity[f.Severity]++
+			rated++
+		}
+	}
+
+	var parts []string
+	if result.Stats.ConflictMarkers > 0 {
+		parts = append(parts, plural(result.Stats.ConflictMarkers, "conflict", "conflicts"))
+	}
+	for _, sev := range []Severity{SeverityCritical, SeverityMajor, SeverityMinor, SeverityNit, SeverityInfo} {
+		if n := bySeverity[sev]; n > 0 {
+			if sev == SeverityNit {
+				parts = append(parts, plural(n, "nit", "nits"))
+			} else {
+				parts = append(parts, fmt.Sprintf("%d %s", n, sev))
+			}
+		}
+	}
+
+	other := len(result.Comments) - result.Stats.ConflictMarkers - rated
+	if other > 0 {
+		if rated == 0 && result.Stats.ConflictMarkers == 0 {
+			parts = append(parts, plural(other, "comment", "comments"))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d other", other))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
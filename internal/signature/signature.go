@@ -0,0 +1,46 @@
+// Package signature marks text salty posts so people (and salty itself) can
+// tell which of its roles wrote it.
+package signature
+
+import "strings"
+
+// Mode is the role salty was in when it posted something
+type Mode string
+
+const (
+	ModeReview Mode = "review"
+	ModeDefend Mode = "defend"
+
+	// ModeDigest marks the review digest. It only points at review
+	// comments, so unlike a review there is nothing in it to defend.
+	ModeDigest Mode = "digest"
+)
+
+// marker is an HTML comment, invisible on GitHub, that identifies the mode
+func marker(mode Mode) string {
+	return "<!-- salty:" + string(mode) + " -->"
+}
+
+// Sign appends the visible signature (if any) and the hidden mode marker to body
+func Sign(body string, mode Mode, sig string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(body, "\n"))
+	if sig != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(sig)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(marker(mode))
+	return sb.String()
+}
+
+// ModeOf returns the mode salty was in when it posted body, or "" if salty
+// didn't post it
+func ModeOf(body string) Mode {
+	for _, mode := range []Mode{ModeReview, ModeDefend, ModeDigest} {
+		if strings.Contains(body, marker(mode)) {
+			return mode
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/user/salty-reviewer/internal/clock"
+	"github.com/user/salty-reviewer/internal/config"
+)
+
+// IdentityTTL is how long a resolved username is trusted before asking GitHub again
+const IdentityTTL = 24 * time.Hour
+
+// State holds what salty remembers between runs. Unlike the config it is
+// written by salty itself and safe to delete at any time.
+type State struct {
+	// Identities maps a token hash to the login it belongs to
+	Identities map[string]Identity `json:"identities,omitempty"`
+
+	// Defenses maps a PR ("owner/repo#123") to the defend responses generated
+	// for it, keyed by a hash of everything that went into each response
+	Defenses map[string]map[string]CachedDefense `json:"defenses,omitempty"`
+}
+
+// Identity is a cached authenticated username
+type Identity struct {
+	Login      string    `json:"login"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// CachedDefense is a defend analysis and response that can be reused while
+// the comment and the code around it stay the same
+type CachedDefense struct {
+	Action          string    `json:"action"`
+	ConfidenceValid int       `json:"confidence_valid"`
+	Response        string    `json:"response"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// mu serializes Update so concurrent callers in one process don't lose writes
+var mu sync.Mutex
+
+// Path returns the full path to the state file
+func Path() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state.json"), nil
+}
+
+// Load reads the state from disk. A missing file is an empty state.
+func Load() (*State, error) {
+	st := &State{}
+
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return nil, fmt.Errorf("could not read state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("could not parse state: %w", err)
+	}
+
+	return st, nil
+}
+
+// Save writes the state to disk
+func (s *State) Save() error {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode state: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("could not write state: %w", err)
+	}
+
+	return nil
+}
+
+// Update loads the state, applies fn and saves the result. An unreadable
+// state file is treated as empty rather than blocking the update.
+func Update(fn func(*State)) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	st, err := Load()
+	if err != nil {
+		st = &State{}
+	}
+	fn(st)
+	return st.Save()
+}
+
+// tokenKey hashes a token so the state file never contains the token itself
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveUsername returns the login for a token, using the cached value if it
+// is younger than IdentityTTL and calling lookup otherwise. Caching is best
+// effort: state read/write failures just mean lookup gets called. The cache
+// age is measured with clk.
+func ResolveUsername(token string, clk clock.Clock, lookup func() (string, error)) (string, error) {
+	st, err := Load()
+	if err != nil {
+		st = &State{}
+	}
+
+	key := tokenKey(token)
+	if id, ok := st.Identities[key]; ok && id.Login != "" && clk.Now().Sub(id.ResolvedAt) < IdentityTTL {
+		return id.Login, nil
+	}
+
+	login, err := lookup()
+	if err != nil {
+		return "", err
+	}
+
+	// Only the current token is kept, so switching tokens invalidates the old entry
+	_ = Update(func(st *State) {
+		st.Identities = map[string]Identity{
+			key: {Login: login, ResolvedAt: clk.Now()},
+		}
+	})
+
+	return login, nil
+}